@@ -0,0 +1,59 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package config
+
+/**
+ * the bandwidth check of vhost, the client connects with the key in tcUrl,
+ * for example, rtmp://host/app?key=35c9b402c12a7246868752e2878f7e0e
+ */
+type BandCheckConf struct {
+	Enabled string `json:"enabled"`
+	Key     string `json:"key"`
+	// the min interval in seconds between checks of vhost.
+	Interval uint32 `json:"interval"`
+	// the max kbps of play and publish sampling.
+	LimitKbps uint32 `json:"limit_kbps"`
+}
+
+const SRS_CONF_DEFAULT_BANDCHECK_KEY = "35c9b402c12a7246868752e2878f7e0e"
+const SRS_CONF_DEFAULT_BANDCHECK_INTERVAL = 30
+const SRS_CONF_DEFAULT_BANDCHECK_LIMIT_KBPS = 1000
+
+func (this *BandCheckConf) initDefault() {
+	if this.Enabled == "" {
+		this.Enabled = "off"
+	}
+
+	if this.Key == "" {
+		this.Key = SRS_CONF_DEFAULT_BANDCHECK_KEY
+	}
+
+	if this.Interval == 0 {
+		this.Interval = SRS_CONF_DEFAULT_BANDCHECK_INTERVAL
+	}
+
+	if this.LimitKbps == 0 {
+		this.LimitKbps = SRS_CONF_DEFAULT_BANDCHECK_LIMIT_KBPS
+	}
+}
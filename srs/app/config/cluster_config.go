@@ -0,0 +1,50 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package config
+
+/**
+ * the cluster of vhost, the edge pulls the stream from origin when the first player comes.
+ */
+type ClusterConf struct {
+	// the mode of vhost, local for origin, remote for edge.
+	Mode string `json:"mode"`
+	// the origin servers of edge, tried in turn when fails,
+	// for example, 127.0.0.1:1935 or rtmps://origin.net:443
+	Origin []string `json:"origin"`
+	// the seconds to keep pulling from origin after the last player leaves.
+	EdgeIdleTimeout uint32 `json:"edge_idle_timeout"`
+}
+
+const SRS_CONF_DEFAULT_CLUSTER_MODE = "local"
+const SRS_CONF_DEFAULT_EDGE_IDLE_TIMEOUT = 10
+
+func (this *ClusterConf) initDefault() {
+	if this.Mode == "" {
+		this.Mode = SRS_CONF_DEFAULT_CLUSTER_MODE
+	}
+
+	if this.EdgeIdleTimeout == 0 {
+		this.EdgeIdleTimeout = SRS_CONF_DEFAULT_EDGE_IDLE_TIMEOUT
+	}
+}
@@ -23,19 +23,27 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 
 package config
 
+/**
+ * the http api listener of server, separated from the http stream server,
+ * for the api to change the server, for example, to push streams.
+ * the token is required to change the server, which is disabled when no token.
+ */
 type HttpApiConf struct {
 	Enabled     string `json:"enabled"`
 	Listen      uint32 `json:"listen"`
 	Crossdomain string `json:"crossdomain"`
+	Token       string `json:"token"`
 }
 
+const SRS_CONF_DEFAULT_HTTP_API_LISTEN = 1985
+
 func (this *HttpApiConf) initDefault() {
 	if this.Enabled == "" {
 		this.Enabled = "off"
 	}
 
 	if this.Listen == 0 {
-		this.Listen = 1985
+		this.Listen = SRS_CONF_DEFAULT_HTTP_API_LISTEN
 	}
 
 	if this.Crossdomain == "" {
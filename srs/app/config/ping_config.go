@@ -0,0 +1,53 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package config
+
+/**
+ * the ping of connection, the server sends ping request to client in interval,
+ * and closes the dead connection which receives nothing from client in timeout.
+ */
+type PingConf struct {
+	Enabled string `json:"enabled"`
+	// the interval in seconds to send ping request.
+	Interval uint32 `json:"interval"`
+	// the timeout in seconds to close the connection which receives nothing.
+	Timeout uint32 `json:"timeout"`
+}
+
+const SRS_CONF_DEFAULT_PING_INTERVAL = 10
+const SRS_CONF_DEFAULT_PING_TIMEOUT = 30
+
+func (this *PingConf) initDefault() {
+	if this.Enabled == "" {
+		this.Enabled = "on"
+	}
+
+	if this.Interval == 0 {
+		this.Interval = SRS_CONF_DEFAULT_PING_INTERVAL
+	}
+
+	if this.Timeout == 0 {
+		this.Timeout = SRS_CONF_DEFAULT_PING_TIMEOUT
+	}
+}
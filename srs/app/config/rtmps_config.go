@@ -0,0 +1,65 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package config
+
+/**
+ * the rtmps(rtmp over tls) listener of server, the certificate is used
+ * when the client specifies no SNI or the SNI vhost has no tls config.
+ */
+type RtmpsConf struct {
+	Enabled string `json:"enabled"`
+	Listen  uint32 `json:"listen"`
+	Key     string `json:"key"`
+	Cert    string `json:"cert"`
+}
+
+const SRS_CONF_DEFAULT_RTMPS_LISTEN = 1936
+const SRS_CONF_DEFAULT_RTMPS_KEY = "./conf/server.key"
+const SRS_CONF_DEFAULT_RTMPS_CERT = "./conf/server.crt"
+
+func (this *RtmpsConf) initDefault() {
+	if this.Enabled == "" {
+		this.Enabled = "off"
+	}
+
+	if this.Listen == 0 {
+		this.Listen = SRS_CONF_DEFAULT_RTMPS_LISTEN
+	}
+
+	if this.Key == "" {
+		this.Key = SRS_CONF_DEFAULT_RTMPS_KEY
+	}
+
+	if this.Cert == "" {
+		this.Cert = SRS_CONF_DEFAULT_RTMPS_CERT
+	}
+}
+
+/**
+ * the certificate of vhost, selected by the SNI of rtmps client.
+ */
+type TlsConf struct {
+	Key  string `json:"key"`
+	Cert string `json:"cert"`
+}
@@ -37,6 +37,9 @@ type SrsConfig struct {
 	pithy_print_ms int64                 `json:"pithy_print_ms"`
 	WorkDir        string                `json:"work_dir"`
 	VHosts         map[string]*VHostConf `json:"vhosts"`
+	Rtmps          *RtmpsConf            `json:"rtmps"`
+	Rtmpt          *RtmptConf            `json:"rtmpt"`
+	HttpApi        *HttpApiConf          `json:"http_api"`
 	subscribers    []*SrsAppSubscriber
 }
 
@@ -72,6 +75,69 @@ func (this *SrsConfig) initDefault() {
 	for _, v := range this.VHosts {
 		v.initDefault()
 	}
+
+	if this.Rtmps != nil {
+		this.Rtmps.initDefault()
+	}
+
+	if this.Rtmpt != nil {
+		this.Rtmpt.initDefault()
+	}
+
+	if this.HttpApi != nil {
+		this.HttpApi.initDefault()
+	}
+}
+
+func (this *SrsConfig) GetRtmpsEnabled() bool {
+	return this.Rtmps != nil && this.Rtmps.Enabled == "on"
+}
+
+func (this *SrsConfig) GetRtmpsListen() uint32 {
+	if this.Rtmps == nil {
+		return SRS_CONF_DEFAULT_RTMPS_LISTEN
+	}
+	return this.Rtmps.Listen
+}
+
+func (this *SrsConfig) GetRtmpsKey() string {
+	if this.Rtmps == nil {
+		return SRS_CONF_DEFAULT_RTMPS_KEY
+	}
+	return this.Rtmps.Key
+}
+
+func (this *SrsConfig) GetRtmpsCert() string {
+	if this.Rtmps == nil {
+		return SRS_CONF_DEFAULT_RTMPS_CERT
+	}
+	return this.Rtmps.Cert
+}
+
+func (this *SrsConfig) GetRtmptEnabled() bool {
+	return this.Rtmpt != nil && this.Rtmpt.Enabled == "on"
+}
+
+func (this *SrsConfig) GetHttpApiEnabled() bool {
+	return this.HttpApi != nil && this.HttpApi.Enabled == "on"
+}
+
+func (this *SrsConfig) GetHttpApiListen() uint32 {
+	if this.HttpApi == nil {
+		return SRS_CONF_DEFAULT_HTTP_API_LISTEN
+	}
+	return this.HttpApi.Listen
+}
+
+func (this *SrsConfig) GetHttpApiCrossdomain() bool {
+	return this.HttpApi == nil || this.HttpApi.Crossdomain == "on"
+}
+
+func (this *SrsConfig) GetHttpApiToken() string {
+	if this.HttpApi == nil {
+		return ""
+	}
+	return this.HttpApi.Token
 }
 
 func (this *SrsConfig) AddSubscriber(s *SrsAppSubscriber) {
@@ -216,6 +282,122 @@ func GetDvrPlan(vhost string) string {
 	return h.Dvr.DvrPlan
 }
 
+func GetBandcheckEnabled(vhost string) bool {
+	h := GetInstance().GetVHost(vhost)
+	if h == nil {
+		return false
+	}
+
+	return h.Enabled == "on" && h.BandCheck != nil && h.BandCheck.Enabled == "on"
+}
+
+func GetBandcheckKey(vhost string) string {
+	if !GetBandcheckEnabled(vhost) {
+		return SRS_CONF_DEFAULT_BANDCHECK_KEY
+	}
+
+	return GetInstance().GetVHost(vhost).BandCheck.Key
+}
+
+/**
+ * the min interval in seconds between bandwidth checks of vhost.
+ */
+func GetBandcheckInterval(vhost string) uint32 {
+	if !GetBandcheckEnabled(vhost) {
+		return SRS_CONF_DEFAULT_BANDCHECK_INTERVAL
+	}
+
+	return GetInstance().GetVHost(vhost).BandCheck.Interval
+}
+
+func GetBandcheckLimitKbps(vhost string) uint32 {
+	if !GetBandcheckEnabled(vhost) {
+		return SRS_CONF_DEFAULT_BANDCHECK_LIMIT_KBPS
+	}
+
+	return GetInstance().GetVHost(vhost).BandCheck.LimitKbps
+}
+
+/**
+ * the ping of connection is enabled by default.
+ */
+func GetPingEnabled(vhost string) bool {
+	h := GetInstance().GetVHost(vhost)
+	if h == nil || h.Enabled != "on" || h.Ping == nil {
+		return true
+	}
+
+	return h.Ping.Enabled == "on"
+}
+
+func GetPingInterval(vhost string) uint32 {
+	h := GetInstance().GetVHost(vhost)
+	if h == nil || h.Enabled != "on" || h.Ping == nil {
+		return SRS_CONF_DEFAULT_PING_INTERVAL
+	}
+
+	return h.Ping.Interval
+}
+
+func GetPingTimeout(vhost string) uint32 {
+	h := GetInstance().GetVHost(vhost)
+	if h == nil || h.Enabled != "on" || h.Ping == nil {
+		return SRS_CONF_DEFAULT_PING_TIMEOUT
+	}
+
+	return h.Ping.Timeout
+}
+
+/**
+ * whether the vhost is edge, which pulls stream from origin.
+ */
+func GetVhostIsEdge(vhost string) bool {
+	h := GetInstance().GetVHost(vhost)
+	if h == nil || h.Enabled != "on" || h.Cluster == nil {
+		return false
+	}
+
+	return h.Cluster.Mode == "remote"
+}
+
+func GetVhostEdgeOrigin(vhost string) []string {
+	h := GetInstance().GetVHost(vhost)
+	if h == nil || h.Enabled != "on" || h.Cluster == nil {
+		return nil
+	}
+
+	return h.Cluster.Origin
+}
+
+func GetEdgeIdleTimeout(vhost string) uint32 {
+	h := GetInstance().GetVHost(vhost)
+	if h == nil || h.Enabled != "on" || h.Cluster == nil {
+		return SRS_CONF_DEFAULT_EDGE_IDLE_TIMEOUT
+	}
+
+	return h.Cluster.EdgeIdleTimeout
+}
+
+func GetVhostForward(vhost string) []string {
+	h := GetInstance().GetVHost(vhost)
+	if h == nil || h.Enabled != "on" {
+		return nil
+	}
+
+	return h.Forward
+}
+
+const SRS_CONF_DEFAULT_TIME_JITTER = "full"
+
+func GetTimeJitter(vhost string) string {
+	h := GetInstance().GetVHost(vhost)
+	if h == nil || h.Enabled != "on" || h.TimerJitter == "" {
+		return SRS_CONF_DEFAULT_TIME_JITTER
+	}
+
+	return h.TimerJitter
+}
+
 const SRS_CONF_DEFAULT_1STPKT_TIMEOUT = 2000
 
 func GetPublish1stpktTimeout(vhost string) uint32 {
@@ -246,6 +428,30 @@ func GetPublishNormalPktTimeout(vhost string) uint32 {
 	return SRS_CONF_DEFAULT_NORPKT_TIMEOUT
 }
 
+/**
+ * the merged-write latency in ms, the player consumer waits at most
+ * this duration to send a batch of messages in a single write,
+ * 0 to disable the merged-write.
+ */
+const SRS_CONF_DEFAULT_MW_LATENCY = 350
+
+func GetMwLatency(vhost string) uint32 {
+	h := GetInstance().GetVHost(vhost)
+	if h == nil {
+		return SRS_CONF_DEFAULT_MW_LATENCY
+	}
+
+	if h.Enabled != "on" {
+		return SRS_CONF_DEFAULT_MW_LATENCY
+	}
+
+	if h.MwLatency == nil {
+		return SRS_CONF_DEFAULT_MW_LATENCY
+	}
+
+	return *h.MwLatency
+}
+
 const SRS_CONF_DEFAULT_PITHY_PRINT_MS = 10000
 
 func (this *SrsConfig) GetPithyPrintMs() int64 {
@@ -26,6 +26,7 @@ package config
 type VHostConf struct {
 	Enabled              string          `json:"enabled"`
 	MinLatency           string          `json:"min_latency"`
+	MwLatency            *uint32         `json:"mw_latency"`
 	GopCache             string          `json:"gop_cache"`
 	QueueLength          uint32          `json:"queue_length"`
 	SendMinInterval      uint32          `json:"send_min_interval"`
@@ -49,10 +50,20 @@ type VHostConf struct {
 	Hls                  *HlsConf        `json:"hls"`
 	HttpHooks            *HttpHooksConf  `json:"http_hooks"`
 	Publish              *PublishConf    `json:"publish"`
+	BandCheck            *BandCheckConf  `json:"bandcheck"`
+	Ping                 *PingConf       `json:"ping"`
+	Tls                  *TlsConf        `json:"tls"`
+	Cluster              *ClusterConf    `json:"cluster"`
 }
 
 func (this *VHostConf) initDefault() {
 	this.MinLatency = "on"
+	// the mw_latency 0 disables the merged-write, only default when absent.
+	if this.MwLatency == nil {
+		mwLatency := uint32(SRS_CONF_DEFAULT_MW_LATENCY)
+		this.MwLatency = &mwLatency
+	}
+
 	if this.GopCache == "" {
 		this.GopCache = "on"
 	}
@@ -136,4 +147,16 @@ func (this *VHostConf) initDefault() {
 	if this.Publish != nil {
 		this.Publish.initDefault()
 	}
+
+	if this.BandCheck != nil {
+		this.BandCheck.initDefault()
+	}
+
+	if this.Ping != nil {
+		this.Ping.initDefault()
+	}
+
+	if this.Cluster != nil {
+		this.Cluster.initDefault()
+	}
 }
@@ -24,12 +24,18 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package app
 
 import (
-	"errors"
 	log "github.com/sirupsen/logrus"
+	"go_srs/srs/app/config"
 	"go_srs/srs/protocol/packet"
 	"go_srs/srs/protocol/rtmp"
+	"time"
 )
 
+/**
+ * the max messages to send in a merged write.
+ */
+const SRS_PERF_MW_MSGS = 128
+
 type ConsumerStopListener interface {
 	OnConsumerStop()
 }
@@ -41,18 +47,23 @@ type SrsConsumer struct {
 	StreamId        int
 	queueRecvThread *SrsQueueRecvThread
 	consuming       bool
+	mwLatency       time.Duration
+	// whether the player paused, only the latest gop is buffered when paused.
+	paused bool
+	// the tracks to play of multitrack stream.
+	tracks *SrsPlayTracks
 }
 
-func NewSrsConsumer(s *SrsSource, c *SrsRtmpConn) Consumer {
-	//todo
+func NewSrsConsumer(s *SrsSource, c *SrsRtmpConn, streamId int, tracks *SrsPlayTracks) *SrsConsumer {
 	consumer := &SrsConsumer{
 		queue:    NewSrsMessageQueue(),
 		source:   s,
 		conn:     c,
-		StreamId: 1,
+		StreamId: streamId,
+		tracks:   tracks,
 	}
-	consumer.queueRecvThread = NewSrsQueueRecvThread(consumer, c.rtmp)
-	consumer.queueRecvThread.Start()
+	consumer.mwLatency = time.Duration(config.GetMwLatency(s.req.vhost)) * time.Millisecond
+	consumer.queueRecvThread = NewSrsQueueRecvThread(consumer)
 	return consumer
 }
 
@@ -70,30 +81,61 @@ func (this *SrsConsumer) ConsumeCycle() error {
 		for !this.queueRecvThread.Empty() { //process signal message
 			msg := this.queueRecvThread.GetMsg()
 			if msg != nil {
-				err := this.processPlayControlMsg(msg)
-				if err != nil {
+				if err := this.processPlayControlMsg(msg); err != nil {
 					return err
 				}
 			}
 		}
+
+		// the player paused, keep the latest gop to resume from the keyframe.
+		if this.paused {
+			this.queue.ShrinkToKeyframe()
+			if err := this.queue.WaitSignal(); err != nil {
+				return err
+			}
+			continue
+		}
+
 		//todo process realtime stream
-		msg, err := this.queue.Wait()
+		// merged write, send a batch of messages in a single writev.
+		msgs, err := this.queue.WaitBatch(this.mwLatency, SRS_PERF_MW_MSGS)
 		if err != nil {
 			return err
 		}
 
-		if msg != nil {
-			err := this.conn.rtmp.SendMsg(msg, this.StreamId)
-			_ = err
+		if len(msgs) > 0 {
+			err := this.conn.rtmp.SendMessages(msgs, this.StreamId)
+			for i := 0; i < len(msgs); i++ {
+				msgs[i].Free()
+			}
+			if err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+/**
+ * interrupt the wait of consumer, to process the control messages.
+ */
+func (this *SrsConsumer) Wakeup() {
+	this.queue.Wakeup()
+}
+
+/**
+ * the connection routes the control messages of the stream to consumer,
+ * for example, the pause and seek of player.
+ */
+func (this *SrsConsumer) Handle(msg *rtmp.SrsRtmpMessage) error {
+	return this.queueRecvThread.Handle(msg)
+}
+
+/**
+ * stop the consume cycle, the connection decides whether to close itself.
+ */
 func (this *SrsConsumer) StopConsume() error {
-	this.conn.Close()
-	this.queueRecvThread.Stop()
 	this.queue.Break()
 	return nil
 }
@@ -114,23 +156,39 @@ func (this *SrsConsumer) processPlayControlMsg(msg *rtmp.SrsRtmpMessage) error {
 		return err
 	}
 	//todo add callpacket
-	//todo process pause message
-	switch pkt.(type) {
-	case *packet.SrsCloseStreamPacket:
+	switch p := pkt.(type) {
+	case *packet.SrsPausePacket:
 		{
-			//todo fix close stream action
-			return errors.New("get close stream packet")
+			return this.onPlayClientPause(p.IsPause.Value)
 		}
-	case *packet.SrsPausePacket:
+	case *packet.SrsSeekPacket:
 		{
-			//todo pause stream
-			return nil
+			// the live stream is not seekable.
+			return this.conn.rtmp.OnPlayClientSeek(this.StreamId, false, p.TimeMs.Value)
 		}
 	}
 	return nil
 }
 
+func (this *SrsConsumer) onPlayClientPause(isPause bool) error {
+	if this.paused == isPause {
+		return nil
+	}
+
+	if err := this.conn.rtmp.OnPlayClientPause(this.StreamId, isPause); err != nil {
+		return err
+	}
+
+	this.paused = isPause
+	log.Info("consumer pause=", isPause)
+	return nil
+}
+
 //todo add rtmp jitter algorithm
-func (this *SrsConsumer) Enqueue(msg *rtmp.SrsRtmpMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
-	this.queue.Enqueue(msg)
+func (this *SrsConsumer) AcceptTrack(msg *rtmp.SrsSharedPtrMessage) bool {
+	return this.tracks.accept(msg)
+}
+
+func (this *SrsConsumer) Enqueue(msg *rtmp.SrsSharedPtrMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
+	this.queue.Enqueue(msg.Copy())
 }
@@ -0,0 +1,613 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"go_srs/srs/app/config"
+	"go_srs/srs/global"
+	"go_srs/srs/protocol/packet"
+	"go_srs/srs/protocol/rtmp"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * the state of play edge.
+ */
+const (
+	// the edge is created, not pulling yet.
+	SrsEdgeStateInit = iota
+	// the edge is pulling stream from origin.
+	SrsEdgeStateIngesting
+	// the edge is stopped for no player, the source is removed from pool.
+	SrsEdgeStateStopped
+)
+
+/**
+ * the interval to retry the next origin when the edge fails to pull.
+ */
+const SRS_EDGE_INGESTER_RETRY_INTERVAL = 3 * time.Second
+
+/**
+ * the recv timeout of edge ingester, the origin which sends nothing maybe not publishing
+ * the stream or stalled, pull from the next origin.
+ */
+const SRS_EDGE_INGESTER_TIMEOUT = 5 * time.Second
+
+/**
+ * the player should fetch a new source when the edge of source is stopped.
+ */
+var errEdgeStopped = errors.New("edge stopped")
+
+/**
+ * the publisher is rejected when another publisher is publishing the stream on edge.
+ */
+var errEdgePublishing = errors.New("edge stream is publishing")
+
+/**
+ * the play edge of source, starts to pull stream from origin when the first player comes,
+ * and stops it when no player for the edge idle timeout.
+ */
+type SrsPlayEdge struct {
+	mtx      sync.Mutex
+	source   *SrsSource
+	req      *SrsRequest
+	state    int
+	players  int
+	ingester *SrsEdgeIngester
+	// stop the edge when fired, reset when player comes.
+	idle *time.Timer
+	// the generation of idle timer, the stale timer fired is ignored.
+	idleGeneration int
+}
+
+func NewSrsPlayEdge(s *SrsSource, r *SrsRequest) *SrsPlayEdge {
+	return &SrsPlayEdge{
+		source: s,
+		req:    r,
+		state:  SrsEdgeStateInit,
+	}
+}
+
+/**
+ * when player comes, start the ingester if not yet.
+ * @return errEdgeStopped when the edge is stopped, the player should fetch a new source.
+ */
+func (this *SrsPlayEdge) OnClientPlay() error {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+
+	if this.state == SrsEdgeStateStopped {
+		return errEdgeStopped
+	}
+
+	this.players++
+	if this.idle != nil {
+		this.idle.Stop()
+		this.idle = nil
+		this.idleGeneration++
+	}
+
+	if this.state == SrsEdgeStateInit {
+		this.ingester = NewSrsEdgeIngester(this.source, this.req)
+		this.ingester.Start()
+		this.state = SrsEdgeStateIngesting
+	}
+	return nil
+}
+
+/**
+ * when player leaves, stop the edge after idle timeout if no player.
+ */
+func (this *SrsPlayEdge) OnClientStop() {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+
+	this.players--
+	if this.players > 0 || this.state != SrsEdgeStateIngesting {
+		return
+	}
+
+	timeout := time.Duration(config.GetEdgeIdleTimeout(this.req.vhost)) * time.Second
+	generation := this.idleGeneration
+	this.idle = time.AfterFunc(timeout, func() {
+		this.onIdle(generation)
+	})
+}
+
+func (this *SrsPlayEdge) onIdle(generation int) {
+	this.mtx.Lock()
+	// the timer is stopped when player comes, but maybe fired already.
+	if this.idleGeneration != generation || this.players > 0 {
+		this.mtx.Unlock()
+		return
+	}
+	this.idle = nil
+	this.state = SrsEdgeStateStopped
+	// remove the source in lock, the new player never fetches the stopped source.
+	RemoveSrsSource(this.source)
+	this.mtx.Unlock()
+
+	log.Info("edge stop pulling for no player, url=", this.req.GetStreamUrl())
+	this.ingester.Stop()
+	this.source.RemoveConsumers()
+	if this.ingester.published {
+		this.source.UnPublish()
+	}
+}
+
+/**
+ * remove the source never played, the player should fetch a new source.
+ */
+func (this *SrsPlayEdge) StopIfIdle() {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+
+	if this.state == SrsEdgeStateInit {
+		this.state = SrsEdgeStateStopped
+		RemoveSrsSource(this.source)
+	}
+}
+
+/**
+ * the ingester of edge, pulls stream from origin and feeds the source,
+ * the origins are selected in round robin, try next origin when fails.
+ */
+type SrsEdgeIngester struct {
+	source *SrsSource
+	req    *SrsRequest
+	// the round robin index of origins.
+	lb uint32
+	// whether source is published, when pulled the stream from origin.
+	published bool
+	mtx       sync.Mutex
+	client    *rtmp.SrsRtmpClient
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan bool
+}
+
+func NewSrsEdgeIngester(s *SrsSource, r *SrsRequest) *SrsEdgeIngester {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SrsEdgeIngester{
+		source: s,
+		req:    r,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan bool),
+	}
+}
+
+func (this *SrsEdgeIngester) Start() {
+	go this.cycle()
+}
+
+/**
+ * stop the ingester, close the client to interrupt the pulling and wait for it.
+ */
+func (this *SrsEdgeIngester) Stop() {
+	this.cancel()
+	this.mtx.Lock()
+	if this.client != nil {
+		this.client.Close()
+	}
+	this.mtx.Unlock()
+	<-this.done
+}
+
+func (this *SrsEdgeIngester) cycle() {
+	defer close(this.done)
+	for {
+		err := this.ingest()
+		if this.ctx.Err() != nil {
+			return
+		}
+		log.Info("edge pull failed, retry next origin, err=", err)
+
+		select {
+		case <-this.ctx.Done():
+			return
+		case <-time.After(SRS_EDGE_INGESTER_RETRY_INTERVAL):
+		}
+	}
+}
+
+/**
+ * connect the origin of tcUrl, for example, rtmp://127.0.0.1:1935/live?vhost=srs.net
+ * the origin without schema is rtmp.
+ */
+func srs_edge_tc_url(origin string, req *SrsRequest) string {
+	if !strings.Contains(origin, "://") {
+		origin = "rtmp://" + origin
+	}
+
+	tcUrl := origin + "/" + req.app
+	if req.vhost != "" {
+		tcUrl += "?vhost=" + req.vhost
+	}
+	return tcUrl
+}
+
+func (this *SrsEdgeIngester) selectOrigin() (string, error) {
+	origins := config.GetVhostEdgeOrigin(this.req.vhost)
+	if len(origins) == 0 {
+		return "", errors.New("edge no origin")
+	}
+
+	origin := origins[this.lb%uint32(len(origins))]
+	this.lb++
+	return origin, nil
+}
+
+func (this *SrsEdgeIngester) setClient(client *rtmp.SrsRtmpClient) bool {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if this.ctx.Err() != nil {
+		return false
+	}
+	this.client = client
+	return true
+}
+
+func (this *SrsEdgeIngester) ingest() error {
+	origin, err := this.selectOrigin()
+	if err != nil {
+		return err
+	}
+
+	tcUrl := srs_edge_tc_url(origin, this.req)
+	ctx, cancel := context.WithTimeout(this.ctx, rtmp.SRS_CONSTS_RTMP_TIMEOUT)
+	defer cancel()
+	client, err := rtmp.DialSrsRtmpClient(ctx, tcUrl)
+	if err != nil {
+		return err
+	}
+
+	if !this.setClient(client) {
+		client.Close()
+		return errEdgeStopped
+	}
+	defer client.Close()
+	// the origin which never responses is as dead.
+	client.SetRecvTimeout(SRS_EDGE_INGESTER_TIMEOUT)
+
+	if err := client.HandShakeContext(ctx); err != nil {
+		return err
+	}
+
+	if _, err := client.ConnectApp(this.req.app, tcUrl, this.req.pageUrl, this.req.swfUrl); err != nil {
+		return err
+	}
+
+	streamId, err := client.CreateStream()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Play(this.req.stream, streamId); err != nil {
+		return err
+	}
+	log.Info("edge pull stream from ", tcUrl, ", stream=", this.req.stream)
+
+	if !this.published {
+		this.published = true
+		if err := this.source.onPublish(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		recvCtx, recvCancel := context.WithTimeout(this.ctx, SRS_EDGE_INGESTER_TIMEOUT)
+		msg, err := client.RecvMessageContext(recvCtx)
+		recvCancel()
+		if err != nil {
+			return err
+		}
+
+		if err := this.processMessage(client, msg); err != nil {
+			return err
+		}
+	}
+}
+
+/**
+ * feed the audio, video and metadata of origin to source,
+ * the error of frame is ignored as the publisher, never stop pulling for a bad frame.
+ */
+func (this *SrsEdgeIngester) processMessage(client *rtmp.SrsRtmpClient, msg *rtmp.SrsRtmpMessage) error {
+	header := msg.GetHeader()
+	if header.IsAudio() {
+		this.source.OnAudio(msg)
+		return nil
+	}
+
+	if header.IsVideo() {
+		this.source.OnVideo(msg)
+		return nil
+	}
+
+	if header.IsAggregate() {
+		msgs, err := msg.DemuxAggregate()
+		if err != nil {
+			return err
+		}
+
+		for _, m := range msgs {
+			if err := this.processMessage(client, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if header.IsAmf0Data() || header.IsAmf3Data() {
+		pkt, err := client.DecodeMessage(msg)
+		if err != nil {
+			return err
+		}
+
+		if p, ok := pkt.(*packet.SrsOnMetaDataPacket); ok {
+			return this.source.OnMetaData(msg, p)
+		}
+	}
+	return nil
+}
+
+/**
+ * the handler of edge forwarder, relays the status and error of origin to publisher.
+ */
+type ISrsEdgeForwarderHandler interface {
+	OnEdgeStatus(pkt *packet.SrsOnStatusCallPacket) error
+	OnEdgeError(err error)
+}
+
+/**
+ * the publish edge of source, relays the publisher to origin,
+ * only one publisher is allowed for each stream.
+ */
+type SrsPublishEdge struct {
+	mtx        sync.Mutex
+	req        *SrsRequest
+	publishing bool
+	forwarder  *SrsEdgeForwarder
+}
+
+func NewSrsPublishEdge(r *SrsRequest) *SrsPublishEdge {
+	return &SrsPublishEdge{
+		req: r,
+	}
+}
+
+/**
+ * when publisher comes, publish the stream to origin.
+ * @return the status of origin, which is relayed to publisher when origin rejects.
+ */
+func (this *SrsPublishEdge) OnClientPublish(h ISrsEdgeForwarderHandler) (*packet.SrsOnStatusCallPacket, error) {
+	this.mtx.Lock()
+	if this.publishing {
+		this.mtx.Unlock()
+		return nil, errEdgePublishing
+	}
+	this.publishing = true
+	this.mtx.Unlock()
+
+	forwarder := NewSrsEdgeForwarder(this.req, h)
+	status, err := forwarder.Start()
+
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if err != nil {
+		this.publishing = false
+		return status, err
+	}
+	this.forwarder = forwarder
+	return status, nil
+}
+
+func (this *SrsPublishEdge) OnProxyPublish(msg *rtmp.SrsRtmpMessage) error {
+	this.mtx.Lock()
+	forwarder := this.forwarder
+	this.mtx.Unlock()
+
+	if forwarder == nil {
+		return errors.New("edge stream not publishing")
+	}
+	return forwarder.Proxy(msg)
+}
+
+func (this *SrsPublishEdge) OnProxyUnpublish() {
+	this.mtx.Lock()
+	forwarder := this.forwarder
+	this.forwarder = nil
+	this.publishing = false
+	this.mtx.Unlock()
+
+	if forwarder != nil {
+		forwarder.Stop()
+	}
+}
+
+/**
+ * the forwarder of edge, publishes the stream to origin and proxies the messages of publisher,
+ * the origins are tried in turn until one accepts.
+ */
+type SrsEdgeForwarder struct {
+	req      *SrsRequest
+	handler  ISrsEdgeForwarderHandler
+	client   *rtmp.SrsRtmpClient
+	streamId int
+	// closed when the publisher stops, the error of origin is not notified.
+	exit chan bool
+	done chan bool
+}
+
+func NewSrsEdgeForwarder(r *SrsRequest, h ISrsEdgeForwarderHandler) *SrsEdgeForwarder {
+	return &SrsEdgeForwarder{
+		req:     r,
+		handler: h,
+		exit:    make(chan bool),
+		done:    make(chan bool),
+	}
+}
+
+/**
+ * publish the stream to origin, start to relay the status of origin when accepted.
+ */
+func (this *SrsEdgeForwarder) Start() (*packet.SrsOnStatusCallPacket, error) {
+	origins := config.GetVhostEdgeOrigin(this.req.vhost)
+	if len(origins) == 0 {
+		return nil, errors.New("edge no origin")
+	}
+
+	var status *packet.SrsOnStatusCallPacket
+	var err error
+	for _, origin := range origins {
+		if status, err = this.connect(origin); err == nil {
+			go this.cycle()
+			return status, nil
+		}
+
+		// the origin rejects the stream, never try others.
+		if status != nil {
+			return status, err
+		}
+		log.Info("edge publish to origin ", origin, " failed, err=", err)
+	}
+	return nil, err
+}
+
+func (this *SrsEdgeForwarder) connect(origin string) (*packet.SrsOnStatusCallPacket, error) {
+	tcUrl := srs_edge_tc_url(origin, this.req)
+	ctx, cancel := context.WithTimeout(context.Background(), rtmp.SRS_CONSTS_RTMP_TIMEOUT)
+	defer cancel()
+	client, err := rtmp.DialSrsRtmpClient(ctx, tcUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	status, streamId, err := srs_edge_publish(ctx, client, this.req, tcUrl)
+	if err != nil {
+		client.Close()
+		return status, err
+	}
+	log.Info("edge publish stream to ", tcUrl, ", stream=", this.req.stream)
+
+	this.client = client
+	this.streamId = streamId
+	return status, nil
+}
+
+/**
+ * publish the stream over the client, and wait for the onStatus of origin.
+ */
+func srs_edge_publish(ctx context.Context, client *rtmp.SrsRtmpClient, req *SrsRequest, tcUrl string) (*packet.SrsOnStatusCallPacket, int, error) {
+	if err := client.HandShakeContext(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	if _, err := client.ConnectApp(req.app, tcUrl, req.pageUrl, req.swfUrl); err != nil {
+		return nil, 0, err
+	}
+
+	streamId, err := client.CreateStream()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := client.Publish(req.stream, streamId); err != nil {
+		return nil, 0, err
+	}
+
+	for {
+		msg, err := client.RecvMessageContext(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		pkt, err := client.DecodeMessage(msg)
+		if err != nil {
+			continue
+		}
+
+		if status, ok := pkt.(*packet.SrsOnStatusCallPacket); ok {
+			var level string
+			_ = status.Data.Get(global.StatusLevel, &level)
+			if level == global.StatusLevelError {
+				return status, 0, errors.New("origin rejects the publish")
+			}
+			return status, streamId, nil
+		}
+	}
+}
+
+/**
+ * relay the status of origin to publisher, notify the publisher when origin fails.
+ */
+func (this *SrsEdgeForwarder) cycle() {
+	defer close(this.done)
+	for {
+		msg, err := this.client.RecvMessage()
+		if err != nil {
+			select {
+			case <-this.exit:
+			default:
+				this.handler.OnEdgeError(err)
+			}
+			return
+		}
+
+		pkt, err := this.client.DecodeMessage(msg)
+		if err != nil {
+			continue
+		}
+
+		if status, ok := pkt.(*packet.SrsOnStatusCallPacket); ok {
+			if err := this.handler.OnEdgeStatus(status); err != nil {
+				this.handler.OnEdgeError(err)
+				return
+			}
+		}
+	}
+}
+
+/**
+ * proxy the audio, video and data messages of publisher to origin.
+ */
+func (this *SrsEdgeForwarder) Proxy(msg *rtmp.SrsRtmpMessage) error {
+	header := msg.GetHeader()
+	if !header.IsAudio() && !header.IsVideo() && !header.IsAggregate() && !header.IsAmf0Data() && !header.IsAmf3Data() {
+		return nil
+	}
+	return this.client.SendMsg(msg, this.streamId)
+}
+
+func (this *SrsEdgeForwarder) Stop() {
+	close(this.exit)
+	this.client.Close()
+	<-this.done
+}
@@ -0,0 +1,323 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"go_srs/srs/app/config"
+	"go_srs/srs/codec/flv"
+	"go_srs/srs/protocol/rtmp"
+	"go_srs/srs/utils"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/**
+ * the state of forward, reported to statistic.
+ */
+const (
+	// connecting to the destination.
+	SRS_FORWARD_STATE_CONNECTING = "connecting"
+	// publishing the stream to the destination.
+	SRS_FORWARD_STATE_FORWARDING = "forwarding"
+	// the forward fails, waiting to reconnect.
+	SRS_FORWARD_STATE_RECONNECTING = "reconnecting"
+)
+
+/**
+ * the type of forwarder, forward to the destinations of vhost,
+ * or push to the destinations added by http api or hooks at runtime.
+ */
+const (
+	SRS_FORWARD_TYPE_FORWARD = "forward"
+	SRS_FORWARD_TYPE_PUSH    = "push"
+)
+
+/**
+ * the interval to reconnect the destination, doubled for each failure to the max,
+ * and reset when the destination is connected.
+ */
+const SRS_FORWARDER_MIN_RETRY_INTERVAL = 1 * time.Second
+const SRS_FORWARDER_MAX_RETRY_INTERVAL = 30 * time.Second
+
+var errForwarderStopped = errors.New("forwarder stopped")
+
+/**
+ * the forwarder consumes the published stream of origin, and publishes it to the destination,
+ * for example, 127.0.0.1:19350 or rtmp://127.0.0.1:19350/live/[stream]?token=xxx
+ */
+type SrsForwarder struct {
+	id          int64
+	typ         string
+	source      *SrsSource
+	req         *SrsRequest
+	destination string
+	queue       *SrsMessageQueue
+	algorithm   SrsRtmpJitterAlgorithm
+	mwLatency   time.Duration
+	// whether the destination is connected, only the latest gop of media is buffered when disconnected,
+	// the metadata and sequence headers are copied from source when connected.
+	connected int32
+
+	mtx    sync.Mutex
+	client *rtmp.SrsRtmpClient
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewSrsForwarder(s *SrsSource, r *SrsRequest, destination string, typ string) *SrsForwarder {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SrsForwarder{
+		id:          utils.SrsGenerateId(),
+		typ:         typ,
+		source:      s,
+		req:         r,
+		destination: destination,
+		queue:       NewSrsMessageQueue(),
+		algorithm:   srs_time_jitter_string2int(config.GetTimeJitter(r.vhost)),
+		mwLatency:   time.Duration(config.GetMwLatency(r.vhost)) * time.Millisecond,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+func (this *SrsForwarder) OnPublish() error {
+	return nil
+}
+
+func (this *SrsForwarder) OnUnpublish() error {
+	return nil
+}
+
+func (this *SrsForwarder) ConsumeCycle() error {
+	stat := GetStatisticInstance()
+	stat.OnForward(this.id, this.typ, this.req, this.destination)
+	defer stat.OnForwardClose(this.id)
+
+	interval := SRS_FORWARDER_MIN_RETRY_INTERVAL
+	for {
+		connected, err := this.forward()
+		if this.ctx.Err() != nil {
+			return nil
+		}
+
+		if connected {
+			interval = SRS_FORWARDER_MIN_RETRY_INTERVAL
+		}
+		log.Warn("forward ", this.req.GetStreamUrl(), " to ", this.destination, " failed, retry in ", interval, ", err=", err)
+		stat.OnForwardError(this.id, err)
+
+		select {
+		case <-this.ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+
+		interval *= 2
+		if interval > SRS_FORWARDER_MAX_RETRY_INTERVAL {
+			interval = SRS_FORWARDER_MAX_RETRY_INTERVAL
+		}
+		// drop the messages of the failure, keep the latest gop of media.
+		this.queue.ShrinkToKeyframe()
+		this.queue.RemoveIf(srs_forward_not_media)
+	}
+}
+
+func (this *SrsForwarder) setClient(client *rtmp.SrsRtmpClient) bool {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if this.ctx.Err() != nil {
+		return false
+	}
+	this.client = client
+	return true
+}
+
+/**
+ * connect and publish to the destination, then forward the messages until fails.
+ * @return whether the destination is connected.
+ */
+func (this *SrsForwarder) forward() (bool, error) {
+	GetStatisticInstance().OnForwardState(this.id, SRS_FORWARD_STATE_CONNECTING)
+
+	tcUrl, req, err := srs_forward_parse(this.destination, this.req)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(this.ctx, rtmp.SRS_CONSTS_RTMP_TIMEOUT)
+	defer cancel()
+	client, err := rtmp.DialSrsRtmpClient(ctx, tcUrl)
+	if err != nil {
+		return false, err
+	}
+
+	if !this.setClient(client) {
+		client.Close()
+		return false, errForwarderStopped
+	}
+	defer client.Close()
+
+	_, streamId, err := srs_edge_publish(ctx, client, req, tcUrl)
+	if err != nil {
+		return false, err
+	}
+	log.Info("forward ", this.req.GetStreamUrl(), " to ", tcUrl, ", stream=", req.stream)
+
+	// drop the messages of destination, the send fails when destination closes.
+	go func() {
+		for {
+			if _, err := client.RecvMessage(); err != nil {
+				client.Close()
+				return
+			}
+		}
+	}()
+
+	GetStatisticInstance().OnForwardState(this.id, SRS_FORWARD_STATE_FORWARDING)
+	atomic.StoreInt32(&this.connected, 1)
+	defer atomic.StoreInt32(&this.connected, 0)
+
+	// the destination is a new stream, correct the time from start,
+	// and start with the metadata and sequence headers, then the media of queue.
+	jitter := NewSrsRtmpJitter()
+	if err := this.send(client, streamId, jitter, this.source.copySequenceHeaders(this)); err != nil {
+		return true, err
+	}
+
+	for {
+		msgs, err := this.queue.WaitBatch(this.mwLatency, SRS_PERF_MW_MSGS)
+		if err != nil {
+			return true, err
+		}
+
+		if err := this.send(client, streamId, jitter, msgs); err != nil {
+			return true, err
+		}
+	}
+}
+
+/**
+ * correct the time and send the msgs to destination, the msgs are freed.
+ */
+func (this *SrsForwarder) send(client *rtmp.SrsRtmpClient, streamId int, jitter *SrsRtmpJitter, msgs []*rtmp.SrsSharedPtrMessage) error {
+	if len(msgs) <= 0 {
+		return nil
+	}
+
+	for i := 0; i < len(msgs); i++ {
+		jitter.Correct(msgs[i], this.algorithm)
+	}
+
+	sendBytes := client.GetSendBytes()
+	err := client.SendMessages(msgs, streamId)
+	for i := 0; i < len(msgs); i++ {
+		msgs[i].Free()
+	}
+
+	GetStatisticInstance().OnForwardBytes(this.id, client.GetSendBytes()-sendBytes)
+	return err
+}
+
+/**
+ * the metadata and sequence headers are not media, which are sent from source when connected.
+ */
+func srs_forward_not_media(msg *rtmp.SrsSharedPtrMessage) bool {
+	h := msg.GetHeader()
+	if h.IsVideo() {
+		return flvcodec.VideoIsSequenceHeader(msg.GetPayload())
+	}
+	if h.IsAudio() {
+		return flvcodec.AudioIsSequenceHeader(msg.GetPayload())
+	}
+	return true
+}
+
+/**
+ * parse the destination of forward, the host[:port] to publish the same app and stream,
+ * or the url rtmp://host[:port]/app/stream?params to publish the app and stream of url,
+ * the params are appended to the stream name, and the [vhost], [app] and [stream]
+ * are replaced by the request.
+ * @return the tcUrl to connect and the request to publish.
+ */
+func srs_forward_parse(destination string, req *SrsRequest) (string, *SrsRequest, error) {
+	destination = strings.NewReplacer("[vhost]", req.vhost, "[app]", req.app, "[stream]", req.stream).Replace(destination)
+
+	r := req.Copy()
+	if !strings.Contains(destination, "://") {
+		return srs_edge_tc_url(destination, req), r, nil
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return "", nil, err
+	}
+
+	p := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(p) > 1 {
+		r.app = strings.Join(p[:len(p)-1], "/")
+		r.stream = p[len(p)-1]
+	} else if p[0] != "" {
+		r.app = p[0]
+	}
+
+	if u.RawQuery != "" {
+		r.stream += "?" + u.RawQuery
+	}
+	return u.Scheme + "://" + u.Host + "/" + r.app, r, nil
+}
+
+func (this *SrsForwarder) StopConsume() error {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	this.cancel()
+	if this.client != nil {
+		this.client.Close()
+	}
+	this.queue.Break()
+	return nil
+}
+
+func (this *SrsForwarder) OnRecvError(err error) {
+	this.source.OnConsumerError(this)
+}
+
+func (this *SrsForwarder) Enqueue(msg *rtmp.SrsSharedPtrMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
+	// the metadata and sequence headers are sent in order when connected,
+	// or sent from source when connected again.
+	if atomic.LoadInt32(&this.connected) == 0 && srs_forward_not_media(msg) {
+		return
+	}
+	this.queue.Enqueue(msg.Copy())
+
+	// the destination is disconnected, keep the latest gop to start from the keyframe.
+	if atomic.LoadInt32(&this.connected) == 0 && msg.GetHeader().IsVideo() && flvcodec.VideoIsKeyFrame(msg.GetPayload()) {
+		this.queue.ShrinkToKeyframe()
+	}
+}
@@ -34,7 +34,7 @@ const SRS_PURE_AUDIO_GUESS_COUNT = 115
 
 type SrsGopCache struct {
 	enabled                  bool
-	gopCache                 []*rtmp.SrsRtmpMessage
+	gopCache                 []*rtmp.SrsSharedPtrMessage
 	cachedVideoCount         uint32
 	audioAfterLastVideoCount uint32
 }
@@ -42,7 +42,7 @@ type SrsGopCache struct {
 func NewSrsGopCache() *SrsGopCache {
 	return &SrsGopCache{
 		enabled:                  true,
-		gopCache:                 make([]*rtmp.SrsRtmpMessage, 0),
+		gopCache:                 make([]*rtmp.SrsSharedPtrMessage, 0),
 		cachedVideoCount:         0,
 		audioAfterLastVideoCount: 0,
 	}
@@ -55,14 +55,14 @@ func (this *SrsGopCache) set(enabled bool) {
 	}
 }
 
-func (this *SrsGopCache) cache(msg *rtmp.SrsRtmpMessage) error {
+func (this *SrsGopCache) cache(msg *rtmp.SrsSharedPtrMessage) error {
 	if !this.enabled {
 		return nil
 	}
 
 	if msg.GetHeader().IsVideo() {
-		if !flvcodec.VideoIsH264(msg.GetPayload()) {
-			return errors.New("cache failed, video data is not h264")
+		if !flvcodec.VideoIsAcceptable(msg.GetPayload()) {
+			return errors.New("cache failed, video data is not acceptable")
 		}
 		this.cachedVideoCount++
 		this.audioAfterLastVideoCount = 0
@@ -80,17 +80,20 @@ func (this *SrsGopCache) cache(msg *rtmp.SrsRtmpMessage) error {
 		this.clear()
 		return errors.New("cache failed, audio cache overflow detected")
 	}
-	//clear gop cache when got key frame
-	if msg.GetHeader().IsVideo() && flvcodec.VideoIsKeyFrame(msg.GetPayload()) {
+	//clear gop cache when got key frame, the gop is of the default track for multitrack.
+	if msg.GetHeader().IsVideo() && msg.GetTrackId() == 0 && flvcodec.VideoIsKeyFrame(msg.GetPayload()) {
 		this.clear()
 		this.cachedVideoCount = 1
 	}
 
-	this.gopCache = append(this.gopCache, msg)
+	this.gopCache = append(this.gopCache, msg.Copy())
 	return nil
 }
 
 func (this *SrsGopCache) clear() {
+	for i := 0; i < len(this.gopCache); i++ {
+		this.gopCache[i].Free()
+	}
 	this.gopCache = this.gopCache[0:0]
 	this.cachedVideoCount = 0
 	this.audioAfterLastVideoCount = 0
@@ -114,7 +117,7 @@ func (this *SrsGopCache) pureAudio() bool {
 
 func (this *SrsGopCache) dump(consumer Consumer, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) error {
 	for i := 0; i < len(this.gopCache); i++ {
-		consumer.Enqueue(this.gopCache[i], atc, jitterAlgorithm)
+		srs_consumer_enqueue(consumer, this.gopCache[i], atc, jitterAlgorithm)
 	}
 	fmt.Println("****************dump count=", len(this.gopCache), "****************")
 	return nil
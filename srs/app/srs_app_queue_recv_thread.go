@@ -25,32 +25,24 @@ package app
 
 import (
 	"go_srs/srs/protocol/rtmp"
+	"sync"
 )
 
+/**
+ * the recv queue of player, the recv thread of connection routes the
+ * control messages of the stream to it, and wakeup the consumer to process them.
+ */
 type SrsQueueRecvThread struct {
-	queue      []*rtmp.SrsRtmpMessage
-	consumer   *SrsConsumer
-	rtmp       *rtmp.SrsRtmpServer
-	recvThread *SrsRecvThread
+	lock     sync.Mutex
+	queue    []*rtmp.SrsRtmpMessage
+	consumer *SrsConsumer
 }
 
-func NewSrsQueueRecvThread(c *SrsConsumer, s *rtmp.SrsRtmpServer) *SrsQueueRecvThread {
-	st := &SrsQueueRecvThread{
-		queue:    make([]*rtmp.SrsRtmpMessage, 1000),
+func NewSrsQueueRecvThread(c *SrsConsumer) *SrsQueueRecvThread {
+	return &SrsQueueRecvThread{
+		queue:    make([]*rtmp.SrsRtmpMessage, 0),
 		consumer: c,
-		rtmp:     s,
 	}
-
-	st.recvThread = NewSrsRecvThread(s, st, 1000)
-	return st
-}
-
-func (this *SrsQueueRecvThread) Start() {
-	this.recvThread.Start()
-}
-
-func (this *SrsQueueRecvThread) Stop() {
-	this.recvThread.Stop()
 }
 
 func (this *SrsQueueRecvThread) CanHandle() bool {
@@ -58,23 +50,29 @@ func (this *SrsQueueRecvThread) CanHandle() bool {
 }
 
 func (this *SrsQueueRecvThread) Handle(msg *rtmp.SrsRtmpMessage) error {
-
 	//todo fix cid change
 	//todo nbmsg++
+	this.lock.Lock()
 	this.queue = append(this.queue, msg)
+	this.lock.Unlock()
+	this.consumer.Wakeup()
 	return nil
 }
 
 func (this *SrsQueueRecvThread) Size() int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
 	return len(this.queue)
 }
 
 func (this *SrsQueueRecvThread) Empty() bool {
-	return len(this.queue) == 0
+	return this.Size() == 0
 }
 
 func (this *SrsQueueRecvThread) GetMsg() *rtmp.SrsRtmpMessage {
-	if this.Empty() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if len(this.queue) == 0 {
 		return nil
 	}
 
@@ -83,11 +81,6 @@ func (this *SrsQueueRecvThread) GetMsg() *rtmp.SrsRtmpMessage {
 	return m
 }
 
-func (this *SrsQueueRecvThread) OnRecvError(err error) {
-	this.consumer.OnRecvError(err)
-	return
-}
-
 func (this *SrsQueueRecvThread) OnThreadStart() {
 	return
 }
@@ -24,10 +24,18 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package app
 
 import (
+	"errors"
 	log "github.com/sirupsen/logrus"
 	"go_srs/srs/protocol/rtmp"
+	"sync"
 )
 
+/**
+ * the handler returns it to quit the recv thread normally,
+ * for example, the closeStream of player, the handler not notified.
+ */
+var ErrRecvThreadQuit = errors.New("recv thread quit")
+
 type ISrsMessageHandler interface {
 	Handle(msg *rtmp.SrsRtmpMessage) error
 	OnRecvError(err error)
@@ -39,6 +47,7 @@ type SrsRecvThread struct {
 	timeout int32
 	exit    chan bool
 	done    chan bool
+	stop    sync.Once
 }
 
 func NewSrsRecvThread(r *rtmp.SrsRtmpServer, h ISrsMessageHandler, timeoutMS int32) *SrsRecvThread {
@@ -63,6 +72,10 @@ DONE:
 			err = this.handler.Handle(msg)
 		}
 
+		if err == ErrRecvThreadQuit {
+			break DONE
+		}
+
 		if err != nil {
 			this.handler.OnRecvError(err)
 			close(this.done)
@@ -87,7 +100,9 @@ DONE:
 }
 
 func (this *SrsRecvThread) Stop() {
-	close(this.exit) //直接关闭，避免cycle先退出
+	this.stop.Do(func() {
+		close(this.exit) //直接关闭，避免cycle先退出
+	})
 }
 
 func (this *SrsRecvThread) Join() {
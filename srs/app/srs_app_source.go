@@ -25,6 +25,9 @@ package app
 
 import (
 	"errors"
+	log "github.com/sirupsen/logrus"
+	"go_srs/srs/app/config"
+	"go_srs/srs/codec"
 	"go_srs/srs/codec/flv"
 	"go_srs/srs/global"
 	"go_srs/srs/protocol/packet"
@@ -39,7 +42,7 @@ type ISrsSourceHandler interface {
 }
 
 type SrsSHRequester interface {
-	GetSH(metaData *rtmp.SrsRtmpMessage, audioSH *rtmp.SrsRtmpMessage, videoSH *rtmp.SrsRtmpMessage)
+	GetSH(metaData *rtmp.SrsSharedPtrMessage, audioSH *rtmp.SrsSharedPtrMessage, videoSH *rtmp.SrsSharedPtrMessage)
 }
 
 type SrsSource struct {
@@ -52,9 +55,12 @@ type SrsSource struct {
 	consumersMtx  sync.Mutex
 	consumers     []Consumer
 	gopCache      *SrsGopCache
-	cacheSHVideo  *rtmp.SrsRtmpMessage
-	cacheSHAudio  *rtmp.SrsRtmpMessage
-	cacheMetaData *rtmp.SrsRtmpMessage
+	cacheSHVideo  *rtmp.SrsSharedPtrMessage
+	cacheSHAudio  *rtmp.SrsSharedPtrMessage
+	cacheMetaData *rtmp.SrsSharedPtrMessage
+	// the sequence headers of other tracks than the default track 0, for enhanced rtmp multitrack.
+	cacheSHVideoTracks map[uint8]*rtmp.SrsSharedPtrMessage
+	cacheSHAudioTracks map[uint8]*rtmp.SrsSharedPtrMessage
 
 	/**
 	 * atc whether atc(use absolute time and donot adjust time),
@@ -64,8 +70,18 @@ type SrsSource struct {
 	// TODO: FIXME: to support reload atc.
 	atc             bool
 	jitterAlgorithm *SrsRtmpJitterAlgorithm
+	// the play edge to pull stream from origin, nil for origin.
+	edge *SrsPlayEdge
+	// the publish edge to relay publisher to origin, nil for origin.
+	publishEdge *SrsPublishEdge
+	// the pushes added by http api or hooks when publishing, the key is id of forwarder.
+	pushesMtx  sync.Mutex
+	publishing bool
+	pushes     map[int64]*SrsForwarder
 }
 
+var errPushNotFound = errors.New("push not found")
+
 var sourcePoolMtx sync.Mutex
 var sourcePool map[string]*SrsSource
 
@@ -75,13 +91,26 @@ func init() {
 
 func NewSrsSource(c *SrsRtmpConn, r *SrsRequest, h ISrsSourceHandler) *SrsSource {
 	source := &SrsSource{
-		source_id: c.id,
-		req:       r,
-		conn:      c,
-		handler:   h,
-		rtmp:      c.rtmp,
-		gopCache:  NewSrsGopCache(),
-		atc:       false,
+		req:      r,
+		handler:  h,
+		gopCache: NewSrsGopCache(),
+		atc:      false,
+
+		cacheSHVideoTracks: make(map[uint8]*rtmp.SrsSharedPtrMessage),
+		cacheSHAudioTracks: make(map[uint8]*rtmp.SrsSharedPtrMessage),
+		pushes:             make(map[int64]*SrsForwarder),
+	}
+
+	// the source of http stream player is created without rtmp conn.
+	if c != nil {
+		source.source_id = c.id
+		source.conn = c
+		source.rtmp = c.rtmp
+	}
+
+	if config.GetVhostIsEdge(r.vhost) {
+		source.edge = NewSrsPlayEdge(source, r)
+		source.publishEdge = NewSrsPublishEdge(r)
 	}
 
 	dvrConsumer := NewSrsDvrConsumer(source, r)
@@ -202,11 +231,76 @@ func (this *SrsSource) onPublish() error {
 		}
 	}
 
+	this.startForwarders()
+
+	this.pushesMtx.Lock()
+	this.publishing = true
+	this.pushesMtx.Unlock()
+
 	stat := GetStatisticInstance()
 	stat.OnStreamPublish(this.req, this.source_id)
 	return nil
 }
 
+/**
+ * forward the stream of origin to the destinations of vhost, the edge never forwards.
+ */
+func (this *SrsSource) startForwarders() {
+	if this.isEdge() {
+		return
+	}
+
+	for _, destination := range config.GetVhostForward(this.req.vhost) {
+		forwarder := NewSrsForwarder(this, this.req, destination, SRS_FORWARD_TYPE_FORWARD)
+		this.AppendConsumer(forwarder)
+		go func() {
+			forwarder.ConsumeCycle()
+		}()
+	}
+}
+
+/**
+ * the player starts to play, the edge pulls stream from origin for the first player.
+ */
+func (this *SrsSource) onEdgeStartPlay() error {
+	if this.edge == nil {
+		return nil
+	}
+	return this.edge.OnClientPlay()
+}
+
+func (this *SrsSource) onEdgeStopPlay() {
+	if this.edge != nil {
+		this.edge.OnClientStop()
+	}
+}
+
+func (this *SrsSource) isEdge() bool {
+	return this.edge != nil
+}
+
+/**
+ * the publisher starts to publish on edge, publish the stream to origin.
+ * @return the status of origin to relay to publisher.
+ */
+func (this *SrsSource) onEdgeStartPublish(h ISrsEdgeForwarderHandler) (*packet.SrsOnStatusCallPacket, error) {
+	status, err := this.publishEdge.OnClientPublish(h)
+	// remove the source never played, except it's used by another publisher.
+	if err != nil && err != errEdgePublishing {
+		this.edge.StopIfIdle()
+	}
+	return status, err
+}
+
+func (this *SrsSource) onEdgeProxyPublish(msg *rtmp.SrsRtmpMessage) error {
+	return this.publishEdge.OnProxyPublish(msg)
+}
+
+func (this *SrsSource) onEdgeProxyUnpublish() {
+	this.publishEdge.OnProxyUnpublish()
+	this.edge.StopIfIdle()
+}
+
 func (this *SrsSource) Initialize() {
 }
 
@@ -214,7 +308,69 @@ func (this *SrsSource) OnRecvError(err error) {
 	RemoveSrsSource(this)
 }
 
+/**
+ * push the publishing stream to the destination, until the push is removed or unpublish.
+ */
+func (this *SrsSource) AddPush(destination string) (*SrsForwarder, error) {
+	if this.isEdge() {
+		return nil, errors.New("edge never pushes")
+	}
+
+	// the consumers are removed when unpublish, so append the push under lock.
+	this.pushesMtx.Lock()
+	defer this.pushesMtx.Unlock()
+	if !this.publishing {
+		return nil, errors.New("stream not publishing")
+	}
+
+	forwarder := NewSrsForwarder(this, this.req, destination, SRS_FORWARD_TYPE_PUSH)
+	this.pushes[forwarder.id] = forwarder
+	this.AppendConsumer(forwarder)
+	go func() {
+		forwarder.ConsumeCycle()
+	}()
+	return forwarder, nil
+}
+
+func (this *SrsSource) RemovePush(id int64) error {
+	this.pushesMtx.Lock()
+	forwarder, ok := this.pushes[id]
+	delete(this.pushes, id)
+	this.pushesMtx.Unlock()
+
+	if !ok {
+		return errPushNotFound
+	}
+	this.RemoveConsumer(forwarder)
+	return nil
+}
+
+/**
+ * remove the push by id, from the source which pushes it.
+ */
+func RemoveSrsPush(id int64) error {
+	sourcePoolMtx.Lock()
+	sources := make([]*SrsSource, 0, len(sourcePool))
+	for _, s := range sourcePool {
+		sources = append(sources, s)
+	}
+	sourcePoolMtx.Unlock()
+
+	for _, s := range sources {
+		if err := s.RemovePush(id); err != errPushNotFound {
+			return err
+		}
+	}
+	return errPushNotFound
+}
+
 func (this *SrsSource) RemoveConsumers() {
+	// reject the pushes, which are removed with the consumers.
+	this.pushesMtx.Lock()
+	this.publishing = false
+	this.pushes = make(map[int64]*SrsForwarder)
+	this.pushesMtx.Unlock()
+
 	this.consumersMtx.Lock()
 	defer this.consumersMtx.Unlock()
 
@@ -225,14 +381,70 @@ func (this *SrsSource) RemoveConsumers() {
 	this.consumers = this.consumers[0:0]
 }
 
-func (this *SrsSource) OnAudio(msg *rtmp.SrsRtmpMessage) error {
+/**
+ * update the cached message, the source holds a copy of msg.
+ */
+func srs_source_update_cache(cache **rtmp.SrsSharedPtrMessage, msg *rtmp.SrsSharedPtrMessage) {
+	if *cache != nil {
+		(*cache).Free()
+	}
+	*cache = msg.Copy()
+}
+
+/**
+ * update the cached message of track, the source holds a copy of msg.
+ */
+func srs_source_update_track_cache(caches map[uint8]*rtmp.SrsSharedPtrMessage, msg *rtmp.SrsSharedPtrMessage) {
+	if cache, ok := caches[msg.GetTrackId()]; ok {
+		cache.Free()
+	}
+	caches[msg.GetTrackId()] = msg.Copy()
+}
+
+/**
+ * create the message of track, which shares the header of the multitrack message.
+ */
+func srs_source_track_message(common *rtmp.SrsRtmpMessage, track *flvcodec.SrsFlvTrack) *rtmp.SrsSharedPtrMessage {
+	m := rtmp.NewSrsRtmpMessage()
+	m.SetHeader(*common.GetHeader())
+	m.SetPayload(track.Payload)
+	return rtmp.NewSrsSharedPtrTrackMessage(m, track.TrackId)
+}
+
+func (this *SrsSource) OnAudio(common *rtmp.SrsRtmpMessage) error {
+	if !flvcodec.AudioIsMultitrack(common.GetPayload()) {
+		return this.onAudio(rtmp.NewSrsSharedPtrMessage(common))
+	}
+
+	// the enhanced rtmp multitrack, each track is delivered as a message.
+	tracks, err := flvcodec.DemuxAudioMultitrack(common.GetPayload())
+	if err != nil {
+		return err
+	}
+
+	for _, track := range tracks {
+		if err := this.onAudio(srs_source_track_message(common, track)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *SrsSource) onAudio(msg *rtmp.SrsSharedPtrMessage) error {
+	// the shared message is copied by all consumers, free the source's after fan-out.
+	defer msg.Free()
+
 	isSequenceHeader := flvcodec.AudioIsSequenceHeader(msg.GetPayload())
 	if isSequenceHeader {
-		this.cacheSHAudio = msg
+		if msg.GetTrackId() == 0 {
+			srs_source_update_cache(&this.cacheSHAudio, msg)
+		} else {
+			srs_source_update_track_cache(this.cacheSHAudioTracks, msg)
+		}
 	}
 
 	for i := 0; i < len(this.consumers); i++ {
-		this.consumers[i].Enqueue(msg, false, this.jitterAlgorithm)
+		srs_consumer_enqueue(this.consumers[i], msg, false, this.jitterAlgorithm)
 	}
 
 	if err := this.gopCache.cache(msg); err != nil {
@@ -240,14 +452,40 @@ func (this *SrsSource) OnAudio(msg *rtmp.SrsRtmpMessage) error {
 	return nil
 }
 
-func (this *SrsSource) OnVideo(msg *rtmp.SrsRtmpMessage) error {
+func (this *SrsSource) OnVideo(common *rtmp.SrsRtmpMessage) error {
+	if !flvcodec.VideoIsMultitrack(common.GetPayload()) {
+		return this.onVideo(rtmp.NewSrsSharedPtrMessage(common))
+	}
+
+	// the enhanced rtmp multitrack, each track is delivered as a message.
+	tracks, err := flvcodec.DemuxVideoMultitrack(common.GetPayload())
+	if err != nil {
+		return err
+	}
+
+	for _, track := range tracks {
+		if err := this.onVideo(srs_source_track_message(common, track)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *SrsSource) onVideo(msg *rtmp.SrsSharedPtrMessage) error {
+	defer msg.Free()
+
 	isSequenceHeader := flvcodec.VideoIsSequenceHeader(msg.GetPayload())
 	if isSequenceHeader {
-		this.cacheSHVideo = msg
+		if msg.GetTrackId() == 0 {
+			srs_source_update_cache(&this.cacheSHVideo, msg)
+			this.onVideoInfo(msg.GetPayload())
+		} else {
+			srs_source_update_track_cache(this.cacheSHVideoTracks, msg)
+		}
 	}
 
 	for i := 0; i < len(this.consumers); i++ {
-		this.consumers[i].Enqueue(msg, false, this.jitterAlgorithm)
+		srs_consumer_enqueue(this.consumers[i], msg, false, this.jitterAlgorithm)
 	}
 
 	if err := this.gopCache.cache(msg); err != nil {
@@ -257,7 +495,26 @@ func (this *SrsSource) OnVideo(msg *rtmp.SrsRtmpMessage) error {
 	return nil
 }
 
-func (this *SrsSource) OnMetaData(msg *rtmp.SrsRtmpMessage, pkt *packet.SrsOnMetaDataPacket) error {
+/**
+ * update the video codec of stream statistic by sequence header,
+ * the profile and level is parsed from AVCDecoderConfigurationRecord for h.264 only.
+ */
+func (this *SrsSource) onVideoInfo(payload []byte) {
+	vcodec := flvcodec.VideoCodecId(payload)
+
+	var profile codec.SrsAvcProfile
+	var level codec.SrsAvcLevel
+	if vcodec == codec.SrsCodecVideoAVC && len(payload) > 8 {
+		profile = codec.SrsAvcProfile(payload[6])
+		level = codec.SrsAvcLevel(payload[8])
+	}
+
+	stat := GetStatisticInstance()
+	stat.OnVideoInfo(this.req, vcodec, profile, level)
+	log.Info("got video sequence header, codec=", codec.SrsCodecVideo2Str(vcodec))
+}
+
+func (this *SrsSource) OnMetaData(common *rtmp.SrsRtmpMessage, pkt *packet.SrsOnMetaDataPacket) error {
 	// SrsAmf0Any* prop = NULL;
 
 	//todo
@@ -314,7 +571,10 @@ func (this *SrsSource) OnMetaData(msg *rtmp.SrsRtmpMessage, pkt *packet.SrsOnMet
 	//this.cacheMetaData.GetHeader().SetLength(int32(len(stream.Data())))
 	//this.cacheMetaData.GetHeader().Print()
 	//this.cacheMetaData.SetPayload(stream.Data())
-	this.cacheMetaData = msg
+	msg := rtmp.NewSrsSharedPtrMessage(common)
+	defer msg.Free()
+
+	srs_source_update_cache(&this.cacheMetaData, msg)
 	for i := 0; i < len(this.consumers); i++ {
 		this.consumers[i].Enqueue(msg, false, this.jitterAlgorithm)
 	}
@@ -333,13 +593,15 @@ func (this *SrsSource) SetCache(cache bool) {
 /**
 * create consumer and dumps packets in cache.
 * @param consumer, output the create consumer.
+* @param req, the request of player, to select the tracks of multitrack stream.
+* @param streamId, the stream of connection to play on.
 * @param ds, whether dumps the sequence header.
 * @param dm, whether dumps the metadata.
 * @param dg, whether dumps the gop cache.
  */
-func (this *SrsSource) CreateConsumer(conn *SrsRtmpConn, ds bool, dm bool, db bool) Consumer {
+func (this *SrsSource) CreateConsumer(conn *SrsRtmpConn, req *SrsRequest, streamId int, ds bool, dm bool, db bool) *SrsConsumer {
 	this.consumersMtx.Lock()
-	consumer := NewSrsConsumer(this, conn)
+	consumer := NewSrsConsumer(this, conn, streamId, req.tracks)
 	this.consumers = append(this.consumers, consumer)
 	this.consumersMtx.Unlock()
 	//todo set queue size
@@ -353,13 +615,15 @@ func (this *SrsSource) CreateConsumer(conn *SrsRtmpConn, ds bool, dm bool, db bo
 	}
 
 	if this.cacheSHVideo != nil {
-		consumer.Enqueue(this.cacheSHVideo, false, this.jitterAlgorithm)
+		srs_consumer_enqueue(consumer, this.cacheSHVideo, false, this.jitterAlgorithm)
 	}
 
 	if this.cacheSHAudio != nil {
-		consumer.Enqueue(this.cacheSHAudio, false, this.jitterAlgorithm)
+		srs_consumer_enqueue(consumer, this.cacheSHAudio, false, this.jitterAlgorithm)
 	}
 
+	this.dumpTrackSequenceHeaders(consumer)
+
 	if err := this.gopCache.dump(consumer, false, this.jitterAlgorithm); err != nil {
 		return nil
 	}
@@ -367,6 +631,45 @@ func (this *SrsSource) CreateConsumer(conn *SrsRtmpConn, ds bool, dm bool, db bo
 	return consumer
 }
 
+/**
+ * copy the metadata and sequence headers which the consumer accepts,
+ * for the consumer to start a new stream, for example, the forwarder reconnects.
+ */
+func (this *SrsSource) copySequenceHeaders(consumer Consumer) []*rtmp.SrsSharedPtrMessage {
+	msgs := make([]*rtmp.SrsSharedPtrMessage, 0)
+	if this.cacheMetaData != nil {
+		msgs = append(msgs, this.cacheMetaData.Copy())
+	}
+
+	caches := []*rtmp.SrsSharedPtrMessage{this.cacheSHVideo, this.cacheSHAudio}
+	for _, msg := range this.cacheSHVideoTracks {
+		caches = append(caches, msg)
+	}
+	for _, msg := range this.cacheSHAudioTracks {
+		caches = append(caches, msg)
+	}
+
+	for _, msg := range caches {
+		if msg != nil && srs_consumer_accept(consumer, msg) {
+			msgs = append(msgs, msg.Copy())
+		}
+	}
+	return msgs
+}
+
+/**
+ * dumps the sequence headers of multitrack to consumer, which accepts the track.
+ */
+func (this *SrsSource) dumpTrackSequenceHeaders(consumer Consumer) {
+	for _, msg := range this.cacheSHVideoTracks {
+		srs_consumer_enqueue(consumer, msg, false, this.jitterAlgorithm)
+	}
+
+	for _, msg := range this.cacheSHAudioTracks {
+		srs_consumer_enqueue(consumer, msg, false, this.jitterAlgorithm)
+	}
+}
+
 func (this *SrsSource) AppendConsumer(consumer Consumer) error {
 	this.consumersMtx.Lock()
 	this.consumers = append(this.consumers, consumer)
@@ -382,13 +685,15 @@ func (this *SrsSource) AppendConsumer(consumer Consumer) error {
 	}
 
 	if this.cacheSHVideo != nil {
-		consumer.Enqueue(this.cacheSHVideo, false, this.jitterAlgorithm)
+		srs_consumer_enqueue(consumer, this.cacheSHVideo, false, this.jitterAlgorithm)
 	}
 
 	if this.cacheSHAudio != nil {
-		consumer.Enqueue(this.cacheSHAudio, false, this.jitterAlgorithm)
+		srs_consumer_enqueue(consumer, this.cacheSHAudio, false, this.jitterAlgorithm)
 	}
 
+	this.dumpTrackSequenceHeaders(consumer)
+
 	if err := this.gopCache.dump(consumer, false, this.jitterAlgorithm); err != nil {
 		return err
 	}
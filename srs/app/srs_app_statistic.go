@@ -26,8 +26,10 @@ package app
 import (
 	log "github.com/sirupsen/logrus"
 	"go_srs/srs/codec"
+	"go_srs/srs/protocol/rtmp"
 	"go_srs/srs/utils"
 	"sync"
+	"time"
 )
 
 type SrsStatisticVhost struct {
@@ -112,21 +114,152 @@ func (this *SrsStatisticStream) Close() {
 	this.vhost.nb_streams--
 }
 
+func (this *SrsStatisticStream) dumps() map[string]interface{} {
+	data := map[string]interface{}{
+		"id":      this.id,
+		"name":    this.stream,
+		"vhost":   this.vhost.vhost,
+		"app":     this.app,
+		"url":     this.url,
+		"active":  this.active,
+		"cid":     this.connection_cid,
+		"clients": this.nb_clients,
+		"frames":  this.nb_frames,
+	}
+
+	if this.video != nil {
+		data["video"] = map[string]interface{}{
+			"codec":   codec.SrsCodecVideo2Str(this.video.vcodec),
+			"profile": this.video.avc_profile,
+			"level":   this.video.avc_level,
+		}
+	}
+
+	if this.audio != nil {
+		data["audio"] = map[string]interface{}{
+			"codec":       this.audio.acodec,
+			"sample_rate": this.audio.asample_rate,
+			"sound_type":  this.audio.asound_type,
+			"aac_object":  this.audio.aac_object,
+		}
+	}
+	return data
+}
+
 type SrsStatisticClient struct {
 	stream *SrsStatisticStream
-	id     int
+	id     int64
 	create int64
+	ip     string
+	vhost  string
+	// the round trip time measured by ping, 0 when client never responses.
+	rtt time.Duration
+	// the rtmp of client, for the io bytes and acknowledgement counters.
+	rtmp *rtmp.SrsRtmpServer
+}
+
+func (this *SrsStatisticClient) dumps() map[string]interface{} {
+	data := map[string]interface{}{
+		"id":     this.id,
+		"ip":     this.ip,
+		"vhost":  this.vhost,
+		"create": this.create,
+		"rtt_ms": int64(this.rtt / time.Millisecond),
+	}
+
+	if this.rtmp != nil {
+		in := this.rtmp.GetInAckSize()
+		out := this.rtmp.GetOutAckSize()
+		data["recv_bytes"] = this.rtmp.GetRecvBytes()
+		data["send_bytes"] = this.rtmp.GetSendBytes()
+		data["ack"] = map[string]interface{}{
+			"in_window":    in.Window,
+			"in_sequence":  in.SequenceNumber,
+			"in_acks":      in.NbAcks,
+			"out_window":   out.Window,
+			"out_sequence": out.SequenceNumber,
+			"out_acks":     out.NbAcks,
+			"out_unacked":  this.rtmp.GetOutUnackedBytes(),
+		}
+	}
+	return data
 }
 
+/**
+ * the forward of stream to destination, the forwarder reconnects when fails.
+ */
+type SrsStatisticForward struct {
+	id          int64
+	typ         string
+	stream      string
+	destination string
+	state       string
+	create      int64
+	// the bytes sent to destination, of all connections.
+	send_bytes int64
+	// the times to reconnect to destination.
+	nb_reconnects int
+	// the last error of forwarder, empty when never fails.
+	error string
+}
+
+func (this *SrsStatisticForward) dumps() map[string]interface{} {
+	return map[string]interface{}{
+		"id":          this.id,
+		"type":        this.typ,
+		"stream":      this.stream,
+		"destination": this.destination,
+		"state":       this.state,
+		"create":      this.create,
+		"send_bytes":  this.send_bytes,
+		"reconnects":  this.nb_reconnects,
+		"error":       this.error,
+	}
+}
+
+/**
+ * the result of bandwidth check, the play is the downlink and the publish is the uplink of client,
+ * the time is in ms.
+ */
+type SrsStatisticBandwidth struct {
+	Ip           string `json:"ip"`
+	Vhost        string `json:"vhost"`
+	StartTime    int64  `json:"start_time"`
+	EndTime      int64  `json:"end_time"`
+	PlayKbps     int64  `json:"play_kbps"`
+	PublishKbps  int64  `json:"publish_kbps"`
+	PlayBytes    int64  `json:"play_bytes"`
+	PublishBytes int64  `json:"publish_bytes"`
+	PlayTime     int64  `json:"play_time"`
+	PublishTime  int64  `json:"publish_time"`
+}
+
+/**
+ * the max results of bandwidth check kept for http api.
+ */
+const SRS_STATISTIC_MAX_BANDWIDTHS = 100
+
 type SrsStatistic struct {
 	vhosts   map[int64]*SrsStatisticVhost
 	rvhosts  map[string]*SrsStatisticVhost
 	streams  map[int64]*SrsStatisticStream
 	rstreams map[string]*SrsStatisticStream
 	clients  map[int64]*SrsStatisticClient
+	// the vhosts and streams are updated by sources and read by http api.
+	streamsLock sync.Mutex
+	// the clients are updated by connections and read by http api.
+	clientsLock sync.Mutex
+	// the recent results of bandwidth check, written by connections and read by http api.
+	bandwidthsLock sync.Mutex
+	bandwidths     []*SrsStatisticBandwidth
+	// the forwards are updated by forwarders and read by http api.
+	forwardsLock sync.Mutex
+	forwards     map[int64]*SrsStatisticForward
 }
 
 func (this *SrsStatistic) FindVHost(vid int64) *SrsStatisticVhost {
+	this.streamsLock.Lock()
+	defer this.streamsLock.Unlock()
 	v, ok := this.vhosts[vid]
 	if !ok {
 		return nil
@@ -135,6 +268,8 @@ func (this *SrsStatistic) FindVHost(vid int64) *SrsStatisticVhost {
 }
 
 func (this *SrsStatistic) FindStream(sid int64) *SrsStatisticStream {
+	this.streamsLock.Lock()
+	defer this.streamsLock.Unlock()
 	s, ok := this.streams[sid]
 	if !ok {
 		return nil
@@ -143,6 +278,8 @@ func (this *SrsStatistic) FindStream(sid int64) *SrsStatisticStream {
 }
 
 func (this *SrsStatistic) FindClient(cid int64) *SrsStatisticClient {
+	this.clientsLock.Lock()
+	defer this.clientsLock.Unlock()
 	c, ok := this.clients[cid]
 	if !ok {
 		return nil
@@ -151,6 +288,8 @@ func (this *SrsStatistic) FindClient(cid int64) *SrsStatisticClient {
 }
 
 func (this *SrsStatistic) OnVideoInfo(req *SrsRequest, vcodec codec.SrsCodecVideo, avc_profile codec.SrsAvcProfile, avc_level codec.SrsAvcLevel) error {
+	this.streamsLock.Lock()
+	defer this.streamsLock.Unlock()
 	vhost := this.createVHost(req)
 	stream := this.createStream(vhost, req)
 	stream.video = NewSrsStatisticStreamVideo(vcodec, avc_profile, avc_level)
@@ -162,6 +301,8 @@ func (this *SrsStatistic) OnAudioInfo(req *SrsRequest,
 	asample_rate codec.SrsCodecAudioSampleRate,
 	asound_type codec.SrsCodecAudioSoundType,
 	aac_object codec.SrsAacObjectType) error {
+	this.streamsLock.Lock()
+	defer this.streamsLock.Unlock()
 	vhost := this.createVHost(req)
 	stream := this.createStream(vhost, req)
 	stream.audio = NewSrsStatisticStreamAudio(acodec, asample_rate, asound_type, aac_object)
@@ -169,6 +310,8 @@ func (this *SrsStatistic) OnAudioInfo(req *SrsRequest,
 }
 
 func (this *SrsStatistic) OnVideoFrames(req *SrsRequest, nb_frames uint64) error {
+	this.streamsLock.Lock()
+	defer this.streamsLock.Unlock()
 	vhost := this.createVHost(req)
 	stream := this.createStream(vhost, req)
 	stream.nb_frames += nb_frames
@@ -177,6 +320,8 @@ func (this *SrsStatistic) OnVideoFrames(req *SrsRequest, nb_frames uint64) error
 }
 
 func (this *SrsStatistic) OnStreamPublish(req *SrsRequest, cid int64) error {
+	this.streamsLock.Lock()
+	defer this.streamsLock.Unlock()
 	vhost := this.createVHost(req)
 	stream := this.createStream(vhost, req)
 	stream.Publish(cid)
@@ -184,12 +329,140 @@ func (this *SrsStatistic) OnStreamPublish(req *SrsRequest, cid int64) error {
 }
 
 func (this *SrsStatistic) OnStreamClose(req *SrsRequest, cid int64) error {
+	this.streamsLock.Lock()
+	defer this.streamsLock.Unlock()
 	vhost := this.createVHost(req)
 	stream := this.createStream(vhost, req)
 	stream.Close()
 	return nil
 }
 
+func (this *SrsStatistic) DumpStreams() []map[string]interface{} {
+	this.streamsLock.Lock()
+	defer this.streamsLock.Unlock()
+	streams := make([]map[string]interface{}, 0, len(this.streams))
+	for _, stream := range this.streams {
+		streams = append(streams, stream.dumps())
+	}
+	return streams
+}
+
+func (this *SrsStatistic) OnClient(id int64, req *SrsRequest, rtmp *rtmp.SrsRtmpServer) {
+	this.clientsLock.Lock()
+	defer this.clientsLock.Unlock()
+	this.clients[id] = &SrsStatisticClient{
+		id:     id,
+		create: utils.GetCurrentMs(),
+		ip:     req.ip,
+		vhost:  req.vhost,
+		rtmp:   rtmp,
+	}
+}
+
+func (this *SrsStatistic) OnDisconnect(id int64) {
+	this.clientsLock.Lock()
+	defer this.clientsLock.Unlock()
+	delete(this.clients, id)
+}
+
+func (this *SrsStatistic) OnClientRtt(id int64, rtt time.Duration) {
+	this.clientsLock.Lock()
+	defer this.clientsLock.Unlock()
+	if client, ok := this.clients[id]; ok {
+		client.rtt = rtt
+	}
+}
+
+func (this *SrsStatistic) DumpClients() []map[string]interface{} {
+	this.clientsLock.Lock()
+	defer this.clientsLock.Unlock()
+	clients := make([]map[string]interface{}, 0, len(this.clients))
+	for _, client := range this.clients {
+		clients = append(clients, client.dumps())
+	}
+	return clients
+}
+
+func (this *SrsStatistic) OnBandwidthCheck(result *SrsStatisticBandwidth) {
+	this.bandwidthsLock.Lock()
+	defer this.bandwidthsLock.Unlock()
+	this.bandwidths = append(this.bandwidths, result)
+	if len(this.bandwidths) > SRS_STATISTIC_MAX_BANDWIDTHS {
+		this.bandwidths = this.bandwidths[len(this.bandwidths)-SRS_STATISTIC_MAX_BANDWIDTHS:]
+	}
+}
+
+/**
+ * the recent results of bandwidth check, the oldest first.
+ */
+func (this *SrsStatistic) DumpBandwidths() []*SrsStatisticBandwidth {
+	this.bandwidthsLock.Lock()
+	defer this.bandwidthsLock.Unlock()
+	results := make([]*SrsStatisticBandwidth, len(this.bandwidths))
+	copy(results, this.bandwidths)
+	return results
+}
+
+func (this *SrsStatistic) OnForward(id int64, typ string, req *SrsRequest, destination string) {
+	this.forwardsLock.Lock()
+	defer this.forwardsLock.Unlock()
+	this.forwards[id] = &SrsStatisticForward{
+		id:          id,
+		typ:         typ,
+		stream:      req.GetStreamUrl(),
+		destination: destination,
+		state:       SRS_FORWARD_STATE_CONNECTING,
+		create:      utils.GetCurrentMs(),
+	}
+}
+
+func (this *SrsStatistic) OnForwardState(id int64, state string) {
+	this.forwardsLock.Lock()
+	defer this.forwardsLock.Unlock()
+	if forward, ok := this.forwards[id]; ok {
+		forward.state = state
+	}
+}
+
+func (this *SrsStatistic) OnForwardBytes(id int64, delta int64) {
+	this.forwardsLock.Lock()
+	defer this.forwardsLock.Unlock()
+	if forward, ok := this.forwards[id]; ok {
+		forward.send_bytes += delta
+	}
+}
+
+func (this *SrsStatistic) OnForwardError(id int64, err error) {
+	this.forwardsLock.Lock()
+	defer this.forwardsLock.Unlock()
+	if forward, ok := this.forwards[id]; ok {
+		forward.state = SRS_FORWARD_STATE_RECONNECTING
+		forward.nb_reconnects++
+		forward.error = err.Error()
+	}
+}
+
+func (this *SrsStatistic) OnForwardClose(id int64) {
+	this.forwardsLock.Lock()
+	defer this.forwardsLock.Unlock()
+	delete(this.forwards, id)
+}
+
+/**
+ * the forwards of type, forward or push.
+ */
+func (this *SrsStatistic) DumpForwards(typ string) []map[string]interface{} {
+	this.forwardsLock.Lock()
+	defer this.forwardsLock.Unlock()
+	forwards := make([]map[string]interface{}, 0, len(this.forwards))
+	for _, forward := range this.forwards {
+		if forward.typ == typ {
+			forwards = append(forwards, forward.dumps())
+		}
+	}
+	return forwards
+}
+
 func (this *SrsStatistic) createVHost(req *SrsRequest) *SrsStatisticVhost {
 	v, ok := this.rvhosts[req.vhost]
 	if !ok {
@@ -219,6 +492,8 @@ func (this *SrsStatistic) createStream(vhost *SrsStatisticVhost, req *SrsRequest
 
 func (this *SrsStatistic) addDeltaToKbps(conn *SrsRtmpConn) {
 	id := conn.id
+	this.clientsLock.Lock()
+	defer this.clientsLock.Unlock()
 	client, ok := this.clients[id]
 	if !ok {
 		return
@@ -232,11 +507,13 @@ var once sync.Once
 func GetStatisticInstance() *SrsStatistic {
 	once.Do(func() {
 		instance = &SrsStatistic{
-			vhosts:   make(map[int64]*SrsStatisticVhost, 0),
-			rvhosts:  make(map[string]*SrsStatisticVhost, 0),
-			streams:  make(map[int64]*SrsStatisticStream, 0),
-			rstreams: make(map[string]*SrsStatisticStream, 0),
-			clients:  make(map[int64]*SrsStatisticClient, 0),
+			vhosts:     make(map[int64]*SrsStatisticVhost, 0),
+			rvhosts:    make(map[string]*SrsStatisticVhost, 0),
+			streams:    make(map[int64]*SrsStatisticStream, 0),
+			rstreams:   make(map[string]*SrsStatisticStream, 0),
+			clients:    make(map[int64]*SrsStatisticClient, 0),
+			bandwidths: make([]*SrsStatisticBandwidth, 0),
+			forwards:   make(map[int64]*SrsStatisticForward, 0),
 		}
 	})
 
@@ -28,6 +28,7 @@ import (
 	"errors"
 	"fmt"
 	"go_srs/srs/codec"
+	"go_srs/srs/codec/flv"
 	"go_srs/srs/utils"
 )
 
@@ -235,6 +236,14 @@ func (this *SrsAvcAacCodec) audio_aac_sequence_header_demux(data []byte) error {
 func (this *SrsAvcAacCodec) videoAvcDemux(data []byte, sample *SrsCodecSampler) error {
 	sample.SetIsVideo(true)
 
+	// the enhanced rtmp, HEVC/AV1/VP9 by FourCC, which is not muxed to ts,
+	// so only parse the frame type and codec id.
+	if flvcodec.VideoIsExHeader(data) {
+		sample.FrameType = codec.SrsCodecVideoAVCFrame(flvcodec.VideoFrameType(data))
+		this.videoCodecId = int(flvcodec.VideoCodecId(data))
+		return nil
+	}
+
 	stream := utils.NewSrsStream(data)
 	// @see: E.4.3 Video Tags, video_file_format_spec_v10_1.pdf, page 78
 	frameType, err := stream.ReadByte()
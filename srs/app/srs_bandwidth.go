@@ -0,0 +1,360 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package app
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"go_srs/srs/app/config"
+	"go_srs/srs/protocol/packet"
+	"go_srs/srs/protocol/rtmp"
+	"go_srs/srs/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * the duration in ms of play and publish sampling.
+ */
+const SRS_BANDWIDTH_SAMPLE_DURATION_MS = 3000
+
+/**
+ * the timeout to wait for the packet of client, twice of the sample duration.
+ */
+const SRS_BANDWIDTH_CHECK_TIMEOUT = 2 * SRS_BANDWIDTH_SAMPLE_DURATION_MS * time.Millisecond
+
+/**
+ * the max fields of the playing data, each field is about 50 bytes.
+ */
+const SRS_BANDWIDTH_PLAY_MAX_FIELDS = 256
+
+var ErrBandwidthKey = errors.New("bandwidth check key mismatch")
+var ErrBandwidthDenied = errors.New("bandwidth check denied, in the interval of last check")
+
+/**
+ * the last check time of vhost, to prevent the check attack,
+ * the check is rejected in the interval of last check.
+ */
+var bandwidthChecks map[string]int64
+var bandwidthChecksLock sync.Mutex
+
+func init() {
+	bandwidthChecks = make(map[string]int64)
+}
+
+func acquireBandwidthCheck(vhost string, intervalMs int64) bool {
+	bandwidthChecksLock.Lock()
+	defer bandwidthChecksLock.Unlock()
+	now := utils.GetCurrentMs()
+	if last, ok := bandwidthChecks[vhost]; ok && now-last < intervalMs {
+		return false
+	}
+	bandwidthChecks[vhost] = now
+	return true
+}
+
+/**
+ * the sample of play or publish, the duration and interval in ms.
+ */
+type SrsBandwidthSample struct {
+	durationMs       int64
+	intervalMs       int64
+	actualDurationMs int64
+	bytes            int64
+	kbps             int64
+}
+
+func NewSrsBandwidthSample() *SrsBandwidthSample {
+	return &SrsBandwidthSample{
+		durationMs: SRS_BANDWIDTH_SAMPLE_DURATION_MS,
+	}
+}
+
+func (this *SrsBandwidthSample) calcKbps(bytes int64, durationMs int64) {
+	this.bytes = bytes
+	this.actualDurationMs = durationMs
+	if durationMs > 0 {
+		this.kbps = bytes * 8 / durationMs
+	}
+}
+
+/**
+ * the bandwidth check of client, samples the play(downlink) and publish(uplink) of client:
+ *		server		client
+ * 		start play		->
+ * 				<-		starting play
+ * 		playing data	->
+ * 		stop play		->
+ * 				<-		stopped play
+ * 		start publish	->
+ * 				<-		starting publish
+ * 				<-		publishing data
+ * 		stop publish	->
+ * 				<-		stopped publish(not flash)
+ * 		finished		->
+ * 				<-		final(not flash)
+ */
+type SrsBandwidth struct {
+	rtmp      *rtmp.SrsRtmpServer
+	req       *SrsRequest
+	limitKbps int64
+}
+
+func NewSrsBandwidth(r *rtmp.SrsRtmpServer, req *SrsRequest) *SrsBandwidth {
+	return &SrsBandwidth{
+		rtmp:      r,
+		req:       req,
+		limitKbps: int64(config.GetBandcheckLimitKbps(req.vhost)),
+	}
+}
+
+/**
+ * check the bandwidth of client which connects to the vhost of bandwidth check,
+ * the client must specifies the key in tcUrl, the connect is responsed when accepted.
+ */
+func (this *SrsBandwidth) BandwidthCheck() error {
+	// only the client with the key can check.
+	key := "key=" + config.GetBandcheckKey(this.req.vhost)
+	if !strings.Contains(this.req.tcUrl, key) {
+		_ = this.rtmp.ResponseConnectReject("bandcheck key mismatch")
+		return ErrBandwidthKey
+	}
+
+	intervalMs := int64(config.GetBandcheckInterval(this.req.vhost)) * 1000
+	if !acquireBandwidthCheck(this.req.vhost, intervalMs) {
+		_ = this.rtmp.ResponseConnectReject("bandcheck rejected")
+		return ErrBandwidthDenied
+	}
+
+	if err := this.rtmp.ResponseConnectApp(this.req.objectEncoding); err != nil {
+		return err
+	}
+
+	return this.doBandwidthCheck()
+}
+
+func (this *SrsBandwidth) doBandwidthCheck() error {
+	play := NewSrsBandwidthSample()
+	publish := NewSrsBandwidthSample()
+
+	startTime := utils.GetCurrentMs()
+
+	if err := this.playStart(play); err != nil {
+		return err
+	}
+	if err := this.playChecking(play); err != nil {
+		return err
+	}
+	if err := this.playStop(play); err != nil {
+		return err
+	}
+
+	if err := this.publishStart(publish); err != nil {
+		return err
+	}
+	if err := this.publishChecking(publish); err != nil {
+		return err
+	}
+	if err := this.publishStop(publish); err != nil {
+		return err
+	}
+
+	endTime := utils.GetCurrentMs()
+	return this.finial(play, publish, startTime, endTime)
+}
+
+func (this *SrsBandwidth) playStart(sample *SrsBandwidthSample) error {
+	pkt := packet.NewSrsBandwidthPacket(packet.SRS_BW_CHECK_START_PLAY)
+	pkt.Data.Set("limit_kbps", float64(this.limitKbps))
+	pkt.Data.Set("duration_ms", float64(sample.durationMs))
+	pkt.Data.Set("interval_ms", float64(sample.intervalMs))
+	if err := this.rtmp.SendPacket(pkt, 0); err != nil {
+		return err
+	}
+	return this.expect(packet.SRS_BW_CHECK_STARTING_PLAY)
+}
+
+/**
+ * send the playing data to client in the sample duration, more data in each packet.
+ */
+func (this *SrsBandwidth) playChecking(sample *SrsBandwidthSample) error {
+	start := time.Now()
+	startBytes := this.rtmp.GetSendBytes()
+
+	fields := 1
+	for time.Since(start) < time.Duration(sample.durationMs)*time.Millisecond {
+		pkt := packet.NewSrsBandwidthPacket(packet.SRS_BW_CHECK_PLAYING)
+		for i := 0; i < fields; i++ {
+			pkt.Data.Set(strconv.Itoa(i), "SRS band check data from server's playing......")
+		}
+		if fields < SRS_BANDWIDTH_PLAY_MAX_FIELDS {
+			fields += 2
+		}
+
+		if err := this.rtmp.SendPacket(pkt, 0); err != nil {
+			return err
+		}
+		this.limit(start, this.rtmp.GetSendBytes()-startBytes)
+	}
+
+	sample.calcKbps(this.rtmp.GetSendBytes()-startBytes, int64(time.Since(start)/time.Millisecond))
+	return nil
+}
+
+func (this *SrsBandwidth) playStop(sample *SrsBandwidthSample) error {
+	pkt := packet.NewSrsBandwidthPacket(packet.SRS_BW_CHECK_STOP_PLAY)
+	pkt.Data.Set("duration_delta", float64(sample.actualDurationMs))
+	pkt.Data.Set("bytes_delta", float64(sample.bytes))
+	if err := this.rtmp.SendPacket(pkt, 0); err != nil {
+		return err
+	}
+	return this.expect(packet.SRS_BW_CHECK_STOPPED_PLAY)
+}
+
+func (this *SrsBandwidth) publishStart(sample *SrsBandwidthSample) error {
+	pkt := packet.NewSrsBandwidthPacket(packet.SRS_BW_CHECK_START_PUBLISH)
+	pkt.Data.Set("limit_kbps", float64(this.limitKbps))
+	pkt.Data.Set("duration_ms", float64(sample.durationMs))
+	pkt.Data.Set("interval_ms", float64(sample.intervalMs))
+	if err := this.rtmp.SendPacket(pkt, 0); err != nil {
+		return err
+	}
+	return this.expect(packet.SRS_BW_CHECK_STARTING_PUBLISH)
+}
+
+/**
+ * recv the publishing data of client in the sample duration,
+ * the client keeps publishing until stopped, so the recv never blocks long.
+ */
+func (this *SrsBandwidth) publishChecking(sample *SrsBandwidthSample) error {
+	start := time.Now()
+	startBytes := this.rtmp.GetRecvBytes()
+
+	for time.Since(start) < time.Duration(sample.durationMs)*time.Millisecond {
+		ctx, cancel := context.WithTimeout(context.Background(), SRS_BANDWIDTH_CHECK_TIMEOUT)
+		_, err := this.rtmp.RecvMessageContext(ctx)
+		cancel()
+		if err != nil {
+			return err
+		}
+		this.limit(start, this.rtmp.GetRecvBytes()-startBytes)
+	}
+
+	sample.calcKbps(this.rtmp.GetRecvBytes()-startBytes, int64(time.Since(start)/time.Millisecond))
+	return nil
+}
+
+func (this *SrsBandwidth) publishStop(sample *SrsBandwidthSample) error {
+	pkt := packet.NewSrsBandwidthPacket(packet.SRS_BW_CHECK_STOP_PUBLISH)
+	pkt.Data.Set("duration_delta", float64(sample.actualDurationMs))
+	pkt.Data.Set("bytes_delta", float64(sample.bytes))
+	if err := this.rtmp.SendPacket(pkt, 0); err != nil {
+		return err
+	}
+
+	// the flash never sends the stopped event, for its publish queue is full.
+	if this.isFlash() {
+		return nil
+	}
+	return this.expect(packet.SRS_BW_CHECK_STOPPED_PUBLISH)
+}
+
+/**
+ * notice the result to client and http api, the flash client disconnects when got the result,
+ * while other clients send the final packet.
+ */
+func (this *SrsBandwidth) finial(play, publish *SrsBandwidthSample, startTime, endTime int64) error {
+	result := &SrsStatisticBandwidth{
+		Ip:           this.req.ip,
+		Vhost:        this.req.vhost,
+		StartTime:    startTime,
+		EndTime:      endTime,
+		PlayKbps:     play.kbps,
+		PublishKbps:  publish.kbps,
+		PlayBytes:    play.bytes,
+		PublishBytes: publish.bytes,
+		PlayTime:     play.actualDurationMs,
+		PublishTime:  publish.actualDurationMs,
+	}
+	GetStatisticInstance().OnBandwidthCheck(result)
+	log.Infof("bandwidth check ip=%s, vhost=%s, play=%dkbps, publish=%dkbps",
+		result.Ip, result.Vhost, result.PlayKbps, result.PublishKbps)
+
+	pkt := packet.NewSrsBandwidthPacket(packet.SRS_BW_CHECK_FINISHED)
+	pkt.Data.Set("start_time", float64(result.StartTime))
+	pkt.Data.Set("end_time", float64(result.EndTime))
+	pkt.Data.Set("play_kbps", float64(result.PlayKbps))
+	pkt.Data.Set("publish_kbps", float64(result.PublishKbps))
+	pkt.Data.Set("play_bytes", float64(result.PlayBytes))
+	pkt.Data.Set("publish_bytes", float64(result.PublishBytes))
+	pkt.Data.Set("play_time", float64(result.PlayTime))
+	pkt.Data.Set("publish_time", float64(result.PublishTime))
+	if err := this.rtmp.SendPacket(pkt, 0); err != nil {
+		return err
+	}
+
+	if this.isFlash() {
+		return nil
+	}
+	return this.expect(packet.SRS_BW_CHECK_FINAL)
+}
+
+func (this *SrsBandwidth) isFlash() bool {
+	return this.req.swfUrl != ""
+}
+
+/**
+ * expect the bandwidth packet of command, ignore the others, for example, the publishing data.
+ */
+func (this *SrsBandwidth) expect(command string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), SRS_BANDWIDTH_CHECK_TIMEOUT)
+	defer cancel()
+	for {
+		pkt := packet.NewSrsBandwidthPacket(command)
+		if err := this.rtmp.ExpectMessageContext(ctx, pkt); err != nil {
+			return err
+		}
+
+		if pkt.CommandName.Value.Value == command {
+			return nil
+		}
+	}
+}
+
+/**
+ * sleep to keep the average kbps since start under the limit.
+ */
+func (this *SrsBandwidth) limit(start time.Time, bytes int64) {
+	if this.limitKbps <= 0 {
+		return
+	}
+
+	// the kbps is bits per ms.
+	expect := time.Duration(bytes*8/this.limitKbps) * time.Millisecond
+	if elapsed := time.Since(start); elapsed < expect {
+		time.Sleep(expect - elapsed)
+	}
+}
@@ -24,6 +24,8 @@ package app
 
 import (
 	"go_srs/srs/protocol/rtmp"
+	"net/url"
+	"strconv"
 )
 
 type Consumer interface {
@@ -32,5 +34,73 @@ type Consumer interface {
 	ConsumeCycle() error
 	StopConsume() error
 	OnRecvError(err error)
-	Enqueue(msg *rtmp.SrsRtmpMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm)
+	Enqueue(msg *rtmp.SrsSharedPtrMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm)
+}
+
+/**
+ * the consumer which selects the tracks of enhanced rtmp multitrack stream,
+ * the consumer which not implements it only consumes the default track 0.
+ */
+type TrackConsumer interface {
+	AcceptTrack(msg *rtmp.SrsSharedPtrMessage) bool
+}
+
+/**
+ * whether the consumer accepts the track of msg.
+ */
+func srs_consumer_accept(consumer Consumer, msg *rtmp.SrsSharedPtrMessage) bool {
+	if c, ok := consumer.(TrackConsumer); ok {
+		return c.AcceptTrack(msg)
+	}
+	return msg.GetTrackId() == 0
+}
+
+/**
+ * enqueue the msg to consumer when consumer accepts the track of msg.
+ */
+func srs_consumer_enqueue(consumer Consumer, msg *rtmp.SrsSharedPtrMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
+	if !srs_consumer_accept(consumer, msg) {
+		return
+	}
+	consumer.Enqueue(msg, atc, jitterAlgorithm)
+}
+
+/**
+ * the tracks to play, specified by query of player, for example, ?audioTrack=1&videoTrack=0,
+ * the default track 0 when not specified.
+ */
+type SrsPlayTracks struct {
+	audio uint8
+	video uint8
+}
+
+func NewSrsPlayTracks(query url.Values) *SrsPlayTracks {
+	return &SrsPlayTracks{
+		audio: srs_parse_track(query, "audioTrack"),
+		video: srs_parse_track(query, "videoTrack"),
+	}
+}
+
+func (this *SrsPlayTracks) accept(msg *rtmp.SrsSharedPtrMessage) bool {
+	var audio, video uint8
+	if this != nil {
+		audio, video = this.audio, this.video
+	}
+
+	if msg.GetHeader().IsAudio() {
+		return msg.GetTrackId() == audio
+	}
+
+	if msg.GetHeader().IsVideo() {
+		return msg.GetTrackId() == video
+	}
+	return true
+}
+
+func srs_parse_track(query url.Values, name string) uint8 {
+	v, err := strconv.ParseUint(query.Get(name), 10, 8)
+	if err != nil {
+		return 0
+	}
+	return uint8(v)
 }
@@ -82,6 +82,7 @@ func (this *SrsDvrConsumer) ConsumeCycle() error {
 					return err
 				}
 			}
+			msg.Free()
 		}
 	}
 	return nil
@@ -97,6 +98,6 @@ func (this *SrsDvrConsumer) OnRecvError(err error) {
 	this.source.OnConsumerError(this)
 }
 
-func (this *SrsDvrConsumer) Enqueue(msg *rtmp.SrsRtmpMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
-	this.queue.Enqueue(msg)
+func (this *SrsDvrConsumer) Enqueue(msg *rtmp.SrsSharedPtrMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
+	this.queue.Enqueue(msg.Copy())
 }
@@ -31,9 +31,9 @@ import (
 type SrsDvrPlan interface {
 	OnPublish() error
 	OnUnpublish() error
-	OnMetaData(metaData *rtmp.SrsRtmpMessage) error
-	OnVideo(video *rtmp.SrsRtmpMessage) error
-	OnAudio(audio *rtmp.SrsRtmpMessage) error
+	OnMetaData(metaData *rtmp.SrsSharedPtrMessage) error
+	OnVideo(video *rtmp.SrsSharedPtrMessage) error
+	OnAudio(audio *rtmp.SrsSharedPtrMessage) error
 }
 
 func NewSrsDvrPlan(req *SrsRequest) SrsDvrPlan {
@@ -65,15 +65,15 @@ func (this *SrsAppendDvrPlan) OnUnpublish() error {
 	return this.segment.Close()
 }
 
-func (this *SrsAppendDvrPlan) OnMetaData(metaData *rtmp.SrsRtmpMessage) error {
+func (this *SrsAppendDvrPlan) OnMetaData(metaData *rtmp.SrsSharedPtrMessage) error {
 	return this.segment.WriteMetaData(metaData)
 }
 
-func (this *SrsAppendDvrPlan) OnVideo(video *rtmp.SrsRtmpMessage) error {
+func (this *SrsAppendDvrPlan) OnVideo(video *rtmp.SrsSharedPtrMessage) error {
 	return this.segment.WriteVideo(video)
 }
 
-func (this *SrsAppendDvrPlan) OnAudio(audio *rtmp.SrsRtmpMessage) error {
+func (this *SrsAppendDvrPlan) OnAudio(audio *rtmp.SrsSharedPtrMessage) error {
 	return this.segment.WriteAudio(audio)
 }
 
@@ -102,15 +102,15 @@ func (this *SrsSessionDvrPlan) OnUnpublish() error {
 	return nil
 }
 
-func (this *SrsSessionDvrPlan) OnMetaData(metaData *rtmp.SrsRtmpMessage) error {
+func (this *SrsSessionDvrPlan) OnMetaData(metaData *rtmp.SrsSharedPtrMessage) error {
 	return this.segment.WriteMetaData(metaData)
 }
 
-func (this *SrsSessionDvrPlan) OnVideo(video *rtmp.SrsRtmpMessage) error {
+func (this *SrsSessionDvrPlan) OnVideo(video *rtmp.SrsSharedPtrMessage) error {
 	return this.segment.WriteVideo(video)
 }
 
-func (this *SrsSessionDvrPlan) OnAudio(audio *rtmp.SrsRtmpMessage) error {
+func (this *SrsSessionDvrPlan) OnAudio(audio *rtmp.SrsSharedPtrMessage) error {
 	return this.segment.WriteAudio(audio)
 }
 
@@ -129,17 +129,17 @@ func (this *SrsSessionDvrPlan) OnAudio(audio *rtmp.SrsRtmpMessage) error {
 //	this.segment.Initialize()
 //}
 //
-//func (this *SrsDvrPlan) On_video(msg *rtmp.SrsRtmpMessage) error {
+//func (this *SrsDvrPlan) On_video(msg *rtmp.SrsSharedPtrMessage) error {
 //	this.segment.WriteVideo(msg)
 //	return nil
 //}
 //
-//func (this *SrsDvrPlan) On_audio(msg *rtmp.SrsRtmpMessage) error {
+//func (this *SrsDvrPlan) On_audio(msg *rtmp.SrsSharedPtrMessage) error {
 //	this.segment.WriteAudio(msg)
 //	return nil
 //}
 //
-//func (this *SrsDvrPlan) OnMetaData(msg *rtmp.SrsRtmpMessage) error {
+//func (this *SrsDvrPlan) OnMetaData(msg *rtmp.SrsSharedPtrMessage) error {
 //	err := this.segment.WriteMetaData(msg)
 //	if err != nil {
 //		return err
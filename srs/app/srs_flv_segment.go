@@ -26,11 +26,11 @@ package app
 import (
 	"encoding/binary"
 	"errors"
-	"fmt"
 	"go_srs/srs/app/config"
 	"go_srs/srs/codec/flv"
 	"go_srs/srs/global"
 	"go_srs/srs/protocol/amf0"
+	"go_srs/srs/protocol/packet"
 	"go_srs/srs/protocol/rtmp"
 	"go_srs/srs/utils"
 	"os"
@@ -166,76 +166,34 @@ func (this *SrsFlvSegment) Close() error {
 	return nil
 }
 
-func (this *SrsFlvSegment) WriteMetaData(msg *rtmp.SrsRtmpMessage) error {
+func (this *SrsFlvSegment) WriteMetaData(msg *rtmp.SrsSharedPtrMessage) error {
 	stream := utils.NewSrsStream(msg.GetPayload())
 
 	var command amf0.SrsAmf0String
 	if err := command.Decode(stream); err != nil {
-		fmt.Println("111111111111111111")
 		return err
 	}
 
-	var name amf0.SrsAmf0String
-	if err := name.Decode(stream); err != nil {
+	pkt := packet.NewSrsOnMetaDataPacket(amf0.SRS_CONSTS_RTMP_SET_DATAFRAME)
+	if err := pkt.Decode(stream); err != nil {
 		return err
 	}
 
-	marker, err := stream.PeekByte()
-	if err != nil {
-		return err
+	if pkt.MetaData == nil {
+		return errors.New("dvr metadata must be object or ecma array")
 	}
 
-	var metaData amf0.ISrsAmf0Any
-	switch marker {
-	case amf0.RTMP_AMF0_Object:
-		{
-			metaData = amf0.GenerateSrsAmf0Any(marker)
-		}
-	case amf0.RTMP_AMF0_EcmaArray:
-		{
-			metaData = amf0.GenerateSrsAmf0Any(marker)
-		}
-	default:
-		{
-			return errors.New("error marker")
-		}
-	}
+	// edit the metadata in place, keep the order and type of properties of publisher.
+	pkt.Remove("fileSize")
+	pkt.Remove("framerate")
+	pkt.Set("service", global.RTMP_SIG_SRS_SERVER)
 
-	if metaData != nil {
-		if err = metaData.Decode(stream); err != nil {
-			return err
-		}
-	}
-
-	switch marker {
-	case amf0.RTMP_AMF0_Object:
-		{
-			metaData.(*amf0.SrsAmf0Object).Remove("fileSize")
-			metaData.(*amf0.SrsAmf0Object).Remove("framerate")
-			metaData.(*amf0.SrsAmf0Object).Set("service", global.RTMP_SIG_SRS_SERVER)
-			metaData.(*amf0.SrsAmf0Object).Set("filesize", float64(0))
-			metaData.(*amf0.SrsAmf0Object).Set("duration", float64(0))
-		}
-	case amf0.RTMP_AMF0_EcmaArray:
-		{
-			metaData.(*amf0.SrsAmf0EcmaArray).Remove("fileSize")
-			metaData.(*amf0.SrsAmf0EcmaArray).Remove("framerate")
-			metaData.(*amf0.SrsAmf0EcmaArray).Set("service", global.RTMP_SIG_SRS_SERVER)
-			metaData.(*amf0.SrsAmf0EcmaArray).Set("filesize", float64(0))
-			metaData.(*amf0.SrsAmf0EcmaArray).Set("duration", float64(0))
-		}
-	default:
-		{
-			return errors.New("error marker")
-		}
-	}
+	// the filesize and duration must be the last, which are updated when close.
+	pkt.Set("filesize", float64(0))
+	pkt.Set("duration", float64(0))
 
 	writeStream := utils.NewSrsStream([]byte{})
-	if err = name.Encode(writeStream); err != nil {
-		return err
-	}
-
-	if err = metaData.Encode(writeStream); err != nil {
+	if err := pkt.Encode(writeStream); err != nil {
 		return err
 	}
 
@@ -252,7 +210,7 @@ func (this *SrsFlvSegment) WriteMetaData(msg *rtmp.SrsRtmpMessage) error {
 	return err
 }
 
-func (this *SrsFlvSegment) onUpdateDuration(msg *rtmp.SrsRtmpMessage) error {
+func (this *SrsFlvSegment) onUpdateDuration(msg *rtmp.SrsSharedPtrMessage) error {
 	if this.startTime < 0 {
 		this.startTime = msg.GetHeader().GetTimestamp()
 	}
@@ -266,13 +224,13 @@ func (this *SrsFlvSegment) onUpdateDuration(msg *rtmp.SrsRtmpMessage) error {
 	return nil
 }
 
-func (this *SrsFlvSegment) WriteAudio(msg *rtmp.SrsRtmpMessage) error {
+func (this *SrsFlvSegment) WriteAudio(msg *rtmp.SrsSharedPtrMessage) error {
 	this.flvEncoder.WriteAudio(uint32(msg.GetHeader().GetTimestamp()), msg.GetPayload())
 	this.onUpdateDuration(msg)
 	return nil
 }
 
-func (this *SrsFlvSegment) WriteVideo(msg *rtmp.SrsRtmpMessage) error {
+func (this *SrsFlvSegment) WriteVideo(msg *rtmp.SrsSharedPtrMessage) error {
 	this.flvEncoder.WriteVideo(uint32(msg.GetHeader().GetTimestamp()), msg.GetPayload())
 	this.onUpdateDuration(msg)
 	return nil
@@ -102,8 +102,8 @@ func (this *SrsHlsCache) onSequenceHeader(muxer *SrsHlsMuxer) error {
 /**
 * write audio to cache, if need to flush, flush to muxer.
  */
-func (this *SrsHlsCache) writeAudio(c *SrsAvcAacCodec, muxer *SrsHlsMuxer, dts int64, sampler *SrsCodecSampler) error {
-	if err := this.cache.cache_audio(c, dts, sampler); err != nil {
+func (this *SrsHlsCache) writeAudio(c *SrsAvcAacCodec, muxer *SrsHlsMuxer, dts int64, sampler *SrsCodecSampler, track uint8) error {
+	if err := this.cache.cache_audio(c, dts, sampler, track); err != nil {
 		return err
 	}
 
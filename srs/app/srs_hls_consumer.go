@@ -23,6 +23,7 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package app
 
 import (
+	log "github.com/sirupsen/logrus"
 	"go_srs/srs/codec"
 	"go_srs/srs/protocol/rtmp"
 	"go_srs/srs/utils"
@@ -37,6 +38,8 @@ type SrsHlsConsumer struct {
 	muxer    *SrsHlsMuxer
 	hlsCache *SrsHlsCache
 	context  *SrsTsContext
+	// the audio tracks of multitrack stream, the codec is for the default track.
+	audioTracks *SrsTsAudioTracks
 
 	lastUpdateTime int64
 	streamDts      int64
@@ -44,16 +47,18 @@ type SrsHlsConsumer struct {
 }
 
 func NewSrsHlsConsumer(s *SrsSource, req *SrsRequest) *SrsHlsConsumer {
+	c := NewSrsAvcAacCodec()
 	return &SrsHlsConsumer{
-		source:    s,
-		req:       req,
-		queue:     NewSrsMessageQueue(),
-		codec:     NewSrsAvcAacCodec(),
-		sampler:   NewSrsCodecSampler(),
-		muxer:     NewSrsHlsMuxer(),
-		hlsCache:  NewSrsHlsCache(),
-		context:   NewSrsTsContext(),
-		consuming: false,
+		source:      s,
+		req:         req,
+		queue:       NewSrsMessageQueue(),
+		codec:       c,
+		sampler:     NewSrsCodecSampler(),
+		muxer:       NewSrsHlsMuxer(),
+		hlsCache:    NewSrsHlsCache(),
+		context:     NewSrsTsContext(),
+		consuming:   false,
+		audioTracks: NewSrsTsAudioTracks(c),
 	}
 }
 
@@ -97,13 +102,17 @@ func (this *SrsHlsConsumer) ConsumeCycle() error {
 					return err
 				}
 			} else {
+				if err := this.onMetadata(msg); err != nil {
+					log.Warn("hls ignore metadata, err=", err)
+				}
 			}
+			msg.Free()
 		}
 	}
 	return nil
 }
 
-func (this *SrsHlsConsumer) onVideo(video *rtmp.SrsRtmpMessage) error {
+func (this *SrsHlsConsumer) onVideo(video *rtmp.SrsSharedPtrMessage) error {
 	this.lastUpdateTime = utils.GetCurrentMs()
 
 	this.sampler.Clear()
@@ -133,26 +142,34 @@ func (this *SrsHlsConsumer) onVideo(video *rtmp.SrsRtmpMessage) error {
 	return nil
 }
 
-func (this *SrsHlsConsumer) onAudio(audio *rtmp.SrsRtmpMessage) error {
+func (this *SrsHlsConsumer) onAudio(audio *rtmp.SrsSharedPtrMessage) error {
 	this.lastUpdateTime = utils.GetCurrentMs()
 
+	track := audio.GetTrackId()
+	c := this.audioTracks.codec(track)
+
 	this.sampler.Clear()
-	err := this.codec.audioAACDemux(audio.GetPayload(), this.sampler)
+	err := c.audioAACDemux(audio.GetPayload(), this.sampler)
 	if err != nil {
 		return err
 	}
 
-	acodec := codec.SrsCodecAudio(this.codec.audioCodecId)
-	//not support
-	if acodec != codec.SrsCodecAudioAAC && acodec != codec.SrsCodecAudioMP3 {
+	acodec := codec.SrsCodecAudio(c.audioCodecId)
+	//not support, the extra tracks must be aac.
+	if acodec != codec.SrsCodecAudioAAC && (track > 0 || acodec != codec.SrsCodecAudioMP3) {
 		return nil
 	}
 
-	if err := this.muxer.updateACodec(acodec); err != nil {
-		return err
+	if track == 0 {
+		if err := this.muxer.updateACodec(acodec); err != nil {
+			return err
+		}
 	}
 
 	if acodec == codec.SrsCodecAudioAAC && this.sampler.AacPacketType == codec.SrsCodecAudioTypeSequenceHeader {
+		if err := this.muxer.updateAudioTrack(track, this.audioTracks.language(track)); err != nil {
+			return err
+		}
 		return this.hlsCache.onSequenceHeader(this.muxer)
 	}
 	//todo config jitter
@@ -160,14 +177,14 @@ func (this *SrsHlsConsumer) onAudio(audio *rtmp.SrsRtmpMessage) error {
 	// for pure audio, we need to update the stream dts also.
 	this.streamDts = dts
 
-	if err := this.hlsCache.writeAudio(this.codec, this.muxer, dts, this.sampler); err != nil {
+	if err := this.hlsCache.writeAudio(c, this.muxer, dts, this.sampler, track); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (this *SrsHlsConsumer) onMetadata(metaData *rtmp.SrsRtmpMessage) error {
-	return nil
+func (this *SrsHlsConsumer) onMetadata(metaData *rtmp.SrsSharedPtrMessage) error {
+	return this.audioTracks.onMetaData(metaData.GetPayload())
 }
 
 func (this *SrsHlsConsumer) StopConsume() error {
@@ -179,6 +196,16 @@ func (this *SrsHlsConsumer) OnRecvError(err error) {
 	this.source.OnConsumerError(this)
 }
 
-func (this *SrsHlsConsumer) Enqueue(msg *rtmp.SrsRtmpMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
-	this.queue.Enqueue(msg)
+/**
+ * the hls muxes all audio tracks and the default video track.
+ */
+func (this *SrsHlsConsumer) AcceptTrack(msg *rtmp.SrsSharedPtrMessage) bool {
+	if msg.GetHeader().IsAudio() {
+		return msg.GetTrackId() < TS_AUDIO_MAX_TRACKS
+	}
+	return msg.GetTrackId() == 0
+}
+
+func (this *SrsHlsConsumer) Enqueue(msg *rtmp.SrsSharedPtrMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
+	this.queue.Enqueue(msg.Copy())
 }
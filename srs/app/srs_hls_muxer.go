@@ -63,11 +63,14 @@ type SrsHlsMuxer struct {
 	current            *SrsHlsSegment
 	acodec             codec.SrsCodecAudio
 	context            *SrsTsContext
+	// the languages of audio tracks, kept for each segment.
+	audioTracks map[uint8]string
 }
 
 func NewSrsHlsMuxer() *SrsHlsMuxer {
 	return &SrsHlsMuxer{
-		context: NewSrsTsContext(),
+		context:     NewSrsTsContext(),
+		audioTracks: make(map[uint8]string),
 	}
 }
 
@@ -199,6 +202,17 @@ func (this *SrsHlsMuxer) updateACodec(ac codec.SrsCodecAudio) error {
 	return this.current.muxer.UpdateACodec(ac)
 }
 
+/**
+ * update the audio track of multitrack stream, each track is an elementary stream of ts.
+ */
+func (this *SrsHlsMuxer) updateAudioTrack(track uint8, language string) error {
+	this.audioTracks[track] = language
+	if this.current == nil {
+		return nil
+	}
+	return this.current.muxer.UpdateAudioTrack(track, language)
+}
+
 const SRS_JUMP_WHEN_PIECE_DEVIATION = 20
 
 func (this *SrsHlsMuxer) segmentOpen(segment_start_dts int64) error {
@@ -261,6 +275,10 @@ func (this *SrsHlsMuxer) segmentOpen(segment_start_dts int64) error {
 	if default_acodec != codec.SrsCodecAudioReserved1 {
 		this.current.muxer.UpdateACodec(default_acodec)
 	}
+
+	for track, language := range this.audioTracks {
+		this.current.muxer.UpdateAudioTrack(track, language)
+	}
 	_ = tmp_file
 	//todo
 	// if err := this.current.muxer.open(tmp_file); err != nil {
@@ -0,0 +1,266 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package app
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"go_srs/srs/app/config"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+/**
+ * the http api of server, mounted at SRS_HTTP_API_PREFIX of the http_api listener,
+ * the response is json object, the code is 0 when success.
+ */
+const SRS_HTTP_API_PREFIX = "/api/v1/"
+
+const SRS_HTTP_API_SUCCESS = 0
+
+type SrsHttpApi struct {
+	mux *http.ServeMux
+}
+
+func NewSrsHttpApi() *SrsHttpApi {
+	api := &SrsHttpApi{
+		mux: http.NewServeMux(),
+	}
+	api.mux.HandleFunc(SRS_HTTP_API_PREFIX+"bandwidths", api.serveBandwidths)
+	api.mux.HandleFunc(SRS_HTTP_API_PREFIX+"clients", api.serveClients)
+	api.mux.HandleFunc(SRS_HTTP_API_PREFIX+"forwards", api.serveForwards)
+	api.mux.HandleFunc(SRS_HTTP_API_PREFIX+"pushes", api.servePushes)
+	api.mux.HandleFunc(SRS_HTTP_API_PREFIX+"streams", api.serveStreams)
+	return api
+}
+
+func (this *SrsHttpApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// only the read of api is allowed for other sites, the change requires preflight.
+	if r.Method == http.MethodGet && config.GetInstance().GetHttpApiCrossdomain() {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+	this.mux.ServeHTTP(w, r)
+}
+
+/**
+ * the recent results of bandwidth check, the kbps of play is the downlink of client.
+ */
+func (this *SrsHttpApi) serveBandwidths(w http.ResponseWriter, r *http.Request) {
+	srs_api_response(w, map[string]interface{}{
+		"code":       SRS_HTTP_API_SUCCESS,
+		"bandwidths": GetStatisticInstance().DumpBandwidths(),
+	})
+}
+
+/**
+ * the forwards of streams to the destinations of vhost,
+ * the state is connecting, forwarding or reconnecting.
+ */
+func (this *SrsHttpApi) serveForwards(w http.ResponseWriter, r *http.Request) {
+	srs_api_response(w, map[string]interface{}{
+		"code":     SRS_HTTP_API_SUCCESS,
+		"forwards": GetStatisticInstance().DumpForwards(SRS_FORWARD_TYPE_FORWARD),
+	})
+}
+
+/**
+ * the push of stream to add, the url is the destination, for example,
+ * {"vhost":"", "app":"live", "stream":"livestream", "url":"rtmp://a.rtmp.youtube.com/live2/xxxx"}
+ */
+type SrsHttpApiPush struct {
+	Vhost  string `json:"vhost"`
+	App    string `json:"app"`
+	Stream string `json:"stream"`
+	Url    string `json:"url"`
+}
+
+/**
+ * the pushes of publishing streams, GET to list the pushes, POST the SrsHttpApiPush
+ * to push the stream, and DELETE ?id=xxx to stop the push,
+ * the POST and DELETE require the token of http_api by header Authorization: Bearer xxx.
+ */
+func (this *SrsHttpApi) servePushes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		if err := srs_api_authorize(r); err != nil {
+			srs_api_error(w, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		{
+			srs_api_response(w, map[string]interface{}{
+				"code":   SRS_HTTP_API_SUCCESS,
+				"pushes": GetStatisticInstance().DumpForwards(SRS_FORWARD_TYPE_PUSH),
+			})
+		}
+	case http.MethodPost:
+		{
+			this.addPush(w, r)
+		}
+	case http.MethodDelete:
+		{
+			this.removePush(w, r)
+		}
+	default:
+		{
+			srs_api_error(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		}
+	}
+}
+
+func (this *SrsHttpApi) addPush(w http.ResponseWriter, r *http.Request) {
+	// the json content type is not simple, the browser never posts it to other sites without preflight.
+	if t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || t != "application/json" {
+		srs_api_error(w, http.StatusUnsupportedMediaType, errors.New("push requires application/json"))
+		return
+	}
+
+	push := &SrsHttpApiPush{}
+	if err := json.NewDecoder(r.Body).Decode(push); err != nil {
+		srs_api_error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if push.App == "" || push.Stream == "" || push.Url == "" {
+		srs_api_error(w, http.StatusBadRequest, errors.New("push requires app, stream and url"))
+		return
+	}
+
+	if u, err := url.Parse(push.Url); err != nil || (u.Scheme != "rtmp" && u.Scheme != "rtmps") || u.Host == "" {
+		srs_api_error(w, http.StatusBadRequest, errors.New("push requires rtmp or rtmps url"))
+		return
+	}
+
+	req := NewSrsRequest()
+	req.vhost = push.Vhost
+	req.app = push.App
+	req.stream = push.Stream
+	source := FetchSource(req)
+	if source == nil {
+		srs_api_error(w, http.StatusNotFound, errors.New("stream not found"))
+		return
+	}
+
+	forwarder, err := source.AddPush(push.Url)
+	if err != nil {
+		srs_api_error(w, http.StatusConflict, err)
+		return
+	}
+
+	srs_api_response(w, map[string]interface{}{
+		"code": SRS_HTTP_API_SUCCESS,
+		"id":   forwarder.id,
+	})
+}
+
+func (this *SrsHttpApi) removePush(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		srs_api_error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := RemoveSrsPush(id); err != nil {
+		srs_api_error(w, http.StatusNotFound, err)
+		return
+	}
+
+	srs_api_response(w, map[string]interface{}{
+		"code": SRS_HTTP_API_SUCCESS,
+	})
+}
+
+/**
+ * the rtmp clients, the rtt_ms is the round trip time measured by ping.
+ */
+func (this *SrsHttpApi) serveClients(w http.ResponseWriter, r *http.Request) {
+	srs_api_response(w, map[string]interface{}{
+		"code":    SRS_HTTP_API_SUCCESS,
+		"clients": GetStatisticInstance().DumpClients(),
+	})
+}
+
+/**
+ * the streams, the video codec is H264, HEVC, AV1 or VP9 when got sequence header.
+ */
+func (this *SrsHttpApi) serveStreams(w http.ResponseWriter, r *http.Request) {
+	srs_api_response(w, map[string]interface{}{
+		"code":    SRS_HTTP_API_SUCCESS,
+		"streams": GetStatisticInstance().DumpStreams(),
+	})
+}
+
+var errHttpApiNoToken = errors.New("http api token not configured")
+var errHttpApiUnauthorized = errors.New("http api token not match")
+
+/**
+ * authorize the request which changes the server by the token of http_api,
+ * the change is disabled when no token configured.
+ */
+func srs_api_authorize(r *http.Request) error {
+	token := config.GetInstance().GetHttpApiToken()
+	if token == "" {
+		return errHttpApiNoToken
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return errHttpApiUnauthorized
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+		return errHttpApiUnauthorized
+	}
+	return nil
+}
+
+func srs_api_response(w http.ResponseWriter, data map[string]interface{}) {
+	srs_api_write(w, http.StatusOK, data)
+}
+
+/**
+ * response the error of api, the code is the http status.
+ */
+func srs_api_error(w http.ResponseWriter, status int, err error) {
+	srs_api_write(w, status, map[string]interface{}{
+		"code":  status,
+		"error": err.Error(),
+	})
+}
+
+func srs_api_write(w http.ResponseWriter, status int, data map[string]interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -35,6 +35,8 @@ type SrsHttpFlvConsumer struct {
 	StreamId   int
 	writer     http.ResponseWriter
 	flvEncoder *flvcodec.SrsFlvEncoder
+	// the tracks to play of multitrack stream.
+	tracks *SrsPlayTracks
 }
 
 func NewSrsHttpFlvConsumer(s *SrsSource, w http.ResponseWriter, r *http.Request) *SrsHttpFlvConsumer {
@@ -44,6 +46,7 @@ func NewSrsHttpFlvConsumer(s *SrsSource, w http.ResponseWriter, r *http.Request)
 		queue:      NewSrsMessageQueue(),
 		StreamId:   0,
 		flvEncoder: flvcodec.NewSrsFlvEncoder(w),
+		tracks:     NewSrsPlayTracks(r.URL.Query()),
 	}
 }
 
@@ -60,7 +63,7 @@ func (this *SrsHttpFlvConsumer) ConsumeCycle() error {
 	go func() {
 		notify := this.writer.(http.CloseNotifier).CloseNotify()
 		<-notify
-		this.StopConsume()
+		this.source.RemoveConsumer(this)
 	}()
 	this.writer.Header().Set("Content-Type", "video/x-flv")
 	for {
@@ -80,21 +83,28 @@ func (this *SrsHttpFlvConsumer) ConsumeCycle() error {
 			} else {
 				this.flvEncoder.WriteMetaData(msg.GetPayload())
 			}
+			msg.Free()
 		}
 	}
 }
 
+/**
+ * called by source when removing the consumer, never remove from source again.
+ */
 func (this *SrsHttpFlvConsumer) StopConsume() error {
-	this.source.RemoveConsumer(this)
 	//send connection close to response writer
 	this.queue.Break()
 	return nil
 }
 
 func (this *SrsHttpFlvConsumer) OnRecvError(err error) {
-	this.StopConsume()
+	this.source.OnConsumerError(this)
 }
 
-func (this *SrsHttpFlvConsumer) Enqueue(msg *rtmp.SrsRtmpMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
-	this.queue.Enqueue(msg)
+func (this *SrsHttpFlvConsumer) AcceptTrack(msg *rtmp.SrsSharedPtrMessage) bool {
+	return this.tracks.accept(msg)
+}
+
+func (this *SrsHttpFlvConsumer) Enqueue(msg *rtmp.SrsSharedPtrMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
+	this.queue.Enqueue(msg.Copy())
 }
@@ -23,8 +23,89 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 
 package app
 
-func OnPublish(url string, req *SrsRequest) error {
-	return nil
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * the timeout of http hooks, the client is rejected when hook timeout.
+ */
+const SRS_HTTP_HOOKS_TIMEOUT = 30 * time.Second
+
+/**
+ * the response of http hooks, the hook responses 0 or the object with code 0 to allow the client.
+ */
+type SrsHttpHooksResponse struct {
+	Code int `json:"code"`
+	// the destinations to push the stream to, only for on_publish,
+	// for example, rtmp://a.rtmp.youtube.com/live2/xxxx
+	Pushes []string `json:"pushes"`
+}
+
+/**
+ * notify the on_publish hook, the publish is rejected when hook fails.
+ * @return the destinations to push the stream to, responsed by hook.
+ */
+func OnPublish(url string, req *SrsRequest) ([]string, error) {
+	if url == "" {
+		return nil, nil
+	}
+
+	res, err := srs_http_hooks_call(url, map[string]interface{}{
+		"action": "on_publish",
+		"ip":     req.ip,
+		"vhost":  req.vhost,
+		"app":    req.app,
+		"tcUrl":  req.tcUrl,
+		"stream": req.stream,
+		"param":  req.param,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Pushes, nil
+}
+
+func srs_http_hooks_call(url string, data map[string]interface{}) (*SrsHttpHooksResponse, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: SRS_HTTP_HOOKS_TIMEOUT}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("http hook " + url + " status " + strconv.Itoa(resp.StatusCode))
+	}
+
+	res := &SrsHttpHooksResponse{}
+	if strings.TrimSpace(string(body)) != "0" {
+		if err := json.Unmarshal(body, res); err != nil {
+			return nil, err
+		}
+	}
+
+	if res.Code != 0 {
+		return nil, errors.New("http hook " + url + " rejects, code=" + strconv.Itoa(res.Code))
+	}
+	return res, nil
 }
 
 func OnUnPublish(url string, req *SrsRequest) error {
@@ -23,19 +23,24 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package app
 
 import (
+	"errors"
 	"fmt"
+	log "github.com/sirupsen/logrus"
+	"go_srs/srs/app/config"
+	"net"
 	"net/http"
-	"net/url"
 	"strings"
 )
 
 type SrsHttpStreamServer struct {
 	sources map[string]*SrsSource
+	handler ISrsSourceHandler
 }
 
-func NewSrsHttpStreamServer() *SrsHttpStreamServer {
+func NewSrsHttpStreamServer(h ISrsSourceHandler) *SrsHttpStreamServer {
 	return &SrsHttpStreamServer{
 		sources: make(map[string]*SrsSource),
+		handler: h,
 	}
 }
 
@@ -63,40 +68,89 @@ func (this *SrsHttpStreamServer) CreateTsConsumer(s *SrsSource, w http.ResponseW
 
 func (this *SrsHttpStreamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("url=", r.URL.Path)
+	var ext string
+	var create func(s *SrsSource, w http.ResponseWriter, r *http.Request) Consumer
 	if strings.HasSuffix(r.URL.Path, ".ts") {
-		s := strings.Replace(r.URL.Path, ".ts", "", -1)
-		m, _ := url.ParseQuery(r.URL.RawQuery)
-		vHostParams, ok := m["vhost"]
-		vhost := "__defaultVhost__"
-		if ok {
-			vhost = vHostParams[0]
-		}
-		source, ok := sourcePool[vhost+s]
-		if !ok {
-			return
-		}
-		fmt.Println("Create Ts Consumer)")
-		consumer := this.CreateTsConsumer(source, w, r)
-		err := consumer.ConsumeCycle()
-		_ = err
-		return
+		ext, create = ".ts", this.CreateTsConsumer
 	} else if strings.HasSuffix(r.URL.Path, ".flv") {
-		s := strings.Replace(r.URL.Path, ".flv", "", -1)
-		m, _ := url.ParseQuery(r.URL.RawQuery)
-		vHostParams, ok := m["vhost"]
-		vhost := "__defaultVhost__"
-		if ok {
-			vhost = vHostParams[0]
-		}
+		ext, create = ".flv", this.CreateFlvConsumer
+	} else {
+		return
+	}
 
-		source, ok := sourcePool[vhost+s]
-		if !ok {
-			return
-		}
-		fmt.Println("Create flv Consumer)")
-		consumer := this.CreateFlvConsumer(source, w, r)
-		err := consumer.ConsumeCycle()
-		_ = err
+	req := srs_http_stream_request(r, ext)
+	if req.stream == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	source, err := this.acquirePlay(req)
+	if err == errHttpStreamNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Info("http stream play ", req.GetStreamUrl(), " failed, err=", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer source.onEdgeStopPlay()
+
+	log.Info("create ", ext, " consumer of ", req.GetStreamUrl())
+	consumer := create(source, w, r)
+	if consumer == nil {
 		return
 	}
+	consumer.ConsumeCycle()
+}
+
+var errHttpStreamNotFound = errors.New("http stream not found")
+
+/**
+ * fetch the publishing source to play, only the edge creates the source to pull from origin,
+ * fetch a new source when the edge of source is stopped.
+ */
+func (this *SrsHttpStreamServer) acquirePlay(req *SrsRequest) (*SrsSource, error) {
+	if !config.GetVhostIsEdge(req.vhost) {
+		if source := FetchSource(req); source != nil {
+			return source, nil
+		}
+		return nil, errHttpStreamNotFound
+	}
+
+	source, err := FetchOrCreate(nil, req, this.handler)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = source.onEdgeStartPlay(); err != errEdgeStopped {
+		return source, err
+	}
+
+	if source, err = FetchOrCreate(nil, req, this.handler); err != nil {
+		return nil, err
+	}
+	return source, source.onEdgeStartPlay()
+}
+
+/**
+ * the request of http stream, the path is /app/stream.flv or /app/stream.ts,
+ * the vhost is specified by query ?vhost=xxx as rtmp.
+ */
+func srs_http_stream_request(r *http.Request, ext string) *SrsRequest {
+	req := NewSrsRequest()
+	req.ip, _, _ = net.SplitHostPort(r.RemoteAddr)
+	req.schema = "http"
+	req.host = r.Host
+	req.vhost = r.URL.Query().Get("vhost")
+	req.param = r.URL.RawQuery
+
+	path := strings.TrimSuffix(strings.Trim(r.URL.Path, "/"), ext)
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		req.app, req.stream = path[:i], path[i+1:]
+	} else {
+		req.stream = path
+	}
+	req.tcUrl = "http://" + r.Host + "/" + req.app
+	return req
 }
@@ -58,7 +58,7 @@ func (this *SrsHttpTsConsumer) ConsumeCycle() error {
 	go func() {
 		notify := this.writer.(http.CloseNotifier).CloseNotify()
 		<-notify
-		this.StopConsume()
+		this.source.RemoveConsumer(this)
 	}()
 	this.writer.Header().Set("Content-Type", "video/MP2T")
 	for {
@@ -71,24 +71,38 @@ func (this *SrsHttpTsConsumer) ConsumeCycle() error {
 			if msg.GetHeader().IsVideo() {
 				this.tsEncoder.WriteVideo(uint32(msg.GetHeader().GetTimestamp()), msg.GetPayload())
 			} else if msg.GetHeader().IsAudio() {
-				this.tsEncoder.WriteAudio(uint32(msg.GetHeader().GetTimestamp()), msg.GetPayload())
+				this.tsEncoder.WriteAudio(uint32(msg.GetHeader().GetTimestamp()), msg.GetPayload(), msg.GetTrackId())
 			} else {
+				this.tsEncoder.WriteMetaData(msg.GetPayload())
 			}
+			msg.Free()
 		}
 	}
 }
 
+/**
+ * called by source when removing the consumer, never remove from source again.
+ */
 func (this *SrsHttpTsConsumer) StopConsume() error {
-	this.source.RemoveConsumer(this)
 	//send connection close to response writer
 	this.queue.Break()
 	return nil
 }
 
 func (this *SrsHttpTsConsumer) OnRecvError(err error) {
-	this.StopConsume()
+	this.source.OnConsumerError(this)
 }
 
-func (this *SrsHttpTsConsumer) Enqueue(msg *rtmp.SrsRtmpMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
-	this.queue.Enqueue(msg)
+/**
+ * the ts muxes all audio tracks and the default video track.
+ */
+func (this *SrsHttpTsConsumer) AcceptTrack(msg *rtmp.SrsSharedPtrMessage) bool {
+	if msg.GetHeader().IsAudio() {
+		return msg.GetTrackId() < TS_AUDIO_MAX_TRACKS
+	}
+	return msg.GetTrackId() == 0
+}
+
+func (this *SrsHttpTsConsumer) Enqueue(msg *rtmp.SrsSharedPtrMessage, atc bool, jitterAlgorithm *SrsRtmpJitterAlgorithm) {
+	this.queue.Enqueue(msg.Copy())
 }
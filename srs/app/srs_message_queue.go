@@ -24,10 +24,11 @@ package app
 
 import (
 	"errors"
-	"fmt"
 	log "github.com/sirupsen/logrus"
 	"go_srs/srs/codec/flv"
 	"go_srs/srs/protocol/rtmp"
+	"sync"
+	"time"
 )
 
 type SrsMessageQueue struct {
@@ -36,9 +37,13 @@ type SrsMessageQueue struct {
 	avEndTime    int64
 	queueSizeMs  int
 
-	msgs     []*rtmp.SrsRtmpMessage
-	msgCount chan int
-	exit     chan bool
+	lock   sync.Mutex
+	msgs   []*rtmp.SrsSharedPtrMessage
+	notify chan bool
+	// to interrupt the wait, for example, the control message of player.
+	wakeup    chan bool
+	exit      chan bool
+	breakOnce sync.Once
 }
 
 func NewSrsMessageQueue() *SrsMessageQueue {
@@ -47,23 +52,28 @@ func NewSrsMessageQueue() *SrsMessageQueue {
 		avStartTime:  0,
 		avEndTime:    0,
 		queueSizeMs:  0,
-		msgs:         make([]*rtmp.SrsRtmpMessage, 0),
-		msgCount:     make(chan int, 10000),
+		msgs:         make([]*rtmp.SrsSharedPtrMessage, 0),
+		notify:       make(chan bool, 1),
+		wakeup:       make(chan bool, 1),
 		exit:         make(chan bool),
 	}
 }
 
-func (this *SrsMessageQueue) Enqueue(msg *rtmp.SrsRtmpMessage) {
-	// if msg == nil {
-	// 	fmt.Println("enque nil*******************")
-	// } else {
-	// 	fmt.Println("enqueue no nil*************")
-	// }
+func (this *SrsMessageQueue) Enqueue(msg *rtmp.SrsSharedPtrMessage) {
+	this.lock.Lock()
 	this.msgs = append(this.msgs, msg)
-	this.msgCount <- len(this.msgs)
+	this.lock.Unlock()
+
+	// wakeup the waiter, never block the publisher.
+	select {
+	case this.notify <- true:
+	default:
+	}
 }
 
 func (this *SrsMessageQueue) Size() int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
 	return len(this.msgs)
 }
 
@@ -76,36 +86,117 @@ func (this *SrsMessageQueue) SetQueueSize(queueSize float64) {
 }
 
 func (this *SrsMessageQueue) Empty() bool {
-	return len(this.msgs) == 0
+	return this.Size() == 0
 }
 
 func (this *SrsMessageQueue) Break() {
-	close(this.exit)
+	this.breakOnce.Do(func() {
+		close(this.exit)
+	})
+}
+
+/**
+ * interrupt the WaitBatch or WaitSignal, never block.
+ */
+func (this *SrsMessageQueue) Wakeup() {
+	select {
+	case this.wakeup <- true:
+	default:
+	}
 }
 
-func (this *SrsMessageQueue) Wait() (*rtmp.SrsRtmpMessage, error) {
+/**
+ * wait for new message or wakeup, without dequeue the messages.
+ */
+func (this *SrsMessageQueue) WaitSignal() error {
 	select {
-	case <-this.msgCount:
+	case <-this.notify:
+	case <-this.wakeup:
+	case <-this.exit:
 		{
-			if len(this.msgs) <= 0 {
-				return nil, nil
-			}
+			log.Info("break from queue")
+			return errors.New("queue break")
+		}
+	}
+	return nil
+}
 
+func (this *SrsMessageQueue) Wait() (*rtmp.SrsSharedPtrMessage, error) {
+	for {
+		this.lock.Lock()
+		if len(this.msgs) > 0 {
 			msg := this.msgs[0]
 			this.msgs = this.msgs[1:]
-			if msg == nil {
-				fmt.Println("msg is nil")
-			}
+			this.lock.Unlock()
 			return msg, nil
 		}
-	case <-this.exit:
-		{
-			log.Info("break from queue")
-			return nil, errors.New("queue break")
+		this.lock.Unlock()
+
+		select {
+		case <-this.notify:
+		case <-this.exit:
+			{
+				log.Info("break from queue")
+				return nil, errors.New("queue break")
+			}
+		}
+	}
+}
+
+/**
+ * wait for a batch of messages for merged write.
+ * block until there is at least one message, then wait at most latency
+ * for more messages, return when got max messages or the latency elapsed.
+ * @remark return the messages in queue, maybe empty, when wakeup.
+ * @param latency the merged-write latency, 0 to return what in queue immediately.
+ * @param max the max messages to dump, 0 for all.
+ */
+func (this *SrsMessageQueue) WaitBatch(latency time.Duration, max int) ([]*rtmp.SrsSharedPtrMessage, error) {
+	var timer <-chan time.Time
+	for {
+		size := this.Size()
+		if size > 0 && (latency <= 0 || (max > 0 && size >= max)) {
+			return this.DumpPackets(max), nil
+		}
+
+		if size > 0 && timer == nil {
+			timer = time.After(latency)
+		}
+
+		select {
+		case <-this.notify:
+		case <-timer:
+			return this.DumpPackets(max), nil
+		case <-this.wakeup:
+			return this.DumpPackets(max), nil
+		case <-this.exit:
+			{
+				log.Info("break from queue")
+				return nil, errors.New("queue break")
+			}
 		}
 	}
 }
 
+/**
+ * dump the messages from the front of queue.
+ * @param max the max messages to dump, 0 for all.
+ */
+func (this *SrsMessageQueue) DumpPackets(max int) []*rtmp.SrsSharedPtrMessage {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	count := len(this.msgs)
+	if max > 0 && count > max {
+		count = max
+	}
+
+	msgs := make([]*rtmp.SrsSharedPtrMessage, count)
+	copy(msgs, this.msgs[:count])
+	this.msgs = this.msgs[count:]
+	return msgs
+}
+
 //todo dump packets with jitter algorithm
 
 /**
@@ -113,8 +204,11 @@ func (this *SrsMessageQueue) Wait() (*rtmp.SrsRtmpMessage, error) {
 * if no iframe found, clear it.
  */
 func (this *SrsMessageQueue) Shrink() {
-	var videoSH *rtmp.SrsRtmpMessage
-	var audioSH *rtmp.SrsRtmpMessage
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	var videoSH *rtmp.SrsSharedPtrMessage
+	var audioSH *rtmp.SrsSharedPtrMessage
 	for i := 0; i < len(this.msgs); i++ {
 		//todo check is raw data?
 		if this.msgs[i].GetHeader().IsVideo() && flvcodec.VideoIsSequenceHeader(this.msgs[i].GetPayload()) {
@@ -125,7 +219,12 @@ func (this *SrsMessageQueue) Shrink() {
 			audioSH = this.msgs[i]
 		}
 	}
-	//clear
+	//clear, the sequence headers are kept, the header of copy is safe to change.
+	for i := 0; i < len(this.msgs); i++ {
+		if this.msgs[i] != videoSH && this.msgs[i] != audioSH {
+			this.msgs[i].Free()
+		}
+	}
 	this.msgs = this.msgs[0:0]
 
 	this.avStartTime = this.avEndTime
@@ -140,7 +239,81 @@ func (this *SrsMessageQueue) Shrink() {
 	}
 }
 
+/**
+ * drop the messages before the latest video keyframe, to resume from it,
+ * the sequence headers and metadata are kept.
+ * for the pure audio stream, drop all audio except the sequence header.
+ * @remark nothing dropped when the keyframe of video not arrived.
+ */
+func (this *SrsMessageQueue) ShrinkToKeyframe() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	pureAudio := true
+	keyframe := -1
+	for i := len(this.msgs) - 1; i >= 0; i-- {
+		h := this.msgs[i].GetHeader()
+		if !h.IsVideo() {
+			continue
+		}
+
+		pureAudio = false
+		payload := this.msgs[i].GetPayload()
+		if flvcodec.VideoIsKeyFrame(payload) && !flvcodec.VideoIsSequenceHeader(payload) {
+			keyframe = i
+			break
+		}
+	}
+
+	if keyframe < 0 {
+		if !pureAudio {
+			return
+		}
+		keyframe = len(this.msgs)
+	}
+
+	msgs := make([]*rtmp.SrsSharedPtrMessage, 0, len(this.msgs)-keyframe)
+	for i := 0; i < keyframe; i++ {
+		msg := this.msgs[i]
+		h := msg.GetHeader()
+		if !h.IsAV() || (h.IsVideo() && flvcodec.VideoIsSequenceHeader(msg.GetPayload())) ||
+			(h.IsAudio() && flvcodec.AudioIsSequenceHeader(msg.GetPayload())) {
+			msgs = append(msgs, msg)
+			continue
+		}
+		msg.Free()
+	}
+	this.msgs = append(msgs, this.msgs[keyframe:]...)
+}
+
+/**
+ * remove and free the messages which the drop returns true.
+ */
+func (this *SrsMessageQueue) RemoveIf(drop func(msg *rtmp.SrsSharedPtrMessage) bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	msgs := this.msgs[:0]
+	for _, msg := range this.msgs {
+		if drop(msg) {
+			msg.Free()
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	for i := len(msgs); i < len(this.msgs); i++ {
+		this.msgs[i] = nil
+	}
+	this.msgs = msgs
+}
+
 func (this *SrsMessageQueue) Clear() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for i := 0; i < len(this.msgs); i++ {
+		this.msgs[i].Free()
+	}
 	this.msgs = this.msgs[0:0]
 	this.avStartTime = -1
 	this.avEndTime = -1
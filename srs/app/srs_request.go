@@ -42,6 +42,8 @@ type SrsRequest struct {
 	stream         string
 	duration       float64
 	objectEncoding float64
+	// the tracks to play of enhanced rtmp multitrack stream.
+	tracks *SrsPlayTracks
 }
 
 func NewSrsRequest() *SrsRequest {
@@ -51,3 +53,11 @@ func NewSrsRequest() *SrsRequest {
 func (this SrsRequest) GetStreamUrl() string {
 	return utils.SrsGenerateStreamUrl(this.vhost, this.app, this.stream)
 }
+
+/**
+ * copy the request, each stream of connection has its own request.
+ */
+func (this *SrsRequest) Copy() *SrsRequest {
+	req := *this
+	return &req
+}
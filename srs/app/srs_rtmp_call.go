@@ -0,0 +1,66 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package app
+
+import (
+	"go_srs/srs/protocol/amf0"
+	"go_srs/srs/protocol/packet"
+	"sync"
+)
+
+/**
+ * the handler of client call, for example, the NetConnection.call of flash,
+ * the response is sent to client as _result, or _error with the description of err.
+ * @remark the handler is invoked in the recv thread of connection, so never wait
+ *       for the SrsRtmpConn.Call in it, which needs the recv thread to get the result.
+ */
+type SrsCallHandler func(conn *SrsRtmpConn, pkt *packet.SrsCallPacket) (amf0.ISrsAmf0Any, error)
+
+var callHandlersMtx sync.Mutex
+var callHandlers map[string]SrsCallHandler
+
+func init() {
+	callHandlers = make(map[string]SrsCallHandler)
+}
+
+/**
+ * register the handler for the call of client, the name is the command name of call,
+ * the previous handler of name is replaced.
+ */
+func RegisterCallHandler(name string, handler SrsCallHandler) {
+	callHandlersMtx.Lock()
+	defer callHandlersMtx.Unlock()
+	callHandlers[name] = handler
+}
+
+func UnregisterCallHandler(name string) {
+	callHandlersMtx.Lock()
+	defer callHandlersMtx.Unlock()
+	delete(callHandlers, name)
+}
+
+func fetchCallHandler(name string) SrsCallHandler {
+	callHandlersMtx.Lock()
+	defer callHandlersMtx.Unlock()
+	return callHandlers[name]
+}
@@ -23,9 +23,11 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package app
 
 import (
+	"context"
 	"errors"
 	log "github.com/sirupsen/logrus"
 	"go_srs/srs/app/config"
+	"go_srs/srs/protocol/amf0"
 	"go_srs/srs/protocol/kbps"
 	"go_srs/srs/protocol/packet"
 	"go_srs/srs/protocol/rtmp"
@@ -37,22 +39,36 @@ import (
 	"time"
 )
 
+/**
+ * the recv timeout when the player closed the stream, wait for a new play.
+ */
+const SRS_PAUSED_RECV_TIMEOUT = 30 * time.Minute
+
+/**
+ * the max streams created by createStream of a connection.
+ */
+const SRS_CONSTS_RTMP_MAX_STREAMS = 64
+
 type SrsRtmpConn struct {
-	id          int64
-	rtmp        *rtmp.SrsRtmpServer
-	req         *SrsRequest
-	res         *SrsResponse
-	server      *SrsServer
-	source      *SrsSource
-	kbps        *kbps.SrsKbps
-	clientType  rtmp.SrsRtmpConnType
-	recvThread  *SrsRecvThread
-	exitMonitor chan bool
+	id   int64
+	rtmp *rtmp.SrsRtmpServer
+	// the request of connect app, each stream copies it.
+	req        *SrsRequest
+	server     *SrsServer
+	kbps       *kbps.SrsKbps
+	recvThread *SrsRecvThread
+	// the error of recv thread, returned by the service cycle.
+	recvErr error
+	// the NetStreams created by createStream, key is the stream id,
+	// only accessed in the recv thread.
+	streams      map[int]*SrsRtmpStream
+	lastStreamId int
+	// the stream name of releaseStream or FCPublish, to identify the FMLE or haivision publisher.
+	fmleStreams map[string]rtmp.SrsRtmpConnType
+	// close the connection when no stream plays or publishes for a while.
+	idle *time.Timer
 	//to allow extern http api to expire the source
-	expire       chan bool
-	nb_msgs      int64
-	video_frames int64
-	audio_frames int64
+	expire chan bool
 }
 
 func NewSrsRtmpConn(c net.Conn, s *SrsServer) *SrsRtmpConn {
@@ -60,10 +76,10 @@ func NewSrsRtmpConn(c net.Conn, s *SrsServer) *SrsRtmpConn {
 	rtmpConn := &SrsRtmpConn{
 		id:          utils.SrsGenerateId(),
 		req:         NewSrsRequest(),
-		res:         NewSrsResponse(1),
 		server:      s,
 		kbps:        kbps.NewSrsKbps(),
-		exitMonitor: make(chan bool),
+		streams:     make(map[int]*SrsRtmpStream),
+		fmleStreams: make(map[string]rtmp.SrsRtmpConnType),
 		expire:      make(chan bool),
 	}
 	rtmpConn.kbps.SetIO(io, io)
@@ -75,65 +91,163 @@ func (this *SrsRtmpConn) ServiceLoop() error {
 	return this.doCycle()
 }
 
+/**
+ * route the message to stream by the message stream id,
+ * the commands of NetConnection are processed by connection.
+ */
 func (this *SrsRtmpConn) Handle(msg *rtmp.SrsRtmpMessage) error {
+	streamId := int(msg.GetHeader().GetStreamId())
 	if msg.GetHeader().IsAmf0Command() || msg.GetHeader().IsAmf3Command() {
 		pkt, err := this.rtmp.DecodeMessage(msg)
 		if err != nil {
 			return err
 		}
-		_ = pkt
-		//todo is_fmle process
+
+		err = this.processCommand(msg, pkt, streamId)
+		this.updateIdle()
+		return err
 	}
-	this.nb_msgs++
-	return this.processPublishMessage(msg)
-}
 
-func (this *SrsRtmpConn) processPublishMessage(msg *rtmp.SrsRtmpMessage) error {
-	//todo fix edge process
-	if msg.GetHeader().IsAudio() {
-		this.audio_frames++
-		if err := this.source.OnAudio(msg); err != nil {
+	if stream, ok := this.streams[streamId]; ok {
+		return stream.Handle(msg)
+	}
+	return nil
+}
 
+func (this *SrsRtmpConn) processCommand(msg *rtmp.SrsRtmpMessage, pkt packet.SrsPacket, streamId int) error {
+	switch p := pkt.(type) {
+	case *packet.SrsCreateStreamPacket:
+		{
+			return this.createStream(p)
 		}
+	case *packet.SrsDeleteStreamPacket:
+		{
+			this.deleteStream(int(p.StreamId.Value))
+			return nil
+		}
+	case *packet.SrsFMLEStartPacket:
+		{
+			return this.processFmleStart(p)
+		}
+	case *packet.SrsCallPacket:
+		{
+			return this.processCall(p)
+		}
+	case *packet.SrsCallResPacket:
+		{
+			this.rtmp.OnCallResult(p)
+			return nil
+		}
+	}
+
+	// the commands of NetStream, ignore the stream not created.
+	stream, ok := this.streams[streamId]
+	if !ok {
+		return nil
 	}
+	return stream.OnCommand(msg, pkt)
+}
 
-	if msg.GetHeader().IsVideo() {
-		this.video_frames++
-		if err := this.source.OnVideo(msg); err != nil {
+func (this *SrsRtmpConn) createStream(pkt *packet.SrsCreateStreamPacket) error {
+	if len(this.streams) >= SRS_CONSTS_RTMP_MAX_STREAMS {
+		return errors.New("exceed the max streams of connection")
+	}
 
-		}
+	this.lastStreamId++
+	streamId := this.lastStreamId
+	this.streams[streamId] = NewSrsRtmpStream(this, streamId)
+	return this.rtmp.ResponseCreateStream(pkt.TransactionId.Value, streamId)
+}
+
+func (this *SrsRtmpConn) deleteStream(streamId int) {
+	if stream, ok := this.streams[streamId]; ok {
+		stream.Close()
+		delete(this.streams, streamId)
 	}
-	//todo fix aggregate message
-	//todo fix amf0 or amf3 data
+}
 
-	// process onMetaData
-	if msg.GetHeader().IsAmf0Data() || msg.GetHeader().IsAmf3Data() {
-		pkt, err := this.rtmp.DecodeMessage(msg)
-		if err != nil {
-			return err
+/**
+ * process the releaseStream, FCPublish and FCUnpublish of FMLE and haivision encoder.
+ */
+func (this *SrsRtmpConn) processFmleStart(pkt *packet.SrsFMLEStartPacket) error {
+	streamName := pkt.StreamName.Value.Value
+	switch pkt.CommandName.Value.Value {
+	case amf0.RTMP_AMF0_COMMAND_RELEASE_STREAM:
+		{
+			this.fmleStreams[streamName] = rtmp.SrsRtmpConnFMLEPublish
 		}
-
-		switch pkt.(type) {
-		case *packet.SrsOnMetaDataPacket:
-			{
-				err := this.source.OnMetaData(msg, pkt.(*packet.SrsOnMetaDataPacket))
-				if err != nil {
-					return err
+	case amf0.RTMP_AMF0_COMMAND_FC_PUBLISH:
+		{
+			// the haivision encoder sends FCPublish without releaseStream.
+			if _, ok := this.fmleStreams[streamName]; !ok {
+				this.fmleStreams[streamName] = rtmp.SrsRtmpConnHaivisionPublish
+			}
+		}
+	case amf0.RTMP_AMF0_COMMAND_UNPUBLISH:
+		{
+			delete(this.fmleStreams, streamName)
+			if i := strings.Index(streamName, "?"); i >= 0 {
+				streamName = streamName[0:i]
+			}
+			for _, stream := range this.streams {
+				if stream.publishing && stream.req.stream == streamName {
+					return stream.FmleUnpublish(pkt.TransactionId.Value)
 				}
 			}
 		}
 	}
-	return nil
+	return this.rtmp.ResponseFMLEStart(pkt.TransactionId.Value)
+}
+
+/**
+ * process the call of client by the registered handler.
+ */
+func (this *SrsRtmpConn) processCall(pkt *packet.SrsCallPacket) error {
+	handler := fetchCallHandler(pkt.CommandName.Value.Value)
+	if handler == nil {
+		// ignore the calls not registered, for example, the _checkbw and getStreamLength.
+		return nil
+	}
+
+	response, err := handler(this, pkt)
+	return this.rtmp.ResponseCall(pkt.TransactionId.Value, response, err)
+}
+
+/**
+ * call the method of client and wait for the result, interrupted when ctx done.
+ * @remark never wait for it in the SrsCallHandler, which blocks the recv thread.
+ */
+func (this *SrsRtmpConn) Call(ctx context.Context, name string, args ...amf0.ISrsAmf0Any) (*packet.SrsCallResPacket, error) {
+	return this.rtmp.Call(ctx, name, args...)
 }
 
+/**
+ * close the connection when no stream plays or publishes,
+ * the timeout is larger after the stream closed, for the client maybe idle.
+ */
+func (this *SrsRtmpConn) updateIdle() {
+	for _, stream := range this.streams {
+		if stream.Active() {
+			this.idle.Stop()
+			return
+		}
+	}
+	this.idle.Reset(SRS_PAUSED_RECV_TIMEOUT)
+}
+
+/**
+ * stop the recv thread and close all streams.
+ */
 func (this *SrsRtmpConn) Stop() {
 	if this.recvThread != nil {
 		this.recvThread.Stop()
 	}
+	if this.idle != nil {
+		this.idle.Stop()
+	}
 	this.rtmp.Close()
-	if this.req.typ == rtmp.SrsRtmpConnFMLEPublish || this.req.typ == rtmp.SrsRtmpConnFlashPublish || this.req.typ == rtmp.SrsRtmpConnHaivisionPublish {
-		this.source.RemoveConsumers()
-		RemoveSrsSource(this.source)
+	for streamId := range this.streams {
+		this.deleteStream(streamId)
 	}
 }
 
@@ -154,14 +268,22 @@ func (this *SrsRtmpConn) doCycle() error {
 		return err
 	}
 
-	err = pkt.(*packet.SrsConnectAppPacket).CommandObj.Get("tcUrl", &this.req.tcUrl)
-	if err != nil {
+	obj, err := pkt.(*packet.SrsConnectAppPacket).GetCommandObj()
+	if errs, ok := err.(amf0.SrsAmf0FieldErrors); ok {
+		// tolerate the properties in unexpected type, the tcUrl is required below.
+		log.Warn("connect app skip properties, ", errs)
+	} else if err != nil {
 		return err
 	}
 
-	_ = pkt.(*packet.SrsConnectAppPacket).CommandObj.Get("pageUrl", &this.req.pageUrl)
-	_ = pkt.(*packet.SrsConnectAppPacket).CommandObj.Get("swfUrl", &this.req.swfUrl)
-	_ = pkt.(*packet.SrsConnectAppPacket).CommandObj.Get("objectEncoding", &this.req.objectEncoding)
+	if obj.TcUrl == "" {
+		return errors.New("invalid request, must specifies the tcUrl.")
+	}
+
+	this.req.tcUrl = obj.TcUrl
+	this.req.pageUrl = obj.PageUrl
+	this.req.swfUrl = obj.SwfUrl
+	this.req.objectEncoding = obj.ObjectEncoding
 	u, err := url.Parse(this.req.tcUrl)
 	this.req.schema = u.Scheme
 	this.req.host = u.Host
@@ -181,8 +303,7 @@ func (this *SrsRtmpConn) doCycle() error {
 		this.req.vhost = vhost[0]
 	}
 
-	this.serviceCycle()
-	return nil
+	return this.serviceCycle()
 }
 
 func (this *SrsRtmpConn) serviceCycle() error {
@@ -198,265 +319,91 @@ func (this *SrsRtmpConn) serviceCycle() error {
 
 	this.req.ip = this.rtmp.GetClientIP()
 
-	err = this.rtmp.SetChunkSize(config.GetInstance().GetChunkSize(this.req.vhost))
-	if err != nil {
-		return err
-	}
+	stat := GetStatisticInstance()
+	stat.OnClient(this.id, this.req, this.rtmp)
+	defer stat.OnDisconnect(this.id)
 
-	err = this.rtmp.ResponseConnectApp(this.req.objectEncoding)
-	if err != nil {
+	// the connection of bandwidth check vhost serves the check only.
+	if config.GetBandcheckEnabled(this.req.vhost) {
+		err = NewSrsBandwidth(this.rtmp, this.req).BandwidthCheck()
+		if err != nil {
+			log.Info("bandwidth check failed, err=", err)
+		}
+		this.Close()
 		return err
 	}
 
-	err = this.rtmp.OnBwDone()
+	err = this.rtmp.SetChunkSize(config.GetInstance().GetChunkSize(this.req.vhost))
 	if err != nil {
 		return err
 	}
 
-	return this.streamServiceCycle()
-}
-
-func (this *SrsRtmpConn) streamServiceCycle() error {
-	var err error
-	this.req.typ, this.req.stream, this.req.duration, err = this.rtmp.IdentifyClient(this.res.StreamId)
+	err = this.rtmp.ResponseConnectApp(this.req.objectEncoding)
 	if err != nil {
 		return err
 	}
 
-	this.req.schema, this.req.host, this.req.vhost, this.req.app, _, this.req.port, this.req.param, err = utils.SrsDiscoveryTcUrl(this.req.tcUrl, this.req.stream)
-
-	if strings.Contains(this.req.stream, "?") {
-		i := strings.Index(this.req.stream, "?")
-		param := this.req.stream[i+1:]
-		m, _ := url.ParseQuery(param)
-		vhost_params, ok := m["vhost"]
-		if ok {
-			this.req.vhost = vhost_params[0]
-		}
-		this.req.stream = this.req.stream[0:i]
-	}
-
-	if err != nil {
-		return errors.New("srs_discovery_tc_url failed")
-	}
-	//todo check edge vhost
-	//todo security check
-
-	if this.req.stream == "" {
-		return errors.New("RTMP: Empty stream name not allowed")
-	}
-
-	this.source, err = FetchOrCreate(this, this.req, this.server)
+	err = this.rtmp.OnBwDone()
 	if err != nil {
 		return err
 	}
 
-	this.clientType = this.req.typ
-
-	switch this.req.typ {
-	case rtmp.SrsRtmpConnPlay:
-		{
-			if err := this.rtmp.StartPlay(this.res.StreamId); err != nil {
-				return err
-			}
-
-			if err := this.httpHooksOnPlay(); err != nil {
-				return err
-			}
-
-			if err := this.playing(this.source); err != nil {
-				return err
-			}
-
-			if err := this.httpHooksOnStop(); err != nil {
-				return err
-			}
+	// the single recv thread of connection, routes the messages to streams.
+	this.idle = time.AfterFunc(rtmp.SRS_CONSTS_RTMP_TIMEOUT, this.Close)
+	this.recvThread = NewSrsRecvThread(this.rtmp, this, 1000)
+	this.recvThread.Start()
 
-			return nil
-		}
-	case rtmp.SrsRtmpConnFMLEPublish:
-		{
-			if err := this.rtmp.StartFmlePublish(0); err != nil {
-				return err
-			}
-			return this.publishing(this.source)
-		}
-	default:
-		{
-			return errors.New("invalid client type")
-		}
-		//todo SrsRtmpConnHaivisionPublish,SrsRtmpConnFlashPublish
+	pingDone := make(chan bool)
+	if config.GetPingEnabled(this.req.vhost) {
+		go this.pingCycle(pingDone)
 	}
-	return nil
-}
 
-func (this *SrsRtmpConn) httpHooksOnPlay() error {
-	vhost := config.GetInstance().GetVHost(this.req.vhost)
-	if vhost == nil {
-		return nil
-	}
+	this.recvThread.Join()
+	close(pingDone)
+	this.Stop()
+	return this.recvErr
+}
 
-	if vhost.HttpHooks != nil && vhost.HttpHooks.Enabled == "on" {
-		if err := OnPlay(vhost.HttpHooks.OnPlay, this.req); err != nil {
-			return err
+/**
+ * send ping request to client in interval to update the rtt,
+ * and close the dead connection which receives nothing in timeout.
+ */
+func (this *SrsRtmpConn) pingCycle(done chan bool) {
+	interval := time.Duration(config.GetPingInterval(this.req.vhost)) * time.Second
+	timeout := time.Duration(config.GetPingTimeout(this.req.vhost)) * time.Second
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
 		}
-	}
-	return nil
-}
 
-func (this *SrsRtmpConn) httpHooksOnStop() error {
-	vhost := config.GetInstance().GetVHost(this.req.vhost)
-	if vhost == nil {
-		return nil
-	}
+		GetStatisticInstance().OnClientRtt(this.id, this.rtmp.GetRtt())
+		if time.Since(this.rtmp.GetLastRecvTime()) >= timeout {
+			log.Info("rtmp conn recv nothing in ", timeout, ", close the dead connection")
+			this.Close()
+			return
+		}
 
-	if vhost.HttpHooks != nil && vhost.HttpHooks.Enabled == "on" {
-		if err := OnStop(vhost.HttpHooks.OnStop, this.req); err != nil {
-			return err
+		if err := this.rtmp.PingRequest(); err != nil {
+			return
 		}
 	}
-	return nil
-}
-
-func (this *SrsRtmpConn) playing(source *SrsSource) error {
-	consumer := source.CreateConsumer(this, true, true, true)
-	return this.doPlaying(source, consumer)
 }
 
 func (this *SrsRtmpConn) RemoveSelf() {
 	this.server.RemoveConn(this)
 }
 
+/**
+ * called by the recv thread before quit, the service cycle returns the err after join.
+ */
 func (this *SrsRtmpConn) OnRecvError(err error) {
-	//判断如果是publish，则删除源
-	this.Stop()
-	this.server.OnRecvError(err, this)
-}
-
-func (this *SrsRtmpConn) doPlaying(source *SrsSource, consumer Consumer) error {
-	//todo refer check
-	//todo srsprint
-	// realtime := false
-	if err := consumer.ConsumeCycle(); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (this *SrsRtmpConn) publishing(s *SrsSource) error {
-	//TODO
-	//refer.check
-	if err := this.httpHooksOnPublish(); err != nil {
-		return err
-	}
-	//judge edge host
-	if err := this.acquirePublish(s, false); err != nil {
-		return err
-	}
-
-	err := this.doPublishing(s)
-
-	this.source.UnPublish()
-	//todo release publish
-	if err := this.httpHooksOnUnpublish(); err != nil {
-		return err
-	}
-	return err
-}
-
-func (this *SrsRtmpConn) httpHooksOnPublish() error {
-	vhost := config.GetInstance().GetVHost(this.req.vhost)
-	if vhost == nil {
-		return nil
-	}
-
-	if vhost.HttpHooks != nil && vhost.HttpHooks.Enabled == "on" {
-		if err := OnPublish(vhost.HttpHooks.OnPublish, this.req); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func (this *SrsRtmpConn) httpHooksOnUnpublish() error {
-	vhost := config.GetInstance().GetVHost(this.req.vhost)
-	if vhost == nil {
-		return nil
-	}
-
-	if vhost.HttpHooks != nil && vhost.HttpHooks.Enabled == "on" {
-		if err := OnPublish(vhost.HttpHooks.OnUnpublish, this.req); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func (this *SrsRtmpConn) acquirePublish(source *SrsSource, isEdge bool) error {
-	//TODO edge process
-
-	err := this.source.onPublish()
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (this *SrsRtmpConn) doPublishing(source *SrsSource) error {
-	this.recvThread = NewSrsRecvThread(this.rtmp, this, 1000)
-	this.recvThread.Start()
-	//这里需要定时检查收到的信息，实现SrsRtmpConn::do_publishing的功能
-	this.startMonitor()
-	this.recvThread.Join()
-	this.stopMonitor()
-	return nil
-}
-
-func (this *SrsRtmpConn) startMonitor() error {
-	go func() {
-		publish_1stpkt_timeout := config.GetPublish1stpktTimeout(this.req.vhost)
-		publish_normal_timeout := config.GetPublishNormalPktTimeout(this.req.vhost)
-		var last_nb_msgs int64 = 0
-		var last_video_frames int64 = 0
-	DONE:
-		for {
-			var timeOut uint32 = 0
-			if this.nb_msgs == 0 {
-				timeOut = publish_1stpkt_timeout
-			} else {
-				timeOut = publish_normal_timeout
-			}
-
-			select {
-			case <-this.exitMonitor:
-				{
-					break DONE
-				}
-			case <-this.expire:
-				{
-					break DONE
-				}
-			case <-time.After(time.Millisecond * time.Duration(timeOut)):
-				{
-					if this.nb_msgs <= last_nb_msgs { //error no msg got
-						break DONE
-					}
-				}
-			}
-			//do some statistic process
-			stat := GetStatisticInstance()
-			stat.OnVideoFrames(this.req, uint64(this.video_frames-last_video_frames))
-			last_video_frames = this.video_frames
-			//todo first need use kbps to get info
-		}
-		log.Info("monitor thread exit")
-	}()
-	return nil
-}
-
-func (this *SrsRtmpConn) stopMonitor() error {
-	close(this.exitMonitor)
-	return nil
+	// the streams are closed when the recv thread quit.
+	this.recvErr = err
 }
 
 func (this *SrsRtmpConn) resample() {
@@ -13,6 +13,19 @@ const (
 	SrsRtmpJitterAlgorithmOFF                         = 0x03
 )
 
+/**
+ * parse the time_jitter of vhost, full, zero or off, default to full.
+ */
+func srs_time_jitter_string2int(time_jitter string) SrsRtmpJitterAlgorithm {
+	switch time_jitter {
+	case "zero":
+		return SrsRtmpJitterAlgorithmZERO
+	case "off":
+		return SrsRtmpJitterAlgorithmOFF
+	}
+	return SrsRtmpJitterAlgorithmFULL
+}
+
 const CONST_MAX_JITTER_MS_NEG = -250
 const CONST_MAX_JITTER_MS = 250
 const DEFAULT_FRAME_TIME_MS = 10
@@ -29,7 +42,7 @@ func NewSrsRtmpJitter() *SrsRtmpJitter {
 	}
 }
 
-func (this *SrsRtmpJitter) Correct(msg *rtmp.SrsRtmpMessage, ag SrsRtmpJitterAlgorithm) error {
+func (this *SrsRtmpJitter) Correct(msg *rtmp.SrsSharedPtrMessage, ag SrsRtmpJitterAlgorithm) error {
 	if ag != SrsRtmpJitterAlgorithmFULL {
 		if ag == SrsRtmpJitterAlgorithmOFF {
 			return nil
@@ -45,7 +58,8 @@ func (this *SrsRtmpJitter) Correct(msg *rtmp.SrsRtmpMessage, ag SrsRtmpJitterAlg
 		return nil
 	}
 
-	if msg.GetHeader().IsAV() {
+	// set to 0 for metadata.
+	if !msg.GetHeader().IsAV() {
 		msg.GetHeader().SetTimestamp(0)
 		return nil
 	}
@@ -67,8 +81,9 @@ func (this *SrsRtmpJitter) Correct(msg *rtmp.SrsRtmpMessage, ag SrsRtmpJitterAlg
 		delta = DEFAULT_FRAME_TIME_MS
 	}
 
-	if this.lastPktCorrectTime+delta > 0 {
-		this.lastPktCorrectTime = this.lastPktCorrectTime + delta
+	this.lastPktCorrectTime = this.lastPktCorrectTime + delta
+	if this.lastPktCorrectTime < 0 {
+		this.lastPktCorrectTime = 0
 	}
 	msg.GetHeader().SetTimestamp(this.lastPktCorrectTime)
 	this.lastPktTime = timestamp
@@ -0,0 +1,500 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package app
+
+import (
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"go_srs/srs/app/config"
+	"go_srs/srs/protocol/packet"
+	"go_srs/srs/protocol/rtmp"
+	"go_srs/srs/utils"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/**
+ * the NetStream of rtmp connection, created by createStream,
+ * each stream plays or publishes its own source.
+ * the stream is only accessed in the recv thread of connection.
+ */
+type SrsRtmpStream struct {
+	conn   *SrsRtmpConn
+	req    *SrsRequest
+	res    *SrsResponse
+	source *SrsSource
+	// the consumer when playing.
+	consumer *SrsConsumer
+	// closed when the play cycle quit.
+	playDone chan bool
+	// closed when the stream is closed by connection, the play cycle quit normally.
+	playClosing chan bool
+	// whether the stream is publishing.
+	publishing   bool
+	exitMonitor  chan bool
+	nb_msgs      int64
+	video_frames int64
+	audio_frames int64
+}
+
+func NewSrsRtmpStream(c *SrsRtmpConn, streamId int) *SrsRtmpStream {
+	return &SrsRtmpStream{
+		conn: c,
+		req:  c.req.Copy(),
+		res:  NewSrsResponse(streamId),
+	}
+}
+
+/**
+ * whether the stream is playing or publishing.
+ */
+func (this *SrsRtmpStream) Active() bool {
+	return this.consumer != nil || this.publishing
+}
+
+/**
+ * identify the stream name and vhost of request, fetch or create the source.
+ */
+func (this *SrsRtmpStream) identify(typ rtmp.SrsRtmpConnType, streamName string, duration float64) error {
+	var err error
+	this.req = this.conn.req.Copy()
+	this.req.typ = typ
+	this.req.stream = streamName
+	this.req.duration = duration
+
+	this.req.schema, this.req.host, this.req.vhost, this.req.app, _, this.req.port, this.req.param, err = utils.SrsDiscoveryTcUrl(this.req.tcUrl, this.req.stream)
+
+	if strings.Contains(this.req.stream, "?") {
+		i := strings.Index(this.req.stream, "?")
+		param := this.req.stream[i+1:]
+		m, _ := url.ParseQuery(param)
+		vhost_params, ok := m["vhost"]
+		if ok {
+			this.req.vhost = vhost_params[0]
+		}
+		this.req.tracks = NewSrsPlayTracks(m)
+		this.req.stream = this.req.stream[0:i]
+	}
+
+	if err != nil {
+		return errors.New("srs_discovery_tc_url failed")
+	}
+	//todo check edge vhost
+	//todo security check
+
+	if this.req.stream == "" {
+		return errors.New("RTMP: Empty stream name not allowed")
+	}
+
+	this.source, err = FetchOrCreate(this.conn, this.req, this.conn.server)
+	return err
+}
+
+/**
+ * process the command of stream, for example, play, publish and closeStream.
+ */
+func (this *SrsRtmpStream) OnCommand(msg *rtmp.SrsRtmpMessage, pkt packet.SrsPacket) error {
+	switch p := pkt.(type) {
+	case *packet.SrsPlayPacket:
+		{
+			this.Close()
+			return this.play(p)
+		}
+	case *packet.SrsPublishPacket:
+		{
+			this.Close()
+			return this.publish(p)
+		}
+	case *packet.SrsCloseStreamPacket:
+		{
+			// the stream is closed by client, the connection is kept for a new play or publish.
+			this.Close()
+			return nil
+		}
+	}
+
+	// the control messages of player, for example, pause and seek.
+	if this.consumer != nil {
+		return this.consumer.Handle(msg)
+	}
+	return nil
+}
+
+/**
+ * process the audio, video and data messages of publisher.
+ */
+func (this *SrsRtmpStream) Handle(msg *rtmp.SrsRtmpMessage) error {
+	if !this.publishing {
+		return nil
+	}
+	this.nb_msgs++
+	return this.processPublishMessage(msg)
+}
+
+/**
+ * close the play or publish of stream, the stream can play or publish again.
+ */
+func (this *SrsRtmpStream) Close() {
+	if this.consumer != nil {
+		this.stopPlay()
+	}
+
+	if this.publishing {
+		this.unpublish()
+	}
+}
+
+func (this *SrsRtmpStream) play(pkt *packet.SrsPlayPacket) error {
+	if err := this.identify(rtmp.SrsRtmpConnPlay, pkt.StreamName.Value.Value, pkt.Duration.Value); err != nil {
+		return err
+	}
+
+	if err := this.conn.rtmp.StartPlay(this.res.StreamId); err != nil {
+		return err
+	}
+
+	if err := this.httpHooksOnPlay(); err != nil {
+		return err
+	}
+
+	if err := this.acquirePlay(); err != nil {
+		return err
+	}
+
+	this.playDone = make(chan bool)
+	this.playClosing = make(chan bool)
+	this.consumer = this.source.CreateConsumer(this.conn, this.req, this.res.StreamId, true, true, true)
+	go this.playing(this.consumer, this.playDone, this.playClosing)
+	return nil
+}
+
+func (this *SrsRtmpStream) playing(consumer *SrsConsumer, done chan bool, closing chan bool) {
+	defer close(done)
+	//todo refer check
+	//todo srsprint
+	// realtime := false
+	err := consumer.ConsumeCycle()
+	select {
+	case <-closing:
+	default:
+		{
+			// the client is gone, close the connection to cleanup all streams.
+			log.Info("rtmp play cycle quit, err=", err)
+			this.conn.Close()
+		}
+	}
+}
+
+/**
+ * notify the edge to pull stream from origin, the stopped edge removed its source,
+ * so fetch a new source to play.
+ */
+func (this *SrsRtmpStream) acquirePlay() error {
+	err := this.source.onEdgeStartPlay()
+	if err != errEdgeStopped {
+		return err
+	}
+
+	if this.source, err = FetchOrCreate(this.conn, this.req, this.conn.server); err != nil {
+		return err
+	}
+	return this.source.onEdgeStartPlay()
+}
+
+func (this *SrsRtmpStream) stopPlay() {
+	close(this.playClosing)
+	this.source.RemoveConsumer(this.consumer)
+	<-this.playDone
+	this.consumer = nil
+	this.source.onEdgeStopPlay()
+
+	if err := this.httpHooksOnStop(); err != nil {
+		log.Info("rtmp on_stop hook failed, err=", err)
+	}
+}
+
+func (this *SrsRtmpStream) publish(pkt *packet.SrsPublishPacket) error {
+	// the FMLE and haivision encoder send FCPublish before publish.
+	streamName := pkt.StreamName.Value.Value
+	typ, ok := this.conn.fmleStreams[streamName]
+	if !ok {
+		typ = rtmp.SrsRtmpConnFlashPublish
+	}
+
+	if err := this.identify(typ, streamName, 0); err != nil {
+		return err
+	}
+
+	// the edge responses the publisher when origin accepts the stream.
+	if !this.source.isEdge() {
+		if err := this.conn.rtmp.ResponsePublish(this.res.StreamId, typ != rtmp.SrsRtmpConnFlashPublish); err != nil {
+			return err
+		}
+	}
+
+	//TODO
+	//refer.check
+	pushes, err := this.httpHooksOnPublish()
+	if err != nil {
+		return err
+	}
+
+	if err := this.acquirePublish(this.source, this.source.isEdge()); err != nil {
+		return err
+	}
+
+	// push the stream to the destinations of hook, the edge never pushes.
+	for _, destination := range pushes {
+		if _, err := this.source.AddPush(destination); err != nil {
+			log.Info("add push ", destination, " failed, err=", err)
+		}
+	}
+
+	this.publishing = true
+	this.nb_msgs = 0
+	this.exitMonitor = make(chan bool)
+	this.startMonitor()
+	return nil
+}
+
+func (this *SrsRtmpStream) acquirePublish(source *SrsSource, isEdge bool) error {
+	if isEdge {
+		status, err := source.onEdgeStartPublish(this)
+		if err != nil {
+			// relay the error of origin to publisher.
+			if status != nil {
+				this.OnEdgeStatus(status)
+			}
+			return err
+		}
+		return this.conn.rtmp.ResponsePublish(this.res.StreamId, this.req.typ != rtmp.SrsRtmpConnFlashPublish)
+	}
+
+	err := source.onPublish()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+/**
+ * relay the status of origin to edge publisher.
+ */
+func (this *SrsRtmpStream) OnEdgeStatus(pkt *packet.SrsOnStatusCallPacket) error {
+	return this.conn.rtmp.SendPacket(pkt, this.res.StreamId)
+}
+
+/**
+ * the origin of edge publisher fails, close the publisher.
+ */
+func (this *SrsRtmpStream) OnEdgeError(err error) {
+	log.Info("edge publish to origin failed, err=", err)
+	this.conn.Close()
+}
+
+func (this *SrsRtmpStream) unpublish() {
+	this.publishing = false
+	this.stopMonitor()
+
+	if this.source.isEdge() {
+		this.source.onEdgeProxyUnpublish()
+	} else {
+		this.source.RemoveConsumers()
+		RemoveSrsSource(this.source)
+		this.source.UnPublish()
+	}
+	//todo release publish
+	if err := this.httpHooksOnUnpublish(); err != nil {
+		log.Info("rtmp on_unpublish hook failed, err=", err)
+	}
+}
+
+/**
+ * the client FCUnpublish the stream, response and unpublish it.
+ */
+func (this *SrsRtmpStream) FmleUnpublish(transactionId float64) error {
+	this.Close()
+	return this.conn.rtmp.FmleUnpublish(this.res.StreamId, transactionId)
+}
+
+func (this *SrsRtmpStream) processPublishMessage(msg *rtmp.SrsRtmpMessage) error {
+	// the edge relays the messages to origin.
+	if this.source.isEdge() {
+		return this.source.onEdgeProxyPublish(msg)
+	}
+
+	if msg.GetHeader().IsAudio() {
+		this.audio_frames++
+		if err := this.source.OnAudio(msg); err != nil {
+
+		}
+	}
+
+	if msg.GetHeader().IsVideo() {
+		this.video_frames++
+		if err := this.source.OnVideo(msg); err != nil {
+
+		}
+	}
+
+	// process aggregate message
+	if msg.GetHeader().IsAggregate() {
+		msgs, err := msg.DemuxAggregate()
+		if err != nil {
+			return err
+		}
+
+		for _, m := range msgs {
+			if err := this.processPublishMessage(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// process onMetaData
+	if msg.GetHeader().IsAmf0Data() || msg.GetHeader().IsAmf3Data() {
+		pkt, err := this.conn.rtmp.DecodeMessage(msg)
+		if err != nil {
+			return err
+		}
+
+		switch pkt.(type) {
+		case *packet.SrsOnMetaDataPacket:
+			{
+				err := this.source.OnMetaData(msg, pkt.(*packet.SrsOnMetaDataPacket))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (this *SrsRtmpStream) startMonitor() error {
+	exitMonitor := this.exitMonitor
+	go func() {
+		publish_1stpkt_timeout := config.GetPublish1stpktTimeout(this.req.vhost)
+		publish_normal_timeout := config.GetPublishNormalPktTimeout(this.req.vhost)
+		var last_nb_msgs int64 = 0
+		var last_video_frames int64 = 0
+	DONE:
+		for {
+			var timeOut uint32 = 0
+			if this.nb_msgs == 0 {
+				timeOut = publish_1stpkt_timeout
+			} else {
+				timeOut = publish_normal_timeout
+			}
+
+			select {
+			case <-exitMonitor:
+				{
+					break DONE
+				}
+			case <-this.conn.expire:
+				{
+					break DONE
+				}
+			case <-time.After(time.Millisecond * time.Duration(timeOut)):
+				{
+					if this.nb_msgs <= last_nb_msgs { //error no msg got
+						break DONE
+					}
+				}
+			}
+			//do some statistic process
+			stat := GetStatisticInstance()
+			stat.OnVideoFrames(this.req, uint64(this.video_frames-last_video_frames))
+			last_video_frames = this.video_frames
+			//todo first need use kbps to get info
+		}
+		log.Info("monitor thread exit")
+	}()
+	return nil
+}
+
+func (this *SrsRtmpStream) stopMonitor() error {
+	close(this.exitMonitor)
+	return nil
+}
+
+func (this *SrsRtmpStream) httpHooksOnPlay() error {
+	vhost := config.GetInstance().GetVHost(this.req.vhost)
+	if vhost == nil {
+		return nil
+	}
+
+	if vhost.HttpHooks != nil && vhost.HttpHooks.Enabled == "on" {
+		if err := OnPlay(vhost.HttpHooks.OnPlay, this.req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *SrsRtmpStream) httpHooksOnStop() error {
+	vhost := config.GetInstance().GetVHost(this.req.vhost)
+	if vhost == nil {
+		return nil
+	}
+
+	if vhost.HttpHooks != nil && vhost.HttpHooks.Enabled == "on" {
+		if err := OnStop(vhost.HttpHooks.OnStop, this.req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ * notify the on_publish hook.
+ * @return the destinations to push the stream to, responsed by hook.
+ */
+func (this *SrsRtmpStream) httpHooksOnPublish() ([]string, error) {
+	vhost := config.GetInstance().GetVHost(this.req.vhost)
+	if vhost == nil {
+		return nil, nil
+	}
+
+	if vhost.HttpHooks != nil && vhost.HttpHooks.Enabled == "on" {
+		return OnPublish(vhost.HttpHooks.OnPublish, this.req)
+	}
+	return nil, nil
+}
+
+func (this *SrsRtmpStream) httpHooksOnUnpublish() error {
+	vhost := config.GetInstance().GetVHost(this.req.vhost)
+	if vhost == nil {
+		return nil
+	}
+
+	if vhost.HttpHooks != nil && vhost.HttpHooks.Enabled == "on" {
+		if err := OnUnPublish(vhost.HttpHooks.OnUnpublish, this.req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package app
+
+import (
+	"crypto/tls"
+	log "github.com/sirupsen/logrus"
+	"go_srs/srs/app/config"
+	"strings"
+)
+
+/**
+ * the tls config of rtmps listener, the certificate of vhost is selected by the SNI,
+ * and the certificate of server is used when no vhost matched.
+ */
+func NewSrsRtmpsConfig() (*tls.Config, error) {
+	conf := config.GetInstance()
+	cert, err := tls.LoadX509KeyPair(conf.GetRtmpsCert(), conf.GetRtmpsKey())
+	if err != nil {
+		return nil, err
+	}
+
+	vhosts := make(map[string]*tls.Certificate)
+	for name, vhost := range conf.VHosts {
+		if vhost.Enabled != "on" || vhost.Tls == nil {
+			continue
+		}
+
+		c, err := tls.LoadX509KeyPair(vhost.Tls.Cert, vhost.Tls.Key)
+		if err != nil {
+			return nil, err
+		}
+		vhosts[strings.ToLower(name)] = &c
+		log.Info("rtmps load certificate of vhost ", name)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			// nil to use the certificate of server.
+			return vhosts[strings.ToLower(hello.ServerName)], nil
+		},
+	}, nil
+}
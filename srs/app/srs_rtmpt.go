@@ -0,0 +1,431 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package app
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * the max bytes pending of each direction, the write of server blocks when exceed,
+ * and the send of client is rejected when exceed.
+ */
+const SRS_RTMPT_MAX_PENDING = 4 * 1024 * 1024
+
+/**
+ * the polling interval responsed to client, in the first byte of idle and send response,
+ * the interval grows when no data, to reduce the polling of idle client.
+ */
+const SRS_RTMPT_MIN_POLLING_INTERVAL = 0x01
+const SRS_RTMPT_MAX_POLLING_INTERVAL = 0x21
+
+/**
+ * close the session when client never polls in the timeout.
+ */
+const SRS_RTMPT_SESSION_TIMEOUT = 30 * time.Second
+
+/**
+ * the max duration to hold the request of client which arrives before
+ * the previous seq, the session is closed when the previous never arrives.
+ */
+const SRS_RTMPT_SEQ_TIMEOUT = 5 * time.Second
+
+const SRS_RTMPT_CONTENT_TYPE = "application/x-fcs"
+
+var errRtmptClosed = errors.New("rtmpt session closed")
+var errRtmptSeq = errors.New("rtmpt invalid seq")
+var errRtmptFull = errors.New("rtmpt too much data pending")
+
+type srsRtmptAddr string
+
+func (this srsRtmptAddr) Network() string {
+	return "rtmpt"
+}
+
+func (this srsRtmptAddr) String() string {
+	return string(this)
+}
+
+/**
+ * the session of rtmpt, adapts the http requests of client to a byte-stream conn,
+ * the data of send request is read by server, and the data written by server
+ * is responsed in the next idle or send request.
+ */
+type SrsRtmptConn struct {
+	id     string
+	local  net.Addr
+	remote net.Addr
+
+	lock sync.Mutex
+	// signaled when data arrives, data drained, deadline fires or closed.
+	cond *sync.Cond
+	// the data from client to server.
+	recvBuf bytes.Buffer
+	// the data from server to client.
+	sendBuf bytes.Buffer
+	closed  bool
+	// the polls without data, to calc the polling interval.
+	idle int
+	// the seq of next request of client, -1 before the first poll.
+	nextSeq       int64
+	readDeadline  time.Time
+	writeDeadline time.Time
+	readTimer     *time.Timer
+	writeTimer    *time.Timer
+	// close the session when client never polls.
+	expire  *time.Timer
+	onClose func(c *SrsRtmptConn)
+}
+
+func NewSrsRtmptConn(id string, r *http.Request, onClose func(c *SrsRtmptConn)) *SrsRtmptConn {
+	c := &SrsRtmptConn{
+		id:      id,
+		local:   srsRtmptAddr(r.Host),
+		remote:  srsRtmptAddr(r.RemoteAddr),
+		nextSeq: -1,
+		onClose: onClose,
+	}
+	c.cond = sync.NewCond(&c.lock)
+	c.expire = time.AfterFunc(SRS_RTMPT_SESSION_TIMEOUT, func() {
+		log.Info("rtmpt session ", id, " expired")
+		c.Close()
+	})
+	return c
+}
+
+func (this *SrsRtmptConn) Read(b []byte) (int, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for this.recvBuf.Len() == 0 {
+		if this.closed {
+			return 0, io.EOF
+		}
+		if srs_deadline_exceeded(this.readDeadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+		this.cond.Wait()
+	}
+	return this.recvBuf.Read(b)
+}
+
+func (this *SrsRtmptConn) Write(b []byte) (int, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for {
+		if this.closed {
+			return 0, io.ErrClosedPipe
+		}
+		if srs_deadline_exceeded(this.writeDeadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+		if this.sendBuf.Len() < SRS_RTMPT_MAX_PENDING {
+			break
+		}
+		this.cond.Wait()
+	}
+	return this.sendBuf.Write(b)
+}
+
+func (this *SrsRtmptConn) Close() error {
+	this.lock.Lock()
+	if this.closed {
+		this.lock.Unlock()
+		return nil
+	}
+	this.closed = true
+	this.expire.Stop()
+	this.cond.Broadcast()
+	this.lock.Unlock()
+
+	this.onClose(this)
+	return nil
+}
+
+func (this *SrsRtmptConn) LocalAddr() net.Addr {
+	return this.local
+}
+
+func (this *SrsRtmptConn) RemoteAddr() net.Addr {
+	return this.remote
+}
+
+func (this *SrsRtmptConn) SetDeadline(t time.Time) error {
+	this.SetReadDeadline(t)
+	return this.SetWriteDeadline(t)
+}
+
+func (this *SrsRtmptConn) SetReadDeadline(t time.Time) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.readDeadline = t
+	this.readTimer = this.resetDeadlineTimer(this.readTimer, t)
+	return nil
+}
+
+func (this *SrsRtmptConn) SetWriteDeadline(t time.Time) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.writeDeadline = t
+	this.writeTimer = this.resetDeadlineTimer(this.writeTimer, t)
+	return nil
+}
+
+/**
+ * wakeup the blocking read or write when the deadline fires.
+ */
+func (this *SrsRtmptConn) resetDeadlineTimer(timer *time.Timer, t time.Time) *time.Timer {
+	if timer != nil {
+		timer.Stop()
+	}
+	this.cond.Broadcast()
+	if t.IsZero() {
+		return nil
+	}
+	return time.AfterFunc(time.Until(t), func() {
+		this.lock.Lock()
+		defer this.lock.Unlock()
+		this.cond.Broadcast()
+	})
+}
+
+/**
+ * the poll of client, feed the data of client and fetch the data of server.
+ * the requests are served in the order of seq, the request arrives early
+ * is held until the previous requests served, the stale request is rejected.
+ * @return the polling interval and the data to client.
+ */
+func (this *SrsRtmptConn) poll(seq int64, data []byte) (byte, []byte, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.closed {
+		return 0, nil, errRtmptClosed
+	}
+	this.expire.Reset(SRS_RTMPT_SESSION_TIMEOUT)
+
+	if this.nextSeq >= 0 && seq > this.nextSeq {
+		deadline := time.Now().Add(SRS_RTMPT_SEQ_TIMEOUT)
+		timer := this.resetDeadlineTimer(nil, deadline)
+		for !this.closed && seq > this.nextSeq && !srs_deadline_exceeded(deadline) {
+			this.cond.Wait()
+		}
+		timer.Stop()
+
+		if this.closed {
+			return 0, nil, errRtmptClosed
+		}
+		// the data of previous request is lost, the stream is broken.
+		if seq > this.nextSeq {
+			log.Info("rtmpt session ", this.id, " lost seq ", this.nextSeq, ", close it")
+			go this.Close()
+			return 0, nil, errRtmptSeq
+		}
+	}
+	if this.nextSeq >= 0 && seq < this.nextSeq {
+		return 0, nil, errRtmptSeq
+	}
+	// reject the seq, the client should resend it when server consumes the data.
+	if this.recvBuf.Len()+len(data) > SRS_RTMPT_MAX_PENDING {
+		return 0, nil, errRtmptFull
+	}
+	this.nextSeq = seq + 1
+
+	this.recvBuf.Write(data)
+	out := make([]byte, this.sendBuf.Len())
+	copy(out, this.sendBuf.Bytes())
+	this.sendBuf.Reset()
+	this.cond.Broadcast()
+
+	if len(data) > 0 || len(out) > 0 {
+		this.idle = 0
+	} else if this.idle < SRS_RTMPT_MAX_POLLING_INTERVAL {
+		this.idle++
+	}
+
+	interval := SRS_RTMPT_MIN_POLLING_INTERVAL + this.idle
+	if interval > SRS_RTMPT_MAX_POLLING_INTERVAL {
+		interval = SRS_RTMPT_MAX_POLLING_INTERVAL
+	}
+	return byte(interval), out, nil
+}
+
+func srs_deadline_exceeded(deadline time.Time) bool {
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
+
+/**
+ * the rtmpt(rtmp tunnelled over http) server, the client posts:
+ *		/fcs/ident2				ident, 404 for no ident.
+ *		/open/1					open session, response the session id.
+ *		/idle/<session>/<seq>	poll the data of server.
+ *		/send/<session>/<seq>	send data to server and poll the data of server.
+ *		/close/<session>/<seq>	close session.
+ * the session serves as a conn of rtmp by the handler.
+ */
+type SrsRtmptServer struct {
+	handler  func(c net.Conn)
+	lock     sync.Mutex
+	sessions map[string]*SrsRtmptConn
+}
+
+func NewSrsRtmptServer(handler func(c net.Conn)) *SrsRtmptServer {
+	return &SrsRtmptServer{
+		handler:  handler,
+		sessions: make(map[string]*SrsRtmptConn),
+	}
+}
+
+/**
+ * mount the rtmpt paths to the mux.
+ */
+func (this *SrsRtmptServer) Mount(mux *http.ServeMux) {
+	for _, path := range []string{"/fcs/", "/open/", "/idle/", "/send/", "/close/"} {
+		mux.Handle(path, this)
+	}
+}
+
+func (this *SrsRtmptServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "rtmpt requires post", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch p[0] {
+	case "open":
+		{
+			this.open(w, r)
+		}
+	case "idle", "send":
+		{
+			this.poll(w, r, p)
+		}
+	case "close":
+		{
+			if c := this.fetch(p); c != nil {
+				c.Close()
+			}
+			srs_rtmpt_response(w, []byte{0x00})
+		}
+	default:
+		{
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func (this *SrsRtmptServer) open(w http.ResponseWriter, r *http.Request) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	id := hex.EncodeToString(b)
+
+	c := NewSrsRtmptConn(id, r, this.remove)
+	this.lock.Lock()
+	this.sessions[id] = c
+	this.lock.Unlock()
+	log.Info("rtmpt open session ", id, ", client=", r.RemoteAddr)
+
+	go this.handler(c)
+	srs_rtmpt_response(w, []byte(id+"\n"))
+}
+
+func (this *SrsRtmptServer) poll(w http.ResponseWriter, r *http.Request, p []string) {
+	c := this.fetch(p)
+	if c == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(p) < 3 {
+		http.Error(w, errRtmptSeq.Error(), http.StatusBadRequest)
+		return
+	}
+	seq, err := strconv.ParseInt(p[2], 10, 64)
+	if err != nil || seq < 0 {
+		http.Error(w, errRtmptSeq.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, SRS_RTMPT_MAX_PENDING))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	interval, out, err := c.poll(seq, data)
+	if err == errRtmptClosed {
+		http.NotFound(w, r)
+		return
+	} else if err == errRtmptFull {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	srs_rtmpt_response(w, append([]byte{interval}, out...))
+}
+
+/**
+ * fetch the session by the path /<command>/<session>/<seq>
+ */
+func (this *SrsRtmptServer) fetch(p []string) *SrsRtmptConn {
+	if len(p) < 2 {
+		return nil
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.sessions[p[1]]
+}
+
+func (this *SrsRtmptServer) remove(c *SrsRtmptConn) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.sessions, c.id)
+}
+
+func srs_rtmpt_response(w http.ResponseWriter, b []byte) {
+	w.Header().Set("Content-Type", SRS_RTMPT_CONTENT_TYPE)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(b)
+}
@@ -23,6 +23,7 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package app
 
 import (
+	"crypto/tls"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"go_srs/srs/app/config"
@@ -43,10 +44,11 @@ type SrsServer struct {
 }
 
 func NewSrsServer() *SrsServer {
-	return &SrsServer{
-		conns:     make([]*SrsRtmpConn, 0),
-		flvServer: NewSrsHttpStreamServer(),
+	server := &SrsServer{
+		conns: make([]*SrsRtmpConn, 0),
 	}
+	server.flvServer = NewSrsHttpStreamServer(server)
+	return server
 }
 
 func (this *SrsServer) OnRecvError(err error, c *SrsRtmpConn) {
@@ -95,8 +97,23 @@ func (this *SrsServer) StartProcess(port uint32) error {
 		return err
 	}
 
+	if config.GetInstance().GetRtmpsEnabled() {
+		if err := this.listenRtmps(); err != nil {
+			return err
+		}
+	}
+
+	if config.GetInstance().GetHttpApiEnabled() {
+		if err := this.listenHttpApi(); err != nil {
+			return err
+		}
+	}
+
 	go func() {
 		http.Handle("/", this.flvServer)
+		if config.GetInstance().GetRtmptEnabled() {
+			NewSrsRtmptServer(this.HandleConnection).Mount(http.DefaultServeMux)
+		}
 		http.Handle("/hls/", http.StripPrefix("/hls/", http.FileServer(http.Dir("./html"))))
 		http.ListenAndServe(":8080", nil)
 	}()
@@ -126,6 +143,56 @@ func (this *SrsServer) StartProcess(port uint32) error {
 	return nil
 }
 
+/**
+ * listen the rtmps, the connection over tls serves as rtmp connection.
+ */
+func (this *SrsServer) listenRtmps() error {
+	tlsConfig, err := NewSrsRtmpsConfig()
+	if err != nil {
+		return err
+	}
+
+	port := config.GetInstance().GetRtmpsListen()
+	ln, err := tls.Listen("tcp", ":"+strconv.Itoa(int(port)), tlsConfig)
+	if err != nil {
+		return err
+	}
+	log.Info("rtmps listen at ", port)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				log.Error("rtmps accept failed, err=", err)
+				return
+			}
+			go this.HandleConnection(conn)
+		}
+	}()
+	return nil
+}
+
+/**
+ * listen the http api, which changes the server, so never serves on the http stream server.
+ */
+func (this *SrsServer) listenHttpApi() error {
+	port := config.GetInstance().GetHttpApiListen()
+	ln, err := net.Listen("tcp", ":"+strconv.Itoa(int(port)))
+	if err != nil {
+		return err
+	}
+	log.Info("http api listen at ", port)
+
+	mux := http.NewServeMux()
+	mux.Handle(SRS_HTTP_API_PREFIX, NewSrsHttpApi())
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			log.Error("http api serve failed, err=", err)
+		}
+	}()
+	return nil
+}
+
 func (this *SrsServer) HandleConnection(conn net.Conn) {
 	rtmpConn := NewSrsRtmpConn(conn, this)
 	err := this.AddConn(rtmpConn)
@@ -133,7 +200,9 @@ func (this *SrsServer) HandleConnection(conn net.Conn) {
 		conn.Close()
 		return
 	}
-	err = rtmpConn.ServiceLoop()
+	if err = rtmpConn.ServiceLoop(); err != nil {
+		log.Info("rtmp conn service cycle quit, err=", err)
+	}
 	this.RemoveConn(rtmpConn)
 }
 
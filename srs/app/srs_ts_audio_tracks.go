@@ -0,0 +1,102 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package app
+
+import (
+	"go_srs/srs/protocol/amf0"
+	"go_srs/srs/utils"
+	"strconv"
+)
+
+/**
+ * the info of track in metadata of enhanced rtmp multitrack stream.
+ */
+type SrsTrackInfo struct {
+	Language string `amf0:"language"`
+}
+
+/**
+ * the audio tracks of enhanced rtmp multitrack stream to mux to ts,
+ * each track has its own codec, and the language from metadata, for example,
+ *      audioTrackIdInfoMap: { "1": { language: "fre" } }
+ */
+type SrsTsAudioTracks struct {
+	codecs    map[uint8]*SrsAvcAacCodec
+	languages map[uint8]string
+}
+
+/**
+ * create the audio tracks, the default track 0 uses the codec c.
+ */
+func NewSrsTsAudioTracks(c *SrsAvcAacCodec) *SrsTsAudioTracks {
+	return &SrsTsAudioTracks{
+		codecs:    map[uint8]*SrsAvcAacCodec{0: c},
+		languages: make(map[uint8]string),
+	}
+}
+
+func (this *SrsTsAudioTracks) codec(track uint8) *SrsAvcAacCodec {
+	c, ok := this.codecs[track]
+	if !ok {
+		c = NewSrsAvcAacCodec()
+		this.codecs[track] = c
+	}
+	return c
+}
+
+func (this *SrsTsAudioTracks) language(track uint8) string {
+	return this.languages[track]
+}
+
+/**
+ * parse the languages of tracks from the metadata, which maybe starts with @setDataFrame and onMetaData.
+ */
+func (this *SrsTsAudioTracks) onMetaData(payload []byte) error {
+	stream := utils.NewSrsStream(payload)
+	for !stream.Empty() {
+		any, err := amf0.ReadAny(stream)
+		if err != nil {
+			return err
+		}
+
+		switch any.(type) {
+		case *amf0.SrsAmf0Object, *amf0.SrsAmf0EcmaArray:
+			{
+				var info struct {
+					AudioTrackIdInfoMap map[string]SrsTrackInfo `amf0:"audioTrackIdInfoMap"`
+				}
+				if err := amf0.UnmarshalAny(any, &info); err != nil {
+					return err
+				}
+
+				for id, track := range info.AudioTrackIdInfoMap {
+					if v, err := strconv.ParseUint(id, 10, 8); err == nil {
+						this.languages[uint8(v)] = track.Language
+					}
+				}
+				return nil
+			}
+		}
+	}
+	return nil
+}
@@ -36,7 +36,7 @@ func NewSrsTsCache() *SrsTsCache {
 	return &SrsTsCache{}
 }
 
-func (this *SrsTsCache) cache_audio(c *SrsAvcAacCodec, dts int64, sampler *SrsCodecSampler) error {
+func (this *SrsTsCache) cache_audio(c *SrsAvcAacCodec, dts int64, sampler *SrsCodecSampler, track uint8) error {
 	if this.audio == nil {
 		this.audio = NewSrsTsMessage()
 		this.audio.writePcr = false
@@ -45,7 +45,7 @@ func (this *SrsTsCache) cache_audio(c *SrsAvcAacCodec, dts int64, sampler *SrsCo
 		this.audio.startPts = dts
 	}
 
-	this.audio.sid = SrsTsPESStreamIdAudioCommon //used in ts stream_id field
+	this.audio.sid = SrsTsPESStreamIdAudioCommon + SrsTsPESStreamId(track) //used in ts stream_id field
 	acodec := codec.SrsCodecAudio(c.audioCodecId)
 	if acodec == codec.SrsCodecAudioAAC {
 		if err := this.do_cache_aac(c, sampler); err != nil {
@@ -36,6 +36,8 @@ type SrsTsEncoder struct {
 	tsCache *SrsTsCache
 	context *SrsTsContext
 	muxer   *SrsTsMuxer
+	// the audio tracks of multitrack stream, the codec is for the default track.
+	audioTracks *SrsTsAudioTracks
 
 	writer io.Writer
 }
@@ -53,6 +55,8 @@ func NewSrsTsEncoder(w io.Writer) *SrsTsEncoder {
 		context: context,
 		writer:  w,
 		muxer:   m,
+
+		audioTracks: NewSrsTsAudioTracks(c),
 	}
 }
 
@@ -60,9 +64,21 @@ func (this *SrsTsEncoder) WriteHeader() error {
 	return nil
 }
 
-func (this *SrsTsEncoder) WriteAudio(timestamp uint32, data []byte) (uint32, error) {
+/**
+ * parse the languages of audio tracks from metadata.
+ */
+func (this *SrsTsEncoder) WriteMetaData(data []byte) error {
+	return this.audioTracks.onMetaData(data)
+}
+
+/**
+ * write the audio of track, the extra tracks must be aac, each track is an elementary stream.
+ */
+func (this *SrsTsEncoder) WriteAudio(timestamp uint32, data []byte, track uint8) (uint32, error) {
+	c := this.audioTracks.codec(track)
+
 	this.sampler.Clear()
-	if err := this.codec.audioAACDemux(data, this.sampler); err != nil {
+	if err := c.audioAACDemux(data, this.sampler); err != nil {
 		//if err := this.codec.audio_mp3_demux(data, this.sample); err != nil {
 		//	return 0, err
 		//}
@@ -70,19 +86,22 @@ func (this *SrsTsEncoder) WriteAudio(timestamp uint32, data []byte) (uint32, err
 		return 0, err
 	}
 
-	acodec := codec.SrsCodecAudio(this.codec.audioCodecId)
-	if acodec != codec.SrsCodecAudioAAC && acodec != codec.SrsCodecAudioMP3 {
+	acodec := codec.SrsCodecAudio(c.audioCodecId)
+	if acodec != codec.SrsCodecAudioAAC && (track > 0 || acodec != codec.SrsCodecAudioMP3) {
 		fmt.Println("audio format error, need aac or mp3")
 		return 0, errors.New("audio format error, need aac or mp3")
 	}
 
-	this.muxer.UpdateACodec(acodec)
+	if track == 0 {
+		this.muxer.UpdateACodec(acodec)
+	}
 	if acodec == codec.SrsCodecAudioAAC && this.sampler.AacPacketType == codec.SrsCodecAudioTypeSequenceHeader {
-		return 0, nil //ignore aac sequence header
+		//ignore aac sequence header
+		return 0, this.muxer.UpdateAudioTrack(track, this.audioTracks.language(track))
 	}
 
 	dts := int64(timestamp * 90)
-	if err := this.tsCache.cache_audio(this.codec, dts, this.sampler); err != nil {
+	if err := this.tsCache.cache_audio(c, dts, this.sampler, track); err != nil {
 		return 0, err
 	}
 
@@ -78,6 +78,12 @@ const TS_VIDEO_AVC_PID = 0x100
 const TS_AUDIO_AAC_PID = 0x101
 const TS_AUDIO_MP3_PID = 0x102
 
+// the pid of audio track of multitrack stream, TS_AUDIO_TRACK_PID + track id, except the default track 0.
+const TS_AUDIO_TRACK_PID = 0x110
+
+// the max audio tracks in ts, the stream id of audio track PES is 0xc0 + track id.
+const TS_AUDIO_MAX_TRACKS = 8
+
 type SrsTsPayload interface {
 	Encode(stream *utils.SrsStream)
 	Decode(stream *utils.SrsStream) error
@@ -112,6 +118,13 @@ func (this *SrsTsMessage) IsVideo() bool {
 	return ((this.sid >> 4) & 0x0f) == SrsTsPESStreamIdVideoChecker
 }
 
+/**
+ * the track of audio, 0 for the default audio track.
+ */
+func (this *SrsTsMessage) AudioTrack() uint8 {
+	return uint8(this.sid - SrsTsPESStreamIdAudioCommon)
+}
+
 func (this *SrsTsMessage) Fresh() bool {
 	return len(this.payload) == 0
 }
@@ -38,6 +38,10 @@ type SrsTsMuxer struct {
 	videoPid    int16
 	wrotePatPmt bool
 	pids        map[int]*SrsTsChannel
+	// the languages of audio tracks, the extra tracks are written as elementary streams of AAC.
+	audioTracks map[uint8]string
+	// the 5bits version of pmt, increased when the pmt rewritten for changes.
+	pmtVersion int8
 
 	context *SrsTsContext
 	writer  io.Writer
@@ -50,6 +54,7 @@ func NewSrsTsMuxer(w io.Writer, c *SrsTsContext, ac codec.SrsCodecAudio, vc code
 		wrotePatPmt: false,
 		ready:       false,
 		pids:        make(map[int]*SrsTsChannel),
+		audioTracks: make(map[uint8]string),
 	}
 
 	muxer.convertACodecToTsStream(ac)
@@ -114,11 +119,38 @@ func (this *SrsTsMuxer) UpdateACodec(ac codec.SrsCodecAudio) error {
 	return nil
 }
 
+/**
+ * update the language of audio track, the pat and pmt is rewritten when track or language changed.
+ */
+func (this *SrsTsMuxer) UpdateAudioTrack(track uint8, language string) error {
+	if track >= TS_AUDIO_MAX_TRACKS {
+		return errors.New("ts: audio track overflow")
+	}
+
+	if l, ok := this.audioTracks[track]; ok && l == language {
+		return nil
+	}
+
+	this.audioTracks[track] = language
+	this.wrotePatPmt = false //rewrite pat pmt
+	return nil
+}
+
 func (this *SrsTsMuxer) Encode(msg *SrsTsMessage) error {
 	if this.as == SrsTsStreamReserved || this.vs == SrsTsStreamReserved {
 		return errors.New("not support as or vs")
 	}
 
+	var track uint8
+	if msg.IsAudio() {
+		track = msg.AudioTrack()
+		if _, ok := this.audioTracks[track]; !ok && track > 0 {
+			if err := this.UpdateAudioTrack(track, ""); err != nil {
+				return err
+			}
+		}
+	}
+
 	if !this.wrotePatPmt {
 		err := this.encodePatPmt()
 		if err != nil {
@@ -133,7 +165,11 @@ func (this *SrsTsMuxer) Encode(msg *SrsTsMessage) error {
 
 	if msg.IsAudio() {
 		//todo pure audio must write pcr
-		this.encodePes(msg, this.audioPid, this.as, -1)
+		if track > 0 {
+			this.encodePes(msg, TS_AUDIO_TRACK_PID+int16(track), SrsTsStreamAudioAAC, -1)
+		} else {
+			this.encodePes(msg, this.audioPid, this.as, -1)
+		}
 	} else {
 		this.encodePes(msg, this.videoPid, this.vs, msg.dts)
 	}
@@ -147,6 +183,10 @@ func (this *SrsTsMuxer) encodePatPmt() error {
 
 	var pmtNumber int16 = TS_PMT_NUMBER
 	var pmtPid int16 = TS_PMT_PID
+	// the pmt is written before, the rewrite is for changes.
+	if this.ready {
+		this.pmtVersion = (this.pmtVersion + 1) % 32
+	}
 	if true {
 		pkt := CreatePAT(this.context, pmtNumber, pmtPid)
 		stream := utils.NewSrsStream([]byte{})
@@ -155,7 +195,7 @@ func (this *SrsTsMuxer) encodePatPmt() error {
 	}
 
 	if true {
-		pkt := CreatePMT(this.context, pmtNumber, pmtPid, this.videoPid, this.vs, this.audioPid, this.as)
+		pkt := CreatePMT(this.context, pmtNumber, pmtPid, this.pmtVersion, this.videoPid, this.vs, this.audioStreams())
 		stream := utils.NewSrsStream([]byte{})
 		pkt.Encode(stream)
 		this.writer.Write(stream.Data())
@@ -165,6 +205,27 @@ func (this *SrsTsMuxer) encodePatPmt() error {
 	return nil
 }
 
+/**
+ * the elementary streams of audio, the default track then the extra tracks by id.
+ */
+func (this *SrsTsMuxer) audioStreams() []*SrsTsPayloadPMTESInfo {
+	audios := make([]*SrsTsPayloadPMTESInfo, 0)
+	if this.as == SrsTsStreamAudioAAC || this.as == SrsTsStreamAudioMp3 {
+		info := NewSrsTsPayloadPMTESInfo(this.as, this.audioPid)
+		info.setLanguage(this.audioTracks[0])
+		audios = append(audios, info)
+	}
+
+	for track := uint8(1); track < TS_AUDIO_MAX_TRACKS; track++ {
+		if language, ok := this.audioTracks[track]; ok {
+			info := NewSrsTsPayloadPMTESInfo(SrsTsStreamAudioAAC, TS_AUDIO_TRACK_PID+int16(track))
+			info.setLanguage(language)
+			audios = append(audios, info)
+		}
+	}
+	return audios
+}
+
 func (this *SrsTsMuxer) encodePes(msg *SrsTsMessage, pid int16, sid SrsTsStream, pcr int64) error {
 	// Sometimes, the context is not ready(PAT/PMT write failed), error in this situation.
 	if !this.ready {
@@ -60,6 +60,21 @@ func NewSrsTsPayloadPMTESInfo(s SrsTsStream, pid int16) *SrsTsPayloadPMTESInfo {
 	}
 }
 
+/**
+ * set the ISO_639_language_descriptor of elementary stream, the language is
+ * the ISO 639-2 code, for example, eng, ignored if not 3 characters.
+ * @see 2.6.18 ISO 639 language descriptor, hls-mpeg-ts-iso13818-1.pdf
+ */
+func (this *SrsTsPayloadPMTESInfo) setLanguage(language string) {
+	if len(language) != 3 {
+		return
+	}
+
+	// descriptor_tag 0x0a, descriptor_length, ISO_639_language_code 24bits, audio_type 8bits.
+	this.ESInfo = []byte{0x0a, 4, language[0], language[1], language[2], 0}
+	this.ESInfoLength = int16(len(this.ESInfo))
+}
+
 func (this *SrsTsPayloadPMTESInfo) Encode(stream *utils.SrsStream) {
 	stream.WriteByte(byte(this.streamType))
 	var epid int16 = 0
@@ -214,7 +229,11 @@ func (this *SrsTsPayloadPMT) Decode(stream *utils.SrsStream) error {
 	return nil
 }
 
-func CreatePMT(context *SrsTsContext, pmtNumber int16, pmtPid int16, vpid int16, vs SrsTsStream, apid int16, as SrsTsStream) *SrsTsPacket {
+/**
+ * create the pmt, the audios are the elementary streams of audio tracks, the first one carries pcr if no video.
+ * @param version the version of pmt, increased when the pmt changed.
+ */
+func CreatePMT(context *SrsTsContext, pmtNumber int16, pmtPid int16, version int8, vpid int16, vs SrsTsStream, audios []*SrsTsPayloadPMTESInfo) *SrsTsPacket {
 	pkt := NewSrsTsPacket()
 
 	pkt.tsHeader.syncByte = SRS_TS_SYNC_BYTE
@@ -236,14 +255,14 @@ func CreatePMT(context *SrsTsContext, pmtNumber int16, pmtPid int16, vpid int16,
 
 	pmt.programNumber = pmtNumber
 	pmt.const1Value0 = 0x3 //2bits
-	pmt.versionNumber = 0
+	pmt.versionNumber = version & 0x1f
 	pmt.currentNextIndicator = 1
 	pmt.sectionNumber = 0
 	pmt.lastSectionNumber = 0
 	pmt.programInfoLength = 0
-	if as == SrsTsStreamAudioAAC || as == SrsTsStreamAudioMp3 {
-		pmt.PCR_PID = apid
-		pmt.infoes = append(pmt.infoes, NewSrsTsPayloadPMTESInfo(as, apid))
+	if len(audios) > 0 {
+		pmt.PCR_PID = audios[0].elementaryPID
+		pmt.infoes = append(pmt.infoes, audios...)
 	}
 
 	// if h.264 specified, use video to carry pcr.
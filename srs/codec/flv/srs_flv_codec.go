@@ -30,16 +30,88 @@ import (
 	"io"
 )
 
+/**
+ * whether the video is enhanced rtmp, the ExVideoTagHeader with FourCC of codec.
+ */
+func VideoIsExHeader(data []byte) bool {
+	if len(data) < 1 {
+		return false
+	}
+	return (data[0] & 0x80) != 0
+}
+
+/**
+ * the FrameType of video, 3bits for enhanced rtmp, 4bits for legacy.
+ */
+func VideoFrameType(data []byte) uint8 {
+	if len(data) < 1 {
+		return 0
+	}
+
+	if VideoIsExHeader(data) {
+		return (data[0] >> 4) & 0x07
+	}
+	return (data[0] >> 4) & 0x0F
+}
+
+/**
+ * the PacketType of enhanced rtmp video.
+ */
+func VideoExPacketType(data []byte) uint8 {
+	if len(data) < 1 {
+		return 0
+	}
+	return data[0] & 0x0F
+}
+
+/**
+ * the codec of video, the FourCC for enhanced rtmp, the CodecID for legacy.
+ */
+func VideoCodecId(data []byte) codec.SrsCodecVideo {
+	if len(data) < 1 {
+		return codec.SrsCodecVideoReserved
+	}
+
+	if !VideoIsExHeader(data) {
+		return codec.SrsCodecVideo(data[0] & 0x0F)
+	}
+
+	if len(data) < 5 {
+		return codec.SrsCodecVideoReserved
+	}
+
+	switch binary.BigEndian.Uint32(data[1:5]) {
+	case codec.SrsCodecVideoFourCCHEVC:
+		return codec.SrsCodecVideoHEVC
+	case codec.SrsCodecVideoFourCCAV1:
+		return codec.SrsCodecVideoAV1
+	case codec.SrsCodecVideoFourCCVP9:
+		return codec.SrsCodecVideoVP9
+	}
+	return codec.SrsCodecVideoReserved
+}
+
 func VideoIsKeyFrame(data []byte) bool {
 	if len(data) < 1 {
 		return false
 	}
 
-	frameType := (data[0] >> 4) & 0x0F
-	return frameType == codec.SrsCodecVideoAVCFrameKeyFrame
+	// the metadata and sequence end of enhanced rtmp are not frames.
+	if VideoIsExHeader(data) {
+		packetType := VideoExPacketType(data)
+		if packetType == codec.SrsCodecVideoExPacketTypeMetadata || packetType == codec.SrsCodecVideoExPacketTypeSequenceEnd {
+			return false
+		}
+	}
+
+	return VideoFrameType(data) == codec.SrsCodecVideoAVCFrameKeyFrame
 }
 
 func VideoIsSequenceHeader(data []byte) bool {
+	if VideoIsEnhanced(data) {
+		return VideoFrameType(data) == codec.SrsCodecVideoAVCFrameKeyFrame && VideoExPacketType(data) == codec.SrsCodecVideoExPacketTypeSequenceStart
+	}
+
 	if !VideoIsH264(data) {
 		return false
 	}
@@ -67,7 +139,7 @@ func AudioIsSequenceHeader(data []byte) bool {
 }
 
 func VideoIsH264(data []byte) bool {
-	if len(data) < 1 {
+	if len(data) < 1 || VideoIsExHeader(data) {
 		return false
 	}
 
@@ -75,6 +147,18 @@ func VideoIsH264(data []byte) bool {
 	return codecId == codec.SrsCodecVideoAVC
 }
 
+/**
+ * whether the video is enhanced rtmp of the supported codec, HEVC, AV1 or VP9.
+ */
+func VideoIsEnhanced(data []byte) bool {
+	if !VideoIsExHeader(data) {
+		return false
+	}
+
+	codecId := VideoCodecId(data)
+	return codecId == codec.SrsCodecVideoHEVC || codecId == codec.SrsCodecVideoAV1 || codecId == codec.SrsCodecVideoVP9
+}
+
 func AudioIsAAC(data []byte) bool {
 	if len(data) < 1 {
 		return false
@@ -89,14 +173,16 @@ func VideoIsAcceptable(data []byte) bool {
 		return false
 	}
 
-	formatType := data[0]
-	codecId := formatType & 0x0F
-	formatType = (formatType >> 4) & 0x0F
-
+	formatType := VideoFrameType(data)
 	if formatType < 1 || formatType > 5 {
 		return false
 	}
 
+	if VideoIsExHeader(data) {
+		return VideoIsEnhanced(data)
+	}
+
+	codecId := data[0] & 0x0F
 	if codecId < 2 || codecId > 7 {
 		return false
 	}
@@ -0,0 +1,186 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package flvcodec
+
+import (
+	"encoding/binary"
+	"errors"
+	"go_srs/srs/codec"
+)
+
+/**
+ * the track of enhanced rtmp multitrack packet, the payload is converted to a single track packet,
+ * that is the legacy FLV tag for AAC and AVC, or the ExHeader with FourCC for other codecs.
+ */
+type SrsFlvTrack struct {
+	TrackId uint8
+	Payload []byte
+}
+
+func AudioIsMultitrack(data []byte) bool {
+	if len(data) < 1 {
+		return false
+	}
+
+	soundFormat := (data[0] >> 4) & 0x0F
+	packetType := data[0] & 0x0F
+	return soundFormat == codec.SrsCodecAudioExHeader && packetType == codec.SrsCodecAudioExPacketTypeMultitrack
+}
+
+func VideoIsMultitrack(data []byte) bool {
+	return VideoIsExHeader(data) && VideoExPacketType(data) == codec.SrsCodecVideoExPacketTypeMultitrack
+}
+
+/**
+ * demux the audio multitrack packet to tracks, the AAC is converted to legacy FLV audio tag.
+ */
+func DemuxAudioMultitrack(data []byte) ([]*SrsFlvTrack, error) {
+	if !AudioIsMultitrack(data) {
+		return nil, errors.New("audio is not multitrack")
+	}
+
+	return srs_flv_demux_multitrack(data, func(fourCC uint32, packetType uint8, body []byte) []byte {
+		if fourCC != codec.SrsCodecAudioFourCCAAC {
+			return srs_flv_ex_header(codec.SrsCodecAudioExHeader<<4|packetType, fourCC, body)
+		}
+
+		// AAC, 44kHz, 16bits, stereo, the actual config is in the AudioSpecificConfig.
+		header := byte(codec.SrsCodecAudioAAC<<4 | 0x0F)
+		switch packetType {
+		case codec.SrsCodecAudioExPacketTypeSequenceStart:
+			return append([]byte{header, codec.SrsCodecAudioTypeSequenceHeader}, body...)
+		case codec.SrsCodecAudioExPacketTypeCodedFrames:
+			return append([]byte{header, codec.SrsCodecAudioTypeRawData}, body...)
+		}
+		return nil
+	})
+}
+
+/**
+ * demux the video multitrack packet to tracks, the AVC is converted to legacy FLV video tag.
+ */
+func DemuxVideoMultitrack(data []byte) ([]*SrsFlvTrack, error) {
+	if !VideoIsMultitrack(data) {
+		return nil, errors.New("video is not multitrack")
+	}
+
+	frameType := VideoFrameType(data)
+	return srs_flv_demux_multitrack(data, func(fourCC uint32, packetType uint8, body []byte) []byte {
+		if fourCC != codec.SrsCodecVideoFourCCAVC {
+			return srs_flv_ex_header(0x80|frameType<<4|packetType, fourCC, body)
+		}
+
+		header := frameType<<4 | codec.SrsCodecVideoAVC
+		switch packetType {
+		case codec.SrsCodecVideoExPacketTypeSequenceStart:
+			return append([]byte{header, codec.SrsCodecVideoAVCTypeSequenceHeader, 0, 0, 0}, body...)
+		case codec.SrsCodecVideoExPacketTypeCodedFrames:
+			// the body starts with the SI24 composition time, same as the legacy tag.
+			return append([]byte{header, codec.SrsCodecVideoAVCTypeNALU}, body...)
+		case codec.SrsCodecVideoExPacketTypeCodedFramesX:
+			return append([]byte{header, codec.SrsCodecVideoAVCTypeNALU, 0, 0, 0}, body...)
+		case codec.SrsCodecVideoExPacketTypeSequenceEnd:
+			return []byte{header, codec.SrsCodecVideoAVCTypeSequenceHeaderEOF, 0, 0, 0}
+		}
+		return nil
+	})
+}
+
+func srs_flv_ex_header(header byte, fourCC uint32, body []byte) []byte {
+	payload := make([]byte, 5, 5+len(body))
+	payload[0] = header
+	binary.BigEndian.PutUint32(payload[1:5], fourCC)
+	return append(payload, body...)
+}
+
+/**
+ * demux the multitrack packet, the data[0] is the audio or video tag header, then
+ *      AvMultitrackType UB [4], PacketType UB [4],
+ *      FourCC UI32 if not ManyTracksManyCodecs,
+ * and the tracks, each track is
+ *      FourCC UI32 if ManyTracksManyCodecs,
+ *      TrackId UI8,
+ *      SizeOfTrack UI24 if not OneTrack,
+ *      the body of track.
+ * the convert builds the single track payload, or nil to ignore the track.
+ */
+func srs_flv_demux_multitrack(data []byte, convert func(fourCC uint32, packetType uint8, body []byte) []byte) ([]*SrsFlvTrack, error) {
+	if len(data) < 2 {
+		return nil, errors.New("multitrack requires the track type")
+	}
+
+	multitrackType := (data[1] >> 4) & 0x0F
+	packetType := data[1] & 0x0F
+	if multitrackType > codec.SrsCodecAvMultitrackTypeManyTracksManyCodecs {
+		return nil, errors.New("multitrack invalid type")
+	}
+
+	pos := 2
+	var fourCC uint32
+	if multitrackType != codec.SrsCodecAvMultitrackTypeManyTracksManyCodecs {
+		if len(data) < pos+4 {
+			return nil, errors.New("multitrack requires the FourCC")
+		}
+		fourCC = binary.BigEndian.Uint32(data[pos : pos+4])
+		pos += 4
+	}
+
+	tracks := make([]*SrsFlvTrack, 0)
+	for pos < len(data) {
+		if multitrackType == codec.SrsCodecAvMultitrackTypeManyTracksManyCodecs {
+			if len(data) < pos+4 {
+				return nil, errors.New("multitrack requires the FourCC of track")
+			}
+			fourCC = binary.BigEndian.Uint32(data[pos : pos+4])
+			pos += 4
+		}
+
+		if len(data) < pos+1 {
+			return nil, errors.New("multitrack requires the track id")
+		}
+		trackId := data[pos]
+		pos++
+
+		size := len(data) - pos
+		if multitrackType != codec.SrsCodecAvMultitrackTypeOneTrack {
+			if len(data) < pos+3 {
+				return nil, errors.New("multitrack requires the size of track")
+			}
+			size = int(data[pos])<<16 | int(data[pos+1])<<8 | int(data[pos+2])
+			pos += 3
+			if len(data) < pos+size {
+				return nil, errors.New("multitrack track overflow")
+			}
+		}
+
+		if payload := convert(fourCC, packetType, data[pos:pos+size]); payload != nil {
+			tracks = append(tracks, &SrsFlvTrack{TrackId: trackId, Payload: payload})
+		}
+		pos += size
+
+		if multitrackType == codec.SrsCodecAvMultitrackTypeOneTrack {
+			break
+		}
+	}
+	return tracks, nil
+}
@@ -78,6 +78,46 @@ const (
 	SrsCodecVideoOn2VP6WithAlphaChannel = 5
 	SrsCodecVideoScreenVideoVersion2    = 6
 	SrsCodecVideoAVC                    = 7
+
+	// the codecs of enhanced rtmp, identified by FourCC, not the CodecID of flv.
+	SrsCodecVideoHEVC = 12
+	SrsCodecVideoAV1  = 13
+	SrsCodecVideoVP9  = 14
+)
+
+// Enhanced RTMP, the ExVideoTagHeader
+// @see https://github.com/veovera/enhanced-rtmp
+// IsExHeader UB [1], 1 for the enhanced rtmp video.
+// FrameType UB [3]
+// PacketType UB [4], instead of CodecID.
+// FourCC UI32, the codec when IsExHeader.
+const (
+	SrsCodecVideoFourCCHEVC = 0x68766331 // 'hvc1'
+	SrsCodecVideoFourCCAV1  = 0x61763031 // 'av01'
+	SrsCodecVideoFourCCVP9  = 0x76703039 // 'vp09'
+	SrsCodecVideoFourCCAVC  = 0x61766331 // 'avc1'
+)
+
+// the PacketType of enhanced rtmp video.
+const (
+	SrsCodecVideoExPacketTypeSequenceStart        = 0
+	SrsCodecVideoExPacketTypeCodedFrames          = 1
+	SrsCodecVideoExPacketTypeSequenceEnd          = 2
+	SrsCodecVideoExPacketTypeCodedFramesX         = 3
+	SrsCodecVideoExPacketTypeMetadata             = 4
+	SrsCodecVideoExPacketTypeMPEG2TSSequenceStart = 5
+	SrsCodecVideoExPacketTypeMultitrack           = 6
+	SrsCodecVideoExPacketTypeModEx                = 7
+)
+
+// the AvMultitrackType of enhanced rtmp multitrack packet, for both audio and video.
+// OneTrack, a track with the FourCC of header.
+// ManyTracks, each track has a size and uses the FourCC of header.
+// ManyTracksManyCodecs, each track has a FourCC and size.
+const (
+	SrsCodecAvMultitrackTypeOneTrack             = 0
+	SrsCodecAvMultitrackTypeManyTracks           = 1
+	SrsCodecAvMultitrackTypeManyTracksManyCodecs = 2
 )
 
 // SoundFormat UB [4]
@@ -125,6 +165,27 @@ const (
 	SrsCodecAudioReservedDeviceSpecificSound     = 15
 )
 
+// Enhanced RTMP, the ExAudioTagHeader when SoundFormat is 9,
+// the AudioPacketType UB [4] instead of SoundRate, SoundSize and SoundType,
+// then the FourCC UI32 of codec.
+const (
+	SrsCodecAudioExHeader = 9
+
+	SrsCodecAudioFourCCAAC  = 0x6d703461 // 'mp4a'
+	SrsCodecAudioFourCCOpus = 0x4f707573 // 'Opus'
+	SrsCodecAudioFourCCMP3  = 0x2e6d7033 // '.mp3'
+)
+
+// the AudioPacketType of enhanced rtmp audio.
+const (
+	SrsCodecAudioExPacketTypeSequenceStart      = 0
+	SrsCodecAudioExPacketTypeCodedFrames        = 1
+	SrsCodecAudioExPacketTypeSequenceEnd        = 2
+	SrsCodecAudioExPacketTypeMultichannelConfig = 4
+	SrsCodecAudioExPacketTypeMultitrack         = 5
+	SrsCodecAudioExPacketTypeModEx              = 7
+)
+
 // AVCPacketType IF CodecID == 7 UI8
 // The following values are defined:
 //     0 = AVC sequence header
@@ -35,3 +35,24 @@ func SrsCodecAacRtmp2Ts(objectType SrsAacObjectType) SrsAacProfile {
 		return SrsAacProfileReserved
 	}
 }
+
+func SrsCodecVideo2Str(c SrsCodecVideo) string {
+	switch c {
+	case SrsCodecVideoAVC:
+		return "H264"
+	case SrsCodecVideoHEVC:
+		return "HEVC"
+	case SrsCodecVideoAV1:
+		return "AV1"
+	case SrsCodecVideoVP9:
+		return "VP9"
+	case SrsCodecVideoOn2VP6, SrsCodecVideoOn2VP6WithAlphaChannel:
+		return "VP6"
+	case SrsCodecVideoSorensonH263:
+		return "H263"
+	case SrsCodecVideoScreenVideo, SrsCodecVideoScreenVideoVersion2:
+		return "Screen"
+	default:
+		return "Other"
+	}
+}
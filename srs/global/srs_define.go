@@ -24,6 +24,9 @@ package global
 
 const SRS_CONSTS_RTMP_PROTOCOL_CHUNK_SIZE = 128
 
+// the chunk size of srs, used by the rtmp client to send packets.
+const SRS_CONSTS_RTMP_SRS_CHUNK_SIZE = 60000
+
 const (
 	RTMP_MSG_SetChunkSize               = 0x01
 	RTMP_MSG_AbortMessage               = 0x02
@@ -158,6 +161,9 @@ const SRS_CONSTS_RTMP_DEFAULT_VHOST = "__defaultVhost__"
 // default port of rtmp
 const SRS_CONSTS_RTMP_DEFAULT_PORT = "1935"
 
+// default port of rtmps, the rtmp over tls
+const SRS_CONSTS_RTMPS_DEFAULT_PORT = "443"
+
 const RTMP_SIG_FMS_VER = "3,5,3,888"
 const RTMP_SIG_AMF0_VER = 0
 const RTMP_SIG_CLIENT_ID = "ASAICiss"
@@ -182,10 +188,13 @@ const (
 	// code value
 	StatusCodeConnectSuccess   = "NetConnection.Connect.Success"
 	StatusCodeConnectRejected  = "NetConnection.Connect.Rejected"
+	StatusCodeCallFailed       = "NetConnection.Call.Failed"
 	StatusCodeStreamReset      = "NetStream.Play.Reset"
 	StatusCodeStreamStart      = "NetStream.Play.Start"
 	StatusCodeStreamPause      = "NetStream.Pause.Notify"
 	StatusCodeStreamUnpause    = "NetStream.Unpause.Notify"
+	StatusCodeStreamSeek       = "NetStream.Seek.Notify"
+	StatusCodeStreamSeekFailed = "NetStream.Seek.Failed"
 	StatusCodePublishStart     = "NetStream.Publish.Start"
 	StatusCodeDataStart        = "NetStream.Data.Start"
 	StatusCodeUnpublishSuccess = "NetStream.Unpublish.Success"
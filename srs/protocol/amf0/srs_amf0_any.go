@@ -23,6 +23,7 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package amf0
 
 import (
+	"errors"
 	"go_srs/srs/utils"
 )
 
@@ -31,6 +32,8 @@ type ISrsAmf0Any interface {
 	Encode(stream *utils.SrsStream) error
 	IsMyType(stream *utils.SrsStream) (bool, error)
 	GetValue() interface{}
+	// the size of encoded bytes, including the marker.
+	Size() int
 }
 
 func GenerateSrsAmf0Any(marker byte) ISrsAmf0Any {
@@ -49,7 +52,43 @@ func GenerateSrsAmf0Any(marker byte) ISrsAmf0Any {
 		return &SrsAmf0Undefined{}
 	case RTMP_AMF0_EcmaArray:
 		return &SrsAmf0EcmaArray{}
+	case RTMP_AMF0_StrictArray:
+		return NewSrsAmf0StrictArray()
+	case RTMP_AMF0_Date:
+		return &SrsAmf0Date{}
+	case RTMP_AMF0_LongString:
+		return &SrsAmf0LongString{}
+	case RTMP_AMF0_Reference:
+		return &SrsAmf0Reference{}
+	case RTMP_AMF0_UnSupported:
+		return &SrsAmf0Unsupported{}
+	case RTMP_AMF0_XmlDocument:
+		return &SrsAmf0XmlDocument{}
+	case RTMP_AMF0_TypedObject:
+		return NewSrsAmf0TypedObject("")
+	case RTMP_AMF0_AVMplusObject:
+		return &SrsAmf0AVMplusObject{}
 	default:
 		return nil
 	}
 }
+
+/**
+ * read any amf0 value from stream, the type is identified by the marker.
+ */
+func ReadAny(stream *utils.SrsStream) (ISrsAmf0Any, error) {
+	marker, err := stream.PeekByte()
+	if err != nil {
+		return nil, err
+	}
+
+	any := GenerateSrsAmf0Any(marker)
+	if any == nil {
+		return nil, errors.New("amf0 read any failed, unsupported marker")
+	}
+
+	if err = any.Decode(stream); err != nil {
+		return nil, err
+	}
+	return any, nil
+}
@@ -0,0 +1,165 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf0
+
+import (
+	"errors"
+	"go_srs/srs/protocol/amf3"
+	"go_srs/srs/utils"
+	"strconv"
+)
+
+/**
+ * the AVM+ object, switch to amf3 for the value,
+ * each AVM+ marker starts a new amf3 reference context.
+ */
+type SrsAmf0AVMplusObject struct {
+	Value amf3.ISrsAmf3Any
+}
+
+func NewSrsAmf0AVMplusObject(v amf3.ISrsAmf3Any) *SrsAmf0AVMplusObject {
+	return &SrsAmf0AVMplusObject{
+		Value: v,
+	}
+}
+
+func (this *SrsAmf0AVMplusObject) Decode(stream *utils.SrsStream) error {
+	marker, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if marker != RTMP_AMF0_AVMplusObject {
+		return errors.New("amf0 check AVM+ object marker failed.")
+	}
+
+	this.Value, err = amf3.ReadAny(amf3.NewSrsAmf3Context(), stream)
+	return err
+}
+
+func (this *SrsAmf0AVMplusObject) Encode(stream *utils.SrsStream) error {
+	if this.Value == nil {
+		return errors.New("amf0 encode empty AVM+ object.")
+	}
+
+	stream.WriteByte(RTMP_AMF0_AVMplusObject)
+	return this.Value.Encode(amf3.NewSrsAmf3Context(), stream)
+}
+
+func (this *SrsAmf0AVMplusObject) IsMyType(stream *utils.SrsStream) (bool, error) {
+	marker, err := stream.PeekByte()
+	if err != nil {
+		return false, err
+	}
+
+	if marker == RTMP_AMF0_AVMplusObject {
+		return true, nil
+	}
+	return false, nil
+}
+
+func (this *SrsAmf0AVMplusObject) GetValue() interface{} {
+	if this.Value == nil {
+		return nil
+	}
+	return this.Value.GetValue()
+}
+
+/**
+ * convert the amf3 value to the equivalent amf0 value,
+ * the value which amf0 can't represent is kept in AVM+ object.
+ */
+func (this *SrsAmf0AVMplusObject) ToAmf0() ISrsAmf0Any {
+	return srs_amf3_to_amf0(this.Value)
+}
+
+func srs_amf3_to_amf0(v amf3.ISrsAmf3Any) ISrsAmf0Any {
+	switch t := v.(type) {
+	case *amf3.SrsAmf3Undefined:
+		return &SrsAmf0Undefined{}
+	case *amf3.SrsAmf3Null:
+		return NewSrsAmf0Null()
+	case *amf3.SrsAmf3Boolean:
+		return NewSrsAmf0Boolean(t.Value)
+	case *amf3.SrsAmf3Integer:
+		return NewSrsAmf0Number(float64(t.Value))
+	case *amf3.SrsAmf3Double:
+		return NewSrsAmf0Number(t.Value)
+	case *amf3.SrsAmf3String:
+		return NewSrsAmf0String(t.Value)
+	case *amf3.SrsAmf3Date:
+		return &SrsAmf0Date{Value: t.Value}
+	case *amf3.SrsAmf3Xml:
+		return NewSrsAmf0XmlDocument(t.Value)
+	case *amf3.SrsAmf3Array:
+		if len(t.Assoc) == 0 {
+			arr := NewSrsAmf0StrictArray()
+			for i := 0; i < len(t.Dense); i++ {
+				arr.Append(srs_amf3_to_amf0(t.Dense[i]))
+			}
+			return arr
+		}
+
+		arr := NewSrsAmf0EcmaArray()
+		for i := 0; i < len(t.Dense); i++ {
+			arr.Properties = append(arr.Properties, SrsValuePair{
+				Name:  SrsAmf0Utf8{Value: strconv.Itoa(i)},
+				Value: srs_amf3_to_amf0(t.Dense[i]),
+			})
+		}
+		for _, p := range t.Assoc {
+			arr.Properties = append(arr.Properties, SrsValuePair{
+				Name:  SrsAmf0Utf8{Value: p.Name},
+				Value: srs_amf3_to_amf0(p.Value),
+			})
+		}
+		return arr
+	case *amf3.SrsAmf3Object:
+		if t.External != nil {
+			return srs_amf3_to_amf0(t.External)
+		}
+		obj := NewSrsAmf0Object()
+		for _, p := range t.Properties() {
+			obj.Properties = append(obj.Properties, SrsValuePair{
+				Name:  SrsAmf0Utf8{Value: p.Name},
+				Value: srs_amf3_to_amf0(p.Value),
+			})
+		}
+		return obj
+	default:
+		return NewSrsAmf0AVMplusObject(v)
+	}
+}
+
+func (this *SrsAmf0AVMplusObject) Size() int {
+	if this.Value == nil {
+		return 0
+	}
+
+	// the amf3 is variable length, encode to get the size.
+	stream := utils.NewSrsStream([]byte{})
+	if err := this.Value.Encode(amf3.NewSrsAmf3Context(), stream); err != nil {
+		return 0
+	}
+	return 1 + len(stream.Data())
+}
@@ -82,3 +82,7 @@ func (this *SrsAmf0Boolean) IsMyType(stream *utils.SrsStream) (bool, error) {
 func (this *SrsAmf0Boolean) GetValue() interface{} {
 	return this.Value
 }
+
+func (this *SrsAmf0Boolean) Size() int {
+	return 1 + 1
+}
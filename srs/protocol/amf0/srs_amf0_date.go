@@ -0,0 +1,99 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf0
+
+import (
+	"encoding/binary"
+	"errors"
+	"go_srs/srs/utils"
+	"time"
+)
+
+/**
+ * the date, the milliseconds from 1970 UTC and the timezone,
+ * the timezone should be 0x0000, which is reserved.
+ */
+type SrsAmf0Date struct {
+	Value    float64
+	TimeZone int16
+}
+
+func NewSrsAmf0Date(t time.Time) *SrsAmf0Date {
+	return &SrsAmf0Date{
+		Value: float64(t.UnixNano() / int64(time.Millisecond)),
+	}
+}
+
+/**
+ * get the date in golang time, in UTC.
+ */
+func (this *SrsAmf0Date) Time() time.Time {
+	ms := int64(this.Value)
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)).UTC()
+}
+
+func (this *SrsAmf0Date) Decode(stream *utils.SrsStream) error {
+	marker, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if marker != RTMP_AMF0_Date {
+		err := errors.New("amf0 check date marker failed.")
+		return err
+	}
+
+	if this.Value, err = stream.ReadFloat64(binary.BigEndian); err != nil {
+		return err
+	}
+
+	this.TimeZone, err = stream.ReadInt16(binary.BigEndian)
+	return err
+}
+
+func (this *SrsAmf0Date) Encode(stream *utils.SrsStream) error {
+	stream.WriteByte(RTMP_AMF0_Date)
+	stream.WriteFloat64(this.Value, binary.BigEndian)
+	stream.WriteInt16(this.TimeZone, binary.BigEndian)
+	return nil
+}
+
+func (this *SrsAmf0Date) IsMyType(stream *utils.SrsStream) (bool, error) {
+	marker, err := stream.PeekByte()
+	if err != nil {
+		return false, err
+	}
+
+	if marker == RTMP_AMF0_Date {
+		return true, nil
+	}
+	return false, nil
+}
+
+func (this *SrsAmf0Date) GetValue() interface{} {
+	return this.Time()
+}
+
+func (this *SrsAmf0Date) Size() int {
+	return 1 + 8 + 2
+}
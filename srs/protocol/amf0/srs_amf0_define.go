@@ -22,6 +22,11 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 */
 package amf0
 
+import (
+	"errors"
+	"go_srs/srs/utils"
+)
+
 const (
 	RTMP_AMF0_Number      = 0x00
 	RTMP_AMF0_Boolean     = 0x01
@@ -55,8 +60,10 @@ const (
 	RTMP_AMF0_COMMAND_CONNECT        = "connect"
 	RTMP_AMF0_COMMAND_CREATE_STREAM  = "createStream"
 	RTMP_AMF0_COMMAND_CLOSE_STREAM   = "closeStream"
+	RTMP_AMF0_COMMAND_DELETE_STREAM  = "deleteStream"
 	RTMP_AMF0_COMMAND_PLAY           = "play"
 	RTMP_AMF0_COMMAND_PAUSE          = "pause"
+	RTMP_AMF0_COMMAND_SEEK           = "seek"
 	RTMP_AMF0_COMMAND_ON_BW_DONE     = "onBWDone"
 	RTMP_AMF0_COMMAND_ON_STATUS      = "onStatus"
 	RTMP_AMF0_COMMAND_RESULT         = "_result"
@@ -75,3 +82,49 @@ type SrsValuePair struct {
 
 const SRS_CONSTS_RTMP_SET_DATAFRAME = "@setDataFrame"
 const SRS_CONSTS_RTMP_ON_METADATA = "onMetaData"
+
+/**
+ * read the properties of object, ecma array and typed object, until the object eof.
+ */
+func srs_amf0_read_properties(stream *utils.SrsStream) ([]SrsValuePair, error) {
+	properties := make([]SrsValuePair, 0)
+	eof := NewSrsAmf0ObjectEOF()
+	for {
+		is_eof, err := eof.IsMyType(stream)
+		if err != nil {
+			return nil, err
+		}
+
+		if is_eof {
+			return properties, eof.Decode(stream)
+		}
+		//读取属性名称
+		var pname SrsAmf0Utf8 = SrsAmf0Utf8{}
+		if err = pname.Decode(stream); err != nil {
+			return nil, err
+		}
+
+		marker, err := stream.PeekByte()
+		if err != nil {
+			return nil, err
+		}
+
+		v := GenerateSrsAmf0Any(marker)
+		if v == nil {
+			return nil, errors.New("amf0 decode property failed, unsupported marker")
+		}
+		if err = v.Decode(stream); err != nil {
+			return nil, err
+		}
+
+		properties = append(properties, SrsValuePair{Name: pname, Value: v})
+	}
+}
+
+func srs_amf0_properties_size(properties []SrsValuePair) int {
+	size := 0
+	for i := 0; i < len(properties); i++ {
+		size += properties[i].Name.Size() + properties[i].Value.Size()
+	}
+	return size
+}
@@ -25,7 +25,6 @@ package amf0
 import (
 	"encoding/binary"
 	"errors"
-	"fmt"
 	"go_srs/srs/utils"
 	_ "log"
 	"reflect"
@@ -81,73 +80,8 @@ func (this *SrsAmf0EcmaArray) Decode(stream *utils.SrsStream) error {
 		return err
 	}
 
-	for {
-		var is_eof bool
-		if is_eof, err = this.eof.IsMyType(stream); err != nil {
-			return err
-		}
-
-		if is_eof {
-			this.eof.Decode(stream)
-			return nil
-		}
-		//读取属性名称
-		var pname SrsAmf0Utf8 = SrsAmf0Utf8{}
-		err = pname.Decode(stream)
-		if err != nil {
-			return err
-		}
-
-		marker, err := stream.PeekByte()
-		if err != nil {
-			return err
-		}
-
-		var v ISrsAmf0Any
-		switch marker {
-		case RTMP_AMF0_Number:
-			{
-				v = &SrsAmf0Number{}
-				err = v.Decode(stream)
-			}
-		case RTMP_AMF0_Boolean:
-			{
-				v = &SrsAmf0Boolean{}
-				err = v.Decode(stream)
-			}
-		case RTMP_AMF0_String:
-			{
-				v = &SrsAmf0String{}
-				err = v.Decode(stream)
-			}
-		case RTMP_AMF0_Object:
-			{
-				v = &SrsAmf0Object{}
-				err = v.Decode(stream)
-			}
-		case RTMP_AMF0_Null:
-			{
-				v = &SrsAmf0Null{}
-				err = v.Decode(stream)
-			}
-		case RTMP_AMF0_Undefined:
-			{
-				v = &SrsAmf0Undefined{}
-				err = v.Decode(stream)
-			}
-		}
-
-		if err != nil {
-			return err
-		}
-
-		pair := SrsValuePair{
-			Name:  pname,
-			Value: v,
-		}
-		this.Properties = append(this.Properties, pair)
-	}
-	return nil
+	this.Properties, err = srs_amf0_read_properties(stream)
+	return err
 }
 
 func (this *SrsAmf0EcmaArray) Encode(stream *utils.SrsStream) error {
@@ -155,7 +89,9 @@ func (this *SrsAmf0EcmaArray) Encode(stream *utils.SrsStream) error {
 	stream.WriteInt32(int32(len(this.Properties)), binary.BigEndian)
 	for i := 0; i < len(this.Properties); i++ {
 		_ = this.Properties[i].Name.Encode(stream)
-		_ = this.Properties[i].Value.Encode(stream)
+		if err := this.Properties[i].Value.Encode(stream); err != nil {
+			return err
+		}
 	}
 	_ = this.eof.Encode(stream)
 	return nil
@@ -200,6 +136,13 @@ func (this *SrsAmf0EcmaArray) Set(name string, value interface{}) {
 			Name:  SrsAmf0Utf8{Value: name},
 			Value: &SrsAmf0Number{Value: value.(float64)},
 		}
+	case ISrsAmf0Any:
+		p = &SrsValuePair{
+			Name:  SrsAmf0Utf8{Value: name},
+			Value: value.(ISrsAmf0Any),
+		}
+	default:
+		return
 	}
 	this.Properties = append(this.Properties, *p)
 }
@@ -210,7 +153,6 @@ func (this *SrsAmf0EcmaArray) Get(name string, pval interface{}) error {
 	}
 
 	for i := 0; i < len(this.Properties); i++ {
-		fmt.Println(this.Properties[i].Name.Value, name)
 		if this.Properties[i].Name.Value == name {
 			if reflect.TypeOf(pval).Elem() == reflect.TypeOf(this.Properties[i].Value.GetValue()) {
 				reflect.ValueOf(pval).Elem().Set(reflect.ValueOf(this.Properties[i].Value.GetValue()))
@@ -226,3 +168,7 @@ func (this *SrsAmf0EcmaArray) Get(name string, pval interface{}) error {
 func (this *SrsAmf0EcmaArray) GetValue() interface{} {
 	return this.Properties
 }
+
+func (this *SrsAmf0EcmaArray) Size() int {
+	return 1 + 4 + srs_amf0_properties_size(this.Properties) + this.eof.Size()
+}
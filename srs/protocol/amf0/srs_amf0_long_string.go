@@ -0,0 +1,97 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf0
+
+import (
+	"encoding/binary"
+	"errors"
+	"go_srs/srs/utils"
+)
+
+/**
+ * the string which is longer than 65535 bytes, the length is in 4bytes.
+ */
+type SrsAmf0LongString struct {
+	Value string
+}
+
+func NewSrsAmf0LongString(str string) *SrsAmf0LongString {
+	return &SrsAmf0LongString{
+		Value: str,
+	}
+}
+
+func srs_amf0_read_utf8_long(stream *utils.SrsStream) (string, error) {
+	len, err := stream.ReadInt32(binary.BigEndian)
+	if err != nil {
+		return "", err
+	}
+
+	return stream.ReadString(uint32(len))
+}
+
+func srs_amf0_write_utf8_long(stream *utils.SrsStream, str string) {
+	stream.WriteInt32(int32(len(str)), binary.BigEndian)
+	stream.WriteString(str)
+}
+
+func (this *SrsAmf0LongString) Decode(stream *utils.SrsStream) error {
+	marker, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if marker != RTMP_AMF0_LongString {
+		err := errors.New("amf0 check long string marker failed.")
+		return err
+	}
+
+	this.Value, err = srs_amf0_read_utf8_long(stream)
+	return err
+}
+
+func (this *SrsAmf0LongString) Encode(stream *utils.SrsStream) error {
+	stream.WriteByte(RTMP_AMF0_LongString)
+	srs_amf0_write_utf8_long(stream, this.Value)
+	return nil
+}
+
+func (this *SrsAmf0LongString) IsMyType(stream *utils.SrsStream) (bool, error) {
+	marker, err := stream.PeekByte()
+	if err != nil {
+		return false, err
+	}
+
+	if marker == RTMP_AMF0_LongString {
+		return true, nil
+	}
+	return false, nil
+}
+
+func (this *SrsAmf0LongString) GetValue() interface{} {
+	return this.Value
+}
+
+func (this *SrsAmf0LongString) Size() int {
+	return 1 + 4 + len(this.Value)
+}
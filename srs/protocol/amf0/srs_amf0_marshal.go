@@ -0,0 +1,544 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf0
+
+import (
+	"errors"
+	"fmt"
+	"go_srs/srs/utils"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var srsAmf0AnyType = reflect.TypeOf((*ISrsAmf0Any)(nil)).Elem()
+var srsTimeType = reflect.TypeOf(time.Time{})
+
+/**
+ * marshal the golang value to amf0 bytes, the value is mapped to:
+ *      bool to Boolean, numbers to Number, string to String or LongString,
+ *      struct to Object, map to EcmaArray, slice and array to StrictArray,
+ *      time.Time to Date, nil to Null, and ISrsAmf0Any is encoded as is.
+ * the struct field is encoded by the tag "amf0", like encoding/json, for example:
+ *      TcUrl string `amf0:"tcUrl"`
+ *      PageUrl string `amf0:"pageUrl,omitempty"`
+ *      Ignored string `amf0:"-"`
+ */
+func Marshal(v interface{}) ([]byte, error) {
+	any, err := MarshalAny(v)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := utils.NewSrsStream([]byte{})
+	if err = any.Encode(stream); err != nil {
+		return nil, err
+	}
+	return stream.Data(), nil
+}
+
+/**
+ * unmarshal the first amf0 value in data to v, which must be a pointer.
+ * @see Marshal for the mapping of types, the properties without field are ignored.
+ */
+func Unmarshal(data []byte, v interface{}) error {
+	any, err := ReadAny(utils.NewSrsStream(data))
+	if err != nil {
+		return err
+	}
+	return UnmarshalAny(any, v)
+}
+
+/**
+ * convert the golang value to amf0 value.
+ */
+func MarshalAny(v interface{}) (ISrsAmf0Any, error) {
+	return srs_amf0_marshal_value(reflect.ValueOf(v))
+}
+
+/**
+ * unmarshal leniently, the properties fail to unmarshal are skipped and the others are set.
+ * @return SrsAmf0FieldErrors when some properties are skipped.
+ */
+func UnmarshalLenient(data []byte, v interface{}) error {
+	any, err := ReadAny(utils.NewSrsStream(data))
+	if err != nil {
+		return err
+	}
+	return UnmarshalAnyLenient(any, v)
+}
+
+/**
+ * convert the amf0 value to golang value v, which must be a pointer.
+ */
+func UnmarshalAny(any ISrsAmf0Any, v interface{}) error {
+	return (&srsAmf0Unmarshaler{}).unmarshal(any, v)
+}
+
+/**
+ * convert the amf0 value to golang value v leniently, @see UnmarshalLenient.
+ */
+func UnmarshalAnyLenient(any ISrsAmf0Any, v interface{}) error {
+	d := &srsAmf0Unmarshaler{lenient: true}
+	if err := d.unmarshal(any, v); err != nil {
+		return err
+	}
+	if len(d.errs) > 0 {
+		return d.errs
+	}
+	return nil
+}
+
+/**
+ * the property skipped by lenient unmarshal, the field is the path of property,
+ * for example, tcUrl or args.name.
+ */
+type SrsAmf0FieldError struct {
+	Field string
+	Err   error
+}
+
+func (this *SrsAmf0FieldError) Error() string {
+	return this.Field + ": " + this.Err.Error()
+}
+
+type SrsAmf0FieldErrors []*SrsAmf0FieldError
+
+func (this SrsAmf0FieldErrors) Error() string {
+	msgs := make([]string, 0, len(this))
+	for _, err := range this {
+		msgs = append(msgs, err.Error())
+	}
+	return "amf0 unmarshal skipped " + strings.Join(msgs, ", ")
+}
+
+/**
+ * whether the property of path is skipped.
+ */
+func (this SrsAmf0FieldErrors) Has(field string) bool {
+	for _, err := range this {
+		if err.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * the state of unmarshal, collects the skipped properties in lenient mode.
+ */
+type srsAmf0Unmarshaler struct {
+	lenient bool
+	errs    SrsAmf0FieldErrors
+}
+
+func (this *srsAmf0Unmarshaler) unmarshal(any ISrsAmf0Any, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("amf0 unmarshal need non-nil pointer")
+	}
+	return this.value(any, rv.Elem(), "")
+}
+
+/**
+ * skip the property of path in lenient mode, or return the err.
+ */
+func (this *srsAmf0Unmarshaler) skip(path string, err error) error {
+	if !this.lenient {
+		return err
+	}
+	this.errs = append(this.errs, &SrsAmf0FieldError{Field: path, Err: err})
+	return nil
+}
+
+func srs_amf0_field_path(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+/**
+ * the field of struct, the index is the path for embedded struct.
+ */
+type srsAmf0Field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+func srs_amf0_struct_fields(t reflect.Type) []srsAmf0Field {
+	fields := make([]srsAmf0Field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("amf0")
+		if tag == "-" {
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		name := opts[0]
+
+		// flatten the embedded struct without name.
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for _, sub := range srs_amf0_struct_fields(f.Type) {
+				sub.index = append([]int{i}, sub.index...)
+				fields = append(fields, sub)
+			}
+			continue
+		}
+
+		// ignore the unexported field.
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		field := srsAmf0Field{name: name, index: []int{i}}
+		for _, opt := range opts[1:] {
+			if opt == "omitempty" {
+				field.omitEmpty = true
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func srs_amf0_is_empty_value(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		return false
+	}
+	return v.IsZero()
+}
+
+func srs_amf0_marshal_value(v reflect.Value) (ISrsAmf0Any, error) {
+	if !v.IsValid() {
+		return NewSrsAmf0Null(), nil
+	}
+
+	if v.Type().Implements(srsAmf0AnyType) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return NewSrsAmf0Null(), nil
+		}
+		return v.Interface().(ISrsAmf0Any), nil
+	}
+
+	if v.Type() == srsTimeType {
+		return NewSrsAmf0Date(v.Interface().(time.Time)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return NewSrsAmf0Null(), nil
+		}
+		return srs_amf0_marshal_value(v.Elem())
+	case reflect.Bool:
+		return NewSrsAmf0Boolean(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewSrsAmf0Number(float64(v.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewSrsAmf0Number(float64(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return NewSrsAmf0Number(v.Float()), nil
+	case reflect.String:
+		if v.Len() > 0xFFFF {
+			return NewSrsAmf0LongString(v.String()), nil
+		}
+		return NewSrsAmf0String(v.String()), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return NewSrsAmf0Null(), nil
+		}
+
+		arr := NewSrsAmf0StrictArray()
+		for i := 0; i < v.Len(); i++ {
+			elem, err := srs_amf0_marshal_value(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			arr.Append(elem)
+		}
+		return arr, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, errors.New("amf0 marshal map failed, key must be string, actual " + v.Type().String())
+		}
+		if v.IsNil() {
+			return NewSrsAmf0Null(), nil
+		}
+
+		// sort the keys, to make the encoded bytes stable.
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+
+		arr := NewSrsAmf0EcmaArray()
+		for _, key := range keys {
+			elem, err := srs_amf0_marshal_value(v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			arr.Properties = append(arr.Properties, SrsValuePair{Name: SrsAmf0Utf8{Value: key.String()}, Value: elem})
+		}
+		return arr, nil
+	case reflect.Struct:
+		obj := NewSrsAmf0Object()
+		for _, field := range srs_amf0_struct_fields(v.Type()) {
+			fv := v.FieldByIndex(field.index)
+			if field.omitEmpty && srs_amf0_is_empty_value(fv) {
+				continue
+			}
+
+			elem, err := srs_amf0_marshal_value(fv)
+			if err != nil {
+				return nil, err
+			}
+			obj.Properties = append(obj.Properties, SrsValuePair{Name: SrsAmf0Utf8{Value: field.name}, Value: elem})
+		}
+		return obj, nil
+	}
+	return nil, errors.New("amf0 marshal unsupported type " + v.Type().String())
+}
+
+/**
+ * get the properties of object, ecma array and typed object.
+ */
+func srs_amf0_properties(any ISrsAmf0Any) ([]SrsValuePair, bool) {
+	switch t := any.(type) {
+	case *SrsAmf0Object:
+		return t.Properties, true
+	case *SrsAmf0EcmaArray:
+		return t.Properties, true
+	case *SrsAmf0TypedObject:
+		return t.Properties, true
+	}
+	return nil, false
+}
+
+/**
+ * convert the amf0 value to the generic golang value,
+ * the object is map[string]interface{}, the strict array is []interface{}.
+ */
+func srs_amf0_to_interface(any ISrsAmf0Any) (interface{}, error) {
+	if props, ok := srs_amf0_properties(any); ok {
+		m := make(map[string]interface{})
+		for _, p := range props {
+			v, err := srs_amf0_to_interface(p.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[p.Name.Value] = v
+		}
+		return m, nil
+	}
+
+	switch t := any.(type) {
+	case *SrsAmf0StrictArray:
+		arr := make([]interface{}, 0, len(t.Elems))
+		for _, elem := range t.Elems {
+			v, err := srs_amf0_to_interface(elem)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case *SrsAmf0AVMplusObject:
+		converted := t.ToAmf0()
+		if _, ok := converted.(*SrsAmf0AVMplusObject); ok {
+			return converted.GetValue(), nil
+		}
+		return srs_amf0_to_interface(converted)
+	}
+	return any.GetValue(), nil
+}
+
+func srs_amf0_type_not_match(any ISrsAmf0Any, v reflect.Value) error {
+	return fmt.Errorf("amf0 unmarshal %T into %v failed, type not match", any, v.Type())
+}
+
+func (this *srsAmf0Unmarshaler) value(any ISrsAmf0Any, v reflect.Value, path string) error {
+	// the amf3 value, convert to amf0 when possible.
+	if avm, ok := any.(*SrsAmf0AVMplusObject); ok {
+		any = avm.ToAmf0()
+	}
+
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		g, err := srs_amf0_to_interface(any)
+		if err != nil {
+			return err
+		}
+		if g == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(g))
+		}
+		return nil
+	}
+
+	if reflect.TypeOf(any).AssignableTo(v.Type()) {
+		v.Set(reflect.ValueOf(any))
+		return nil
+	}
+
+	switch any.(type) {
+	case *SrsAmf0Null, *SrsAmf0Undefined:
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return this.value(any, v.Elem(), path)
+	}
+
+	if props, ok := srs_amf0_properties(any); ok {
+		return this.properties(props, v, path)
+	}
+
+	switch t := any.(type) {
+	case *SrsAmf0Number:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(int64(t.Value))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			v.SetUint(uint64(t.Value))
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(t.Value)
+		default:
+			return srs_amf0_type_not_match(any, v)
+		}
+	case *SrsAmf0Boolean:
+		if v.Kind() != reflect.Bool {
+			return srs_amf0_type_not_match(any, v)
+		}
+		v.SetBool(t.Value)
+	case *SrsAmf0String, *SrsAmf0LongString, *SrsAmf0XmlDocument:
+		if v.Kind() != reflect.String {
+			return srs_amf0_type_not_match(any, v)
+		}
+		v.SetString(any.GetValue().(string))
+	case *SrsAmf0Date:
+		if v.Type() != srsTimeType {
+			return srs_amf0_type_not_match(any, v)
+		}
+		v.Set(reflect.ValueOf(t.Time()))
+	case *SrsAmf0StrictArray:
+		switch v.Kind() {
+		case reflect.Slice:
+			v.Set(reflect.MakeSlice(v.Type(), len(t.Elems), len(t.Elems)))
+		case reflect.Array:
+			if v.Len() < len(t.Elems) {
+				return errors.New("amf0 unmarshal strict array failed, array too small")
+			}
+		default:
+			return srs_amf0_type_not_match(any, v)
+		}
+
+		for i := 0; i < len(t.Elems); i++ {
+			if err := this.value(t.Elems[i], v.Index(i), srs_amf0_field_path(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+	default:
+		return srs_amf0_type_not_match(any, v)
+	}
+	return nil
+}
+
+/**
+ * unmarshal the properties to map or struct, the property fails is skipped in lenient mode.
+ */
+func (this *srsAmf0Unmarshaler) properties(props []SrsValuePair, v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return errors.New("amf0 unmarshal map failed, key must be string, actual " + v.Type().String())
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+
+		for _, p := range props {
+			name := srs_amf0_field_path(path, p.Name.Value)
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := this.value(p.Value, elem, name); err != nil {
+				if err = this.skip(name, err); err != nil {
+					return err
+				}
+				continue
+			}
+			v.SetMapIndex(reflect.ValueOf(p.Name.Value).Convert(v.Type().Key()), elem)
+		}
+		return nil
+	case reflect.Struct:
+		fields := srs_amf0_struct_fields(v.Type())
+		for _, p := range props {
+			field := srs_amf0_find_field(fields, p.Name.Value)
+			if field == nil {
+				continue
+			}
+			name := srs_amf0_field_path(path, p.Name.Value)
+			fv := v.FieldByIndex(field.index)
+			if err := this.value(p.Value, fv, name); err != nil {
+				if err = this.skip(name, err); err != nil {
+					return err
+				}
+				// never keep the field partially set.
+				fv.Set(reflect.Zero(fv.Type()))
+			}
+		}
+		return nil
+	}
+	return errors.New("amf0 unmarshal object failed, type not match " + v.Type().String())
+}
+
+/**
+ * find the field by name, prefer the exact match, then case-insensitive.
+ */
+func srs_amf0_find_field(fields []srsAmf0Field, name string) *srsAmf0Field {
+	for i := 0; i < len(fields); i++ {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	for i := 0; i < len(fields); i++ {
+		if strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
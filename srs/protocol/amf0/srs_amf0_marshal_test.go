@@ -0,0 +1,317 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf0
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go_srs/srs/utils"
+)
+
+type amf0TestVhost struct {
+	Vhost string `amf0:"vhost"`
+}
+
+type amf0TestConnect struct {
+	amf0TestVhost
+	TcUrl    string                 `amf0:"tcUrl"`
+	PageUrl  string                 `amf0:"pageUrl,omitempty"`
+	Ignored  string                 `amf0:"-"`
+	Encoding float64                `amf0:"objectEncoding"`
+	Audio    bool                   `amf0:"audio"`
+	Codecs   []string               `amf0:"codecs"`
+	Args     map[string]interface{} `amf0:"args"`
+	Time     time.Time              `amf0:"time"`
+	Count    int                    `amf0:"count"`
+	Next     *amf0TestConnect       `amf0:"next,omitempty"`
+	Untagged uint16
+	ignored  string
+}
+
+/**
+ * the truncated data of each marshaled value, never unmarshal.
+ */
+func amf0TestTruncated(t *testing.T, name string, b []byte, v interface{}) {
+	for n := 0; n < len(b); n += 1 + n/64 {
+		if err := Unmarshal(b[:n], v); err == nil {
+			t.Fatalf("%s: truncated %d/%d expect error", name, n, len(b))
+		}
+	}
+	if err := Unmarshal(b[:len(b)-1], v); err == nil {
+		t.Fatalf("%s: truncated %d/%d expect error", name, len(b)-1, len(b))
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	now := time.Unix(1571212800, 123000000).UTC()
+	connect := amf0TestConnect{
+		amf0TestVhost: amf0TestVhost{Vhost: "__defaultVhost__"},
+		TcUrl:         "rtmp://127.0.0.1/live",
+		Encoding:      3,
+		Audio:         true,
+		Codecs:        []string{"avc1", "mp4a"},
+		Args:          map[string]interface{}{"token": "xxx", "level": float64(1), "flags": []interface{}{true, nil}},
+		Time:          now,
+		Count:         -2,
+		Next:          &amf0TestConnect{TcUrl: "rtmp://127.0.0.1/next", Time: now},
+		Untagged:      65535,
+	}
+	// the ignored fields are never marshaled.
+	ignored := connect
+	ignored.Ignored = "ignored"
+	ignored.ignored = "ignored"
+
+	cases := []struct {
+		name   string
+		v      interface{}
+		expect interface{}
+	}{
+		{"bool", true, true},
+		{"int", -42, -42},
+		{"uint8", uint8(255), uint8(255)},
+		{"float", 3.25, 3.25},
+		{"string", "hello, 世界", "hello, 世界"},
+		{"string empty", "", ""},
+		{"long string", strings.Repeat("a", 0x10000), strings.Repeat("a", 0x10000)},
+		{"time", now, now},
+		{"slice", []string{"a", "b"}, []string{"a", "b"}},
+		{"slice nil", []string(nil), []string(nil)},
+		{"array", [2]int{1, 2}, [2]int{1, 2}},
+		{"map", map[string]float64{"b": 2, "a": 1}, map[string]float64{"a": 1, "b": 2}},
+		{"map interface", map[string]interface{}{"a": "x", "b": nil, "c": map[string]interface{}{"d": false}}, map[string]interface{}{"a": "x", "b": nil, "c": map[string]interface{}{"d": false}}},
+		{"struct", ignored, connect},
+		{"struct pointer", &connect, &connect},
+		{"nil", nil, map[string]int(nil)},
+	}
+
+	for _, c := range cases {
+		b, err := Marshal(c.v)
+		if err != nil {
+			t.Fatalf("%s: marshal %v", c.name, err)
+		}
+
+		v := reflect.New(reflect.TypeOf(c.expect))
+		if err := Unmarshal(b, v.Interface()); err != nil {
+			t.Fatalf("%s: unmarshal % x, %v", c.name, b, err)
+		}
+		if !reflect.DeepEqual(v.Elem().Interface(), c.expect) {
+			t.Fatalf("%s: got %#v, expect %#v", c.name, v.Elem().Interface(), c.expect)
+		}
+
+		amf0TestTruncated(t, c.name, b, v.Interface())
+	}
+}
+
+func TestMarshalStructFields(t *testing.T) {
+	b, err := Marshal(&amf0TestConnect{TcUrl: "rtmp://127.0.0.1/live", Ignored: "ignored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	any, err := ReadAny(utils.NewSrsStream(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, ok := any.(*SrsAmf0Object)
+	if !ok {
+		t.Fatalf("got %T, expect object", any)
+	}
+
+	// the embedded struct is flattened, the omitempty, ignored and unexported fields are not encoded.
+	var names []string
+	for _, p := range obj.Properties {
+		names = append(names, p.Name.Value)
+	}
+	expect := []string{"vhost", "tcUrl", "objectEncoding", "audio", "codecs", "args", "time", "count", "Untagged"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Fatalf("got %v, expect %v", names, expect)
+	}
+}
+
+func TestUnmarshalFieldName(t *testing.T) {
+	b, err := Marshal(map[string]interface{}{"TCURL": "rtmp://127.0.0.1/live", "vhost": "v", "unknown": 1, "Ignored": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the case-insensitive name is matched, the unknown and ignored names are skipped.
+	var v amf0TestConnect
+	if err := Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.TcUrl != "rtmp://127.0.0.1/live" || v.Vhost != "v" || v.Ignored != "" {
+		t.Fatalf("got %+v", v)
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	cases := []struct {
+		name string
+		v    interface{}
+	}{
+		{"map key", map[int]string{1: "a"}},
+		{"chan", make(chan int)},
+		{"func field", struct{ F func() }{func() {}}},
+	}
+
+	for _, c := range cases {
+		if _, err := Marshal(c.v); err == nil {
+			t.Fatalf("%s: expect error", c.name)
+		}
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	var connect amf0TestConnect
+	var array [1]int
+	cases := []struct {
+		name string
+		v    interface{}
+		out  interface{}
+	}{
+		{"not pointer", "a", connect},
+		{"nil pointer", "a", (*amf0TestConnect)(nil)},
+		{"string to number", "a", new(int)},
+		{"number to string", 1, new(string)},
+		{"bool to string", true, new(string)},
+		{"number to time", 1, new(time.Time)},
+		{"array to struct", []int{1}, &connect},
+		{"array too small", []int{1, 2}, &array},
+		{"object to slice", map[string]int{"a": 1}, new([]int)},
+		{"object to map key", map[string]int{"a": 1}, new(map[int]int)},
+		{"field", map[string]interface{}{"tcUrl": 1}, &connect},
+		{"nested field", map[string]interface{}{"next": map[string]interface{}{"audio": "yes"}}, &connect},
+	}
+
+	for _, c := range cases {
+		b, err := Marshal(c.v)
+		if err != nil {
+			t.Fatalf("%s: marshal %v", c.name, err)
+		}
+		if err := Unmarshal(b, c.out); err == nil {
+			t.Fatalf("%s: expect error", c.name)
+		}
+	}
+}
+
+type amf0TestLenient struct {
+	TcUrl string         `amf0:"tcUrl"`
+	Count int            `amf0:"count"`
+	Args  map[string]int `amf0:"args"`
+	Sub   struct {
+		Name string `amf0:"name"`
+		Id   int    `amf0:"id"`
+	} `amf0:"sub"`
+	Codecs []string `amf0:"codecs"`
+}
+
+func TestUnmarshalLenient(t *testing.T) {
+	b, err := Marshal(map[string]interface{}{
+		"tcUrl":  1,
+		"count":  3,
+		"args":   map[string]interface{}{"a": 1, "b": "x"},
+		"sub":    map[string]interface{}{"name": 2, "id": 7},
+		"codecs": []interface{}{"avc1", 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the strict unmarshal fails at the first property.
+	var v amf0TestLenient
+	if err := Unmarshal(b, &v); err == nil {
+		t.Fatal("strict: expect error")
+	}
+
+	v = amf0TestLenient{}
+	err = UnmarshalLenient(b, &v)
+	errs, ok := err.(SrsAmf0FieldErrors)
+	if !ok {
+		t.Fatalf("got %v, expect field errors", err)
+	}
+
+	// the keys of map are sorted when marshal.
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	if expect := []string{"args.b", "codecs", "sub.name", "tcUrl"}; !reflect.DeepEqual(fields, expect) {
+		t.Fatalf("got %v, expect %v", fields, expect)
+	}
+	if !errs.Has("tcUrl") || errs.Has("count") {
+		t.Fatalf("has got %v", errs)
+	}
+
+	// the skipped fields are zero, the others are set.
+	if v.TcUrl != "" || v.Count != 3 || !reflect.DeepEqual(v.Args, map[string]int{"a": 1}) || v.Sub.Name != "" || v.Sub.Id != 7 || v.Codecs != nil {
+		t.Fatalf("got %+v", v)
+	}
+
+	// no field errors.
+	if b, err = Marshal(map[string]interface{}{"tcUrl": "rtmp://127.0.0.1/live"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalLenient(b, &v); err != nil || v.TcUrl != "rtmp://127.0.0.1/live" {
+		t.Fatalf("got %+v %v", v, err)
+	}
+
+	// the error of data is never skipped.
+	if err := UnmarshalLenient(b[:len(b)-1], &v); err == nil {
+		t.Fatal("truncated: expect error")
+	} else if _, ok := err.(SrsAmf0FieldErrors); ok {
+		t.Fatalf("truncated: got field errors %v", err)
+	}
+}
+
+/**
+ * the crafted count or length of stream, never allocate by it.
+ */
+func TestUnmarshalOversized(t *testing.T) {
+	cases := []struct {
+		name string
+		b    []byte
+	}{
+		{"string", []byte{RTMP_AMF0_String, 0xff, 0xff}},
+		{"long string", []byte{RTMP_AMF0_LongString, 0xff, 0xff, 0xff, 0xff}},
+		{"strict array", []byte{RTMP_AMF0_StrictArray, 0x7f, 0xff, 0xff, 0xff}},
+		{"ecma array", []byte{RTMP_AMF0_EcmaArray, 0x7f, 0xff, 0xff, 0xff, 0x00, 0x00}},
+		{"object without eof", []byte{RTMP_AMF0_Object, 0x00, 0x01, 'a', RTMP_AMF0_Null}},
+	}
+
+	for _, c := range cases {
+		var before, after runtime.MemStats
+		var v interface{}
+		runtime.ReadMemStats(&before)
+		if err := Unmarshal(c.b, &v); err == nil {
+			t.Fatalf("%s: % x expect error", c.name, c.b)
+		}
+		runtime.ReadMemStats(&after)
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Fatalf("%s: allocated %d bytes", c.name, n)
+		}
+	}
+}
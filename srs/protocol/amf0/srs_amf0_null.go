@@ -68,3 +68,7 @@ func (this *SrsAmf0Null) IsMyType(stream *utils.SrsStream) (bool, error) {
 func (this *SrsAmf0Null) GetValue() interface{} {
 	return nil
 }
+
+func (this *SrsAmf0Null) Size() int {
+	return 1
+}
@@ -77,3 +77,7 @@ func (this *SrsAmf0Number) IsMyType(stream *utils.SrsStream) (bool, error) {
 func (this *SrsAmf0Number) GetValue() interface{} {
 	return this.Value
 }
+
+func (this *SrsAmf0Number) Size() int {
+	return 1 + 8
+}
@@ -50,80 +50,17 @@ func (this *SrsAmf0Object) Decode(stream *utils.SrsStream) error {
 		return err
 	}
 
-	for {
-		var is_eof bool
-		if is_eof, err = this.eof.IsMyType(stream); err != nil {
-			return err
-		}
-
-		if is_eof {
-			this.eof.Decode(stream)
-			return nil
-		}
-		//读取属性名称
-		var pname SrsAmf0Utf8 = SrsAmf0Utf8{}
-		err = pname.Decode(stream)
-		if err != nil {
-			return err
-		}
-
-		marker, err := stream.PeekByte()
-		if err != nil {
-			return err
-		}
-
-		var v ISrsAmf0Any
-		switch marker {
-		case RTMP_AMF0_Number:
-			{
-				v = &SrsAmf0Number{}
-				err = v.Decode(stream)
-			}
-		case RTMP_AMF0_Boolean:
-			{
-				v = &SrsAmf0Boolean{}
-				err = v.Decode(stream)
-			}
-		case RTMP_AMF0_String:
-			{
-				v = &SrsAmf0String{}
-				err = v.Decode(stream)
-			}
-		case RTMP_AMF0_Object:
-			{
-				v = &SrsAmf0Object{}
-				err = v.Decode(stream)
-			}
-		case RTMP_AMF0_Null:
-			{
-				v = &SrsAmf0Null{}
-				err = v.Decode(stream)
-			}
-		case RTMP_AMF0_Undefined:
-			{
-				v = &SrsAmf0Undefined{}
-				err = v.Decode(stream)
-			}
-		}
-
-		if err != nil {
-			return err
-		}
-
-		pair := SrsValuePair{
-			Name:  pname,
-			Value: v,
-		}
-		this.Properties = append(this.Properties, pair)
-	}
-	return nil
+	this.Properties, err = srs_amf0_read_properties(stream)
+	return err
 }
 
 func (this *SrsAmf0Object) Encode(stream *utils.SrsStream) error {
 	stream.WriteByte(RTMP_AMF0_Object)
 	for i := 0; i < len(this.Properties); i++ {
 		_ = this.Properties[i].Name.Encode(stream)
-		_ = this.Properties[i].Value.Encode(stream)
+		if err := this.Properties[i].Value.Encode(stream); err != nil {
+			return err
+		}
 	}
 	_ = this.eof.Encode(stream)
 	return nil
@@ -170,6 +107,13 @@ func (this *SrsAmf0Object) Set(name string, value interface{}) {
 			Name:  SrsAmf0Utf8{Value: name},
 			Value: value.(*SrsAmf0EcmaArray),
 		}
+	case ISrsAmf0Any:
+		p = &SrsValuePair{
+			Name:  SrsAmf0Utf8{Value: name},
+			Value: value.(ISrsAmf0Any),
+		}
+	default:
+		return
 	}
 
 	this.Properties = append(this.Properties, *p)
@@ -203,3 +147,7 @@ func (this *SrsAmf0Object) Get(name string, pval interface{}) error {
 func (this *SrsAmf0Object) GetValue() interface{} {
 	return this.Properties
 }
+
+func (this *SrsAmf0Object) Size() int {
+	return 1 + srs_amf0_properties_size(this.Properties) + this.eof.Size()
+}
@@ -80,3 +80,7 @@ func (this *SrsAmf0ObjectEOF) IsMyType(stream *utils.SrsStream) (bool, error) {
 func (this *SrsAmf0ObjectEOF) GetValue() interface{} {
 	return nil
 }
+
+func (this *SrsAmf0ObjectEOF) Size() int {
+	return 2 + 1
+}
@@ -72,3 +72,7 @@ func (this *SrsAmf0String) IsMyType(stream *utils.SrsStream) (bool, error) {
 func (this *SrsAmf0String) GetValue() interface{} {
 	return this.Value.Value
 }
+
+func (this *SrsAmf0String) Size() int {
+	return 1 + this.Value.Size()
+}
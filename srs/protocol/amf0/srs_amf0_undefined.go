@@ -63,3 +63,7 @@ func (this *SrsAmf0Undefined) IsMyType(stream *utils.SrsStream) (bool, error) {
 func (this *SrsAmf0Undefined) GetValue() interface{} {
 	return nil
 }
+
+func (this *SrsAmf0Undefined) Size() int {
+	return 1
+}
@@ -44,16 +44,20 @@ func (this *SrsAmf0Utf8) Decode(stream *utils.SrsStream) error {
 		return err
 	}
 
-	if len <= 0 {
-		err = errors.New("amf0 read empty string.")
-		return err
+	// empty string is allowed, for instance, the empty value of metadata.
+	if len == 0 {
+		this.Value = ""
+		return nil
 	}
 
-	this.Value, err = stream.ReadString(uint32(len))
+	this.Value, err = stream.ReadString(uint32(uint16(len)))
 	return err
 }
 
 func (this *SrsAmf0Utf8) Encode(stream *utils.SrsStream) error {
+	if len(this.Value) > 0xFFFF {
+		return errors.New("amf0 utf8 too long, use long string.")
+	}
 	stream.WriteInt16(int16(len(this.Value)), binary.BigEndian)
 	stream.WriteString(this.Value)
 	return nil
@@ -66,3 +70,7 @@ func (this *SrsAmf0Utf8) IsMyType(stream *utils.SrsStream) (bool, error) {
 func (this *SrsAmf0Utf8) GetValue() interface{} {
 	return this.Value
 }
+
+func (this *SrsAmf0Utf8) Size() int {
+	return 2 + len(this.Value)
+}
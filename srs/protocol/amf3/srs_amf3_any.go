@@ -0,0 +1,104 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf3
+
+import (
+	"errors"
+	"go_srs/srs/utils"
+)
+
+/**
+ * the amf3 value, the reference tables of strings, objects and traits
+ * are kept in the context, which is shared by the values of an amf3 session.
+ */
+type ISrsAmf3Any interface {
+	Decode(ctx *SrsAmf3Context, stream *utils.SrsStream) error
+	Encode(ctx *SrsAmf3Context, stream *utils.SrsStream) error
+	IsMyType(stream *utils.SrsStream) (bool, error)
+	GetValue() interface{}
+}
+
+func GenerateSrsAmf3Any(marker byte) ISrsAmf3Any {
+	switch marker {
+	case RTMP_AMF3_Undefined:
+		return &SrsAmf3Undefined{}
+	case RTMP_AMF3_Null:
+		return &SrsAmf3Null{}
+	case RTMP_AMF3_False, RTMP_AMF3_True:
+		return &SrsAmf3Boolean{}
+	case RTMP_AMF3_Integer:
+		return &SrsAmf3Integer{}
+	case RTMP_AMF3_Double:
+		return &SrsAmf3Double{}
+	case RTMP_AMF3_String:
+		return &SrsAmf3String{}
+	case RTMP_AMF3_XmlDocument:
+		return &SrsAmf3Xml{Document: true}
+	case RTMP_AMF3_Date:
+		return &SrsAmf3Date{}
+	case RTMP_AMF3_Array:
+		return NewSrsAmf3Array()
+	case RTMP_AMF3_Object:
+		return NewSrsAmf3Object()
+	case RTMP_AMF3_Xml:
+		return &SrsAmf3Xml{}
+	case RTMP_AMF3_ByteArray:
+		return &SrsAmf3ByteArray{}
+	default:
+		return nil
+	}
+}
+
+/**
+ * read any amf3 value from stream, the marker is used to generate the value.
+ */
+func ReadAny(ctx *SrsAmf3Context, stream *utils.SrsStream) (ISrsAmf3Any, error) {
+	marker, err := stream.PeekByte()
+	if err != nil {
+		return nil, err
+	}
+
+	v := GenerateSrsAmf3Any(marker)
+	if v == nil {
+		return nil, errors.New("amf3 read any failed, unsupported marker")
+	}
+
+	if err = v.Decode(ctx, stream); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func isMyType(stream *utils.SrsStream, markers ...byte) (bool, error) {
+	marker, err := stream.PeekByte()
+	if err != nil {
+		return false, err
+	}
+
+	for _, m := range markers {
+		if marker == m {
+			return true, nil
+		}
+	}
+	return false, nil
+}
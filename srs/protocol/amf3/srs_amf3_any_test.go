@@ -0,0 +1,239 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf3
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"go_srs/srs/utils"
+)
+
+func amf3TestEncode(v ISrsAmf3Any) ([]byte, error) {
+	st := utils.NewSrsStream([]byte{})
+	if err := v.Encode(NewSrsAmf3Context(), st); err != nil {
+		return nil, err
+	}
+	return st.Data(), nil
+}
+
+/**
+ * read all values in b with a context, error if any byte left.
+ */
+func amf3TestDecode(b []byte) ([]ISrsAmf3Any, error) {
+	ctx := NewSrsAmf3Context()
+	st := utils.NewSrsStream(b)
+	var values []ISrsAmf3Any
+	for !st.Empty() {
+		v, err := ReadAny(ctx, st)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func TestAnyRoundTrip(t *testing.T) {
+	array := NewSrsAmf3Array()
+	array.Dense = append(array.Dense, NewSrsAmf3Integer(1), NewSrsAmf3String("a"), NewSrsAmf3Null())
+	array.Assoc = append(array.Assoc, SrsAmf3ValuePair{Name: "a", Value: NewSrsAmf3String("a")})
+
+	object := NewSrsAmf3Object()
+	object.Set("name", NewSrsAmf3String("livestream"))
+	object.Set("duration", NewSrsAmf3Double(10.5))
+	object.Set("array", array)
+	object.Set("bytes", NewSrsAmf3ByteArray([]byte{0x00, 0x01, 0x02}))
+
+	point := NewSrsAmf3Object()
+	point.Traits = &SrsAmf3Traits{ClassName: "Point", Members: []string{"x", "y"}}
+	point.Sealed = append(point.Sealed, NewSrsAmf3Integer(-1), NewSrsAmf3Integer(2))
+
+	collection := NewSrsAmf3Object()
+	collection.Traits = &SrsAmf3Traits{ClassName: SRS_AMF3_CLASS_ARRAY_COLLECTION, Externalizable: true, Members: []string{}}
+	collection.External = array
+
+	cases := []struct {
+		name string
+		v    ISrsAmf3Any
+		// the decoded value, nil if the same as v.
+		expect ISrsAmf3Any
+	}{
+		{"undefined", NewSrsAmf3Undefined(), nil},
+		{"null", NewSrsAmf3Null(), nil},
+		{"true", NewSrsAmf3Boolean(true), nil},
+		{"false", NewSrsAmf3Boolean(false), nil},
+		{"integer zero", NewSrsAmf3Integer(0), nil},
+		{"integer negative", NewSrsAmf3Integer(-1), nil},
+		{"integer max", NewSrsAmf3Integer(SRS_AMF3_INTEGER_MAX), nil},
+		{"integer min", NewSrsAmf3Integer(SRS_AMF3_INTEGER_MIN), nil},
+		// the integer out of 29bits is double.
+		{"integer over max", NewSrsAmf3Integer(SRS_AMF3_INTEGER_MAX + 1), NewSrsAmf3Double(SRS_AMF3_INTEGER_MAX + 1)},
+		{"integer under min", NewSrsAmf3Integer(SRS_AMF3_INTEGER_MIN - 1), NewSrsAmf3Double(SRS_AMF3_INTEGER_MIN - 1)},
+		{"double", NewSrsAmf3Double(-3.25), nil},
+		{"string empty", NewSrsAmf3String(""), nil},
+		{"string", NewSrsAmf3String("hello, 世界"), nil},
+		{"xml", NewSrsAmf3Xml("<a/>", false), nil},
+		{"xml document", NewSrsAmf3Xml("<a/>", true), nil},
+		{"date", NewSrsAmf3Date(1571212800000), nil},
+		{"bytearray empty", NewSrsAmf3ByteArray([]byte{}), nil},
+		{"array", array, nil},
+		{"object", object, nil},
+		{"object sealed", point, nil},
+		{"object externalizable", collection, nil},
+	}
+
+	for _, c := range cases {
+		b, err := amf3TestEncode(c.v)
+		if err != nil {
+			t.Fatalf("%s: encode %v", c.name, err)
+		}
+
+		values, err := amf3TestDecode(b)
+		if err != nil || len(values) != 1 {
+			t.Fatalf("%s: decode % x got %d values, %v", c.name, b, len(values), err)
+		}
+		expect := c.expect
+		if expect == nil {
+			expect = c.v
+		}
+		if !reflect.DeepEqual(values[0], expect) {
+			t.Fatalf("%s: got %#v, expect %#v", c.name, values[0], expect)
+		}
+
+		// the truncated value is error.
+		for n := 0; n < len(b); n++ {
+			if _, err := ReadAny(NewSrsAmf3Context(), utils.NewSrsStream(b[:n])); err == nil {
+				t.Fatalf("%s: truncated % x expect error", c.name, b[:n])
+			}
+		}
+	}
+}
+
+func TestAnyEncodeError(t *testing.T) {
+	sealed := NewSrsAmf3Object()
+	sealed.Traits = &SrsAmf3Traits{ClassName: "Point", Members: []string{"x", "y"}}
+	sealed.Sealed = append(sealed.Sealed, NewSrsAmf3Integer(1))
+
+	external := NewSrsAmf3Object()
+	external.Traits = &SrsAmf3Traits{ClassName: "flex.messaging.io.Unknown", Externalizable: true}
+	external.External = NewSrsAmf3Null()
+
+	cases := []struct {
+		name string
+		v    ISrsAmf3Any
+	}{
+		{"sealed values not match members", sealed},
+		{"unsupported externalizable", external},
+	}
+
+	for _, c := range cases {
+		if _, err := amf3TestEncode(c.v); err == nil {
+			t.Fatalf("%s: expect error", c.name)
+		}
+	}
+}
+
+/**
+ * the values reference the tables of strings, objects and traits.
+ */
+func TestAnyReference(t *testing.T) {
+	cases := []struct {
+		name   string
+		b      []byte
+		expect []interface{}
+	}{
+		{"string", []byte{0x06, 0x07, 'a', 'b', 'c', 0x06, 0x00}, []interface{}{"abc", "abc"}},
+		{"array", []byte{0x09, 0x03, 0x01, 0x04, 0x07, 0x09, 0x00}, []interface{}{
+			[]ISrsAmf3Any{NewSrsAmf3Integer(7)}, []ISrsAmf3Any{NewSrsAmf3Integer(7)},
+		}},
+		// the class Point with member x, then the object of traits reference.
+		{"traits", []byte{0x0a, 0x13, 0x0b, 'P', 'o', 'i', 'n', 't', 0x03, 'x', 0x04, 0x05, 0x0a, 0x01, 0x04, 0x06}, []interface{}{
+			[]SrsAmf3ValuePair{{"x", NewSrsAmf3Integer(5)}}, []SrsAmf3ValuePair{{"x", NewSrsAmf3Integer(6)}},
+		}},
+		{"date", []byte{0x08, 0x01, 0x40, 0x59, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x08, 0x00}, []interface{}{float64(100), float64(100)}},
+		{"bytearray", []byte{0x0c, 0x05, 0x01, 0x02, 0x0c, 0x00}, []interface{}{[]byte{0x01, 0x02}, []byte{0x01, 0x02}}},
+	}
+
+	for _, c := range cases {
+		values, err := amf3TestDecode(c.b)
+		if err != nil || len(values) != len(c.expect) {
+			t.Fatalf("%s: got %d values, %v", c.name, len(values), err)
+		}
+		for i, v := range values {
+			if !reflect.DeepEqual(v.GetValue(), c.expect[i]) {
+				t.Fatalf("%s: value %d got %#v, expect %#v", c.name, i, v.GetValue(), c.expect[i])
+			}
+		}
+	}
+}
+
+func TestAnyInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		b    []byte
+	}{
+		{"unsupported marker", []byte{RTMP_AMF3_Dictionary, 0x01}},
+		{"string reference", []byte{0x06, 0x02}},
+		{"object reference", []byte{0x09, 0x02}},
+		{"traits reference", []byte{0x0a, 0x05}},
+		// the empty dynamic object, then the array reference to it.
+		{"reference type", []byte{0x0a, 0x0b, 0x01, 0x01, 0x09, 0x00}},
+		{"unsupported externalizable", []byte{0x0a, 0x07, 0x03, 'a', 0x01}},
+	}
+
+	for _, c := range cases {
+		if _, err := amf3TestDecode(c.b); err == nil {
+			t.Fatalf("%s: % x expect error", c.name, c.b)
+		}
+	}
+}
+
+/**
+ * the crafted count or size of stream, never allocate by it.
+ */
+func TestAnyOversized(t *testing.T) {
+	cases := []struct {
+		name string
+		b    []byte
+	}{
+		{"string", []byte{0x06, 0xff, 0xff, 0xff, 0xff}},
+		{"xml", []byte{0x0b, 0xff, 0xff, 0xff, 0xff}},
+		{"bytearray", []byte{0x0c, 0xff, 0xff, 0xff, 0xff}},
+		{"array dense", []byte{0x09, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"object members", []byte{0x0a, 0xff, 0xff, 0xff, 0xf3, 0x01}},
+	}
+
+	for _, c := range cases {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if _, err := amf3TestDecode(c.b); err == nil {
+			t.Fatalf("%s: % x expect error", c.name, c.b)
+		}
+		runtime.ReadMemStats(&after)
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Fatalf("%s: allocated %d bytes", c.name, n)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf3
+
+import (
+	"errors"
+	"go_srs/srs/utils"
+)
+
+/**
+ * the array of amf3, with the associative part(name-value pairs terminated by empty name)
+ * and the dense part(values indexed from 0).
+ */
+type SrsAmf3Array struct {
+	Dense []ISrsAmf3Any
+	Assoc []SrsAmf3ValuePair
+}
+
+func NewSrsAmf3Array() *SrsAmf3Array {
+	return &SrsAmf3Array{
+		Dense: make([]ISrsAmf3Any, 0),
+		Assoc: make([]SrsAmf3ValuePair, 0),
+	}
+}
+
+func (this *SrsAmf3Array) Decode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	marker, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if marker != RTMP_AMF3_Array {
+		return errors.New("amf3 check array marker failed.")
+	}
+
+	ref, count, err := ctx.readObjectRef(stream)
+	if err != nil {
+		return err
+	}
+
+	if ref != nil {
+		v, ok := ref.(*SrsAmf3Array)
+		if !ok {
+			return errors.New("amf3 array reference type not match.")
+		}
+		*this = *v
+		return nil
+	}
+
+	// the object table must be updated before the elements, which may reference it.
+	ctx.addObject(this)
+
+	this.Assoc = make([]SrsAmf3ValuePair, 0)
+	for {
+		name, err := ctx.ReadUtf8(stream)
+		if err != nil {
+			return err
+		}
+		if len(name) == 0 {
+			break
+		}
+
+		v, err := ReadAny(ctx, stream)
+		if err != nil {
+			return err
+		}
+		this.Assoc = append(this.Assoc, SrsAmf3ValuePair{Name: name, Value: v})
+	}
+
+	// never preallocate by the count of stream, which maybe crafted.
+	this.Dense = make([]ISrsAmf3Any, 0)
+	for i := uint32(0); i < count; i++ {
+		v, err := ReadAny(ctx, stream)
+		if err != nil {
+			return err
+		}
+		this.Dense = append(this.Dense, v)
+	}
+	return nil
+}
+
+func (this *SrsAmf3Array) Encode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	stream.WriteByte(RTMP_AMF3_Array)
+	if err := WriteU29(stream, uint32(len(this.Dense))<<1|0x01); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(this.Assoc); i++ {
+		if err := ctx.WriteUtf8(stream, this.Assoc[i].Name); err != nil {
+			return err
+		}
+		if err := this.Assoc[i].Value.Encode(ctx, stream); err != nil {
+			return err
+		}
+	}
+	// the empty string to terminate the associative part.
+	if err := ctx.WriteUtf8(stream, ""); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(this.Dense); i++ {
+		if err := this.Dense[i].Encode(ctx, stream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *SrsAmf3Array) IsMyType(stream *utils.SrsStream) (bool, error) {
+	return isMyType(stream, RTMP_AMF3_Array)
+}
+
+func (this *SrsAmf3Array) GetValue() interface{} {
+	return this.Dense
+}
@@ -0,0 +1,93 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf3
+
+import (
+	"errors"
+	"go_srs/srs/utils"
+)
+
+type SrsAmf3ByteArray struct {
+	Value []byte
+}
+
+func NewSrsAmf3ByteArray(v []byte) *SrsAmf3ByteArray {
+	return &SrsAmf3ByteArray{
+		Value: v,
+	}
+}
+
+func (this *SrsAmf3ByteArray) Decode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	marker, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if marker != RTMP_AMF3_ByteArray {
+		return errors.New("amf3 check bytearray marker failed.")
+	}
+
+	ref, size, err := ctx.readObjectRef(stream)
+	if err != nil {
+		return err
+	}
+
+	if ref != nil {
+		v, ok := ref.(*SrsAmf3ByteArray)
+		if !ok {
+			return errors.New("amf3 bytearray reference type not match.")
+		}
+		*this = *v
+		return nil
+	}
+
+	b, err := stream.ReadBytes(size)
+	if err != nil {
+		return err
+	}
+	this.Value = make([]byte, len(b))
+	copy(this.Value, b)
+	ctx.addObject(this)
+	return nil
+}
+
+func (this *SrsAmf3ByteArray) Encode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	if len(this.Value) > SRS_AMF3_U29_MAX>>1 {
+		return errors.New("amf3 bytearray too long")
+	}
+
+	stream.WriteByte(RTMP_AMF3_ByteArray)
+	if err := WriteU29(stream, uint32(len(this.Value))<<1|0x01); err != nil {
+		return err
+	}
+	stream.WriteBytes(this.Value)
+	return nil
+}
+
+func (this *SrsAmf3ByteArray) IsMyType(stream *utils.SrsStream) (bool, error) {
+	return isMyType(stream, RTMP_AMF3_ByteArray)
+}
+
+func (this *SrsAmf3ByteArray) GetValue() interface{} {
+	return this.Value
+}
@@ -0,0 +1,190 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf3
+
+import (
+	"errors"
+	"go_srs/srs/utils"
+)
+
+/**
+ * the reference tables of amf3.
+ * @remark, the tables are reset for each AVM+ marker in amf0.
+ */
+type SrsAmf3Context struct {
+	strings []string
+	objects []ISrsAmf3Any
+	traits  []*SrsAmf3Traits
+	// the index of encoded strings, to write string reference.
+	encodedStrings map[string]int
+}
+
+func NewSrsAmf3Context() *SrsAmf3Context {
+	return &SrsAmf3Context{
+		strings:        make([]string, 0),
+		objects:        make([]ISrsAmf3Any, 0),
+		traits:         make([]*SrsAmf3Traits, 0),
+		encodedStrings: make(map[string]int),
+	}
+}
+
+func (this *SrsAmf3Context) addObject(v ISrsAmf3Any) {
+	this.objects = append(this.objects, v)
+}
+
+func (this *SrsAmf3Context) getObject(index uint32) (ISrsAmf3Any, error) {
+	if int(index) >= len(this.objects) {
+		return nil, errors.New("amf3 invalid object reference")
+	}
+	return this.objects[index], nil
+}
+
+func (this *SrsAmf3Context) addTraits(t *SrsAmf3Traits) {
+	this.traits = append(this.traits, t)
+}
+
+func (this *SrsAmf3Context) getTraits(index uint32) (*SrsAmf3Traits, error) {
+	if int(index) >= len(this.traits) {
+		return nil, errors.New("amf3 invalid traits reference")
+	}
+	return this.traits[index], nil
+}
+
+/**
+ * read the UTF-8-vr, which is U29S-ref or U29S-value with UTF-8 chars.
+ * @remark, the empty string is never sent by reference.
+ */
+func (this *SrsAmf3Context) ReadUtf8(stream *utils.SrsStream) (string, error) {
+	ref, err := ReadU29(stream)
+	if err != nil {
+		return "", err
+	}
+
+	// string reference.
+	if ref&0x01 == 0 {
+		index := ref >> 1
+		if int(index) >= len(this.strings) {
+			return "", errors.New("amf3 invalid string reference")
+		}
+		return this.strings[index], nil
+	}
+
+	size := ref >> 1
+	if size == 0 {
+		return "", nil
+	}
+
+	str, err := stream.ReadString(size)
+	if err != nil {
+		return "", err
+	}
+	this.strings = append(this.strings, str)
+	return str, nil
+}
+
+/**
+ * write the UTF-8-vr, use reference when the string is already written.
+ */
+func (this *SrsAmf3Context) WriteUtf8(stream *utils.SrsStream, str string) error {
+	if len(str) == 0 {
+		return WriteU29(stream, 0x01)
+	}
+
+	if index, ok := this.encodedStrings[str]; ok {
+		return WriteU29(stream, uint32(index)<<1)
+	}
+
+	if len(str) > SRS_AMF3_U29_MAX>>1 {
+		return errors.New("amf3 string too long")
+	}
+
+	this.encodedStrings[str] = len(this.encodedStrings)
+	if err := WriteU29(stream, uint32(len(str))<<1|0x01); err != nil {
+		return err
+	}
+	stream.WriteString(str)
+	return nil
+}
+
+/**
+ * read the U29, the variable length unsigned 29-bit integer,
+ * the first 3bytes use 7bits and the high bit to flag more bytes,
+ * the 4th byte use all 8bits.
+ */
+func ReadU29(stream *utils.SrsStream) (uint32, error) {
+	var v uint32 = 0
+	for i := 0; i < 4; i++ {
+		b, err := stream.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		if i == 3 {
+			return v<<8 | uint32(b), nil
+		}
+
+		v = v<<7 | uint32(b&0x7F)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	return v, nil
+}
+
+func WriteU29(stream *utils.SrsStream, v uint32) error {
+	if v < 0x80 {
+		stream.WriteByte(byte(v))
+	} else if v < 0x4000 {
+		stream.WriteByte(byte(v>>7 | 0x80))
+		stream.WriteByte(byte(v & 0x7F))
+	} else if v < 0x200000 {
+		stream.WriteByte(byte(v>>14 | 0x80))
+		stream.WriteByte(byte(v>>7&0x7F | 0x80))
+		stream.WriteByte(byte(v & 0x7F))
+	} else if v <= SRS_AMF3_U29_MAX {
+		stream.WriteByte(byte(v>>22 | 0x80))
+		stream.WriteByte(byte(v>>15&0x7F | 0x80))
+		stream.WriteByte(byte(v>>8&0x7F | 0x80))
+		stream.WriteByte(byte(v & 0xFF))
+	} else {
+		return errors.New("amf3 U29 out of range")
+	}
+	return nil
+}
+
+/**
+ * read the U29O-ref of the object types(date, array, object, xml and bytearray),
+ * @return the referenced object if the low bit is 0, or the U29 without the low bit.
+ */
+func (this *SrsAmf3Context) readObjectRef(stream *utils.SrsStream) (ISrsAmf3Any, uint32, error) {
+	ref, err := ReadU29(stream)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if ref&0x01 == 0 {
+		v, err := this.getObject(ref >> 1)
+		return v, 0, err
+	}
+	return nil, ref >> 1, nil
+}
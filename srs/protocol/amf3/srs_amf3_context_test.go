@@ -0,0 +1,103 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf3
+
+import (
+	"bytes"
+	"testing"
+
+	"go_srs/srs/utils"
+)
+
+func TestU29(t *testing.T) {
+	cases := []struct {
+		value   uint32
+		encoded []byte
+	}{
+		{0, []byte{0x00}},
+		{0x7f, []byte{0x7f}},
+		{0x80, []byte{0x81, 0x00}},
+		{0x3fff, []byte{0xff, 0x7f}},
+		{0x4000, []byte{0x81, 0x80, 0x00}},
+		{0x1fffff, []byte{0xff, 0xff, 0x7f}},
+		{0x200000, []byte{0x80, 0xc0, 0x80, 0x00}},
+		// the 4th byte use all 8bits.
+		{0x1fffff80, []byte{0xff, 0xff, 0xff, 0x80}},
+		{SRS_AMF3_U29_MAX, []byte{0xff, 0xff, 0xff, 0xff}},
+	}
+
+	for _, c := range cases {
+		st := utils.NewSrsStream([]byte{})
+		if err := WriteU29(st, c.value); err != nil {
+			t.Fatalf("U29 %#x: %v", c.value, err)
+		}
+		if !bytes.Equal(st.Data(), c.encoded) {
+			t.Fatalf("U29 %#x: got % x, expect % x", c.value, st.Data(), c.encoded)
+		}
+
+		v, err := ReadU29(utils.NewSrsStream(c.encoded))
+		if err != nil || v != c.value {
+			t.Fatalf("U29 % x: got %#x %v, expect %#x", c.encoded, v, err, c.value)
+		}
+
+		// the truncated U29 is error.
+		for n := 0; n < len(c.encoded); n++ {
+			if _, err := ReadU29(utils.NewSrsStream(c.encoded[:n])); err == nil {
+				t.Fatalf("U29 % x: expect error", c.encoded[:n])
+			}
+		}
+	}
+
+	if err := WriteU29(utils.NewSrsStream([]byte{}), SRS_AMF3_U29_MAX+1); err == nil {
+		t.Fatal("U29 out of range: expect error")
+	}
+}
+
+func TestUtf8Reference(t *testing.T) {
+	ctx := NewSrsAmf3Context()
+	st := utils.NewSrsStream([]byte{})
+	for _, s := range []string{"abc", "", "def", "abc", "", "def"} {
+		if err := ctx.WriteUtf8(st, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the written string is referenced, the empty string is never referenced.
+	expect := []byte{0x07, 'a', 'b', 'c', 0x01, 0x07, 'd', 'e', 'f', 0x00, 0x01, 0x02}
+	if !bytes.Equal(st.Data(), expect) {
+		t.Fatalf("got % x, expect % x", st.Data(), expect)
+	}
+
+	ctx = NewSrsAmf3Context()
+	rs := utils.NewSrsStream(expect)
+	for _, s := range []string{"abc", "", "def", "abc", "", "def"} {
+		if v, err := ctx.ReadUtf8(rs); err != nil || v != s {
+			t.Fatalf("got %q %v, expect %q", v, err, s)
+		}
+	}
+
+	// the reference out of the table.
+	if _, err := NewSrsAmf3Context().ReadUtf8(utils.NewSrsStream([]byte{0x00})); err == nil {
+		t.Fatal("invalid reference: expect error")
+	}
+}
@@ -0,0 +1,90 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf3
+
+import (
+	"encoding/binary"
+	"errors"
+	"go_srs/srs/utils"
+)
+
+/**
+ * the date of amf3, the milliseconds from 1970 UTC, without timezone.
+ */
+type SrsAmf3Date struct {
+	Value float64
+}
+
+func NewSrsAmf3Date(v float64) *SrsAmf3Date {
+	return &SrsAmf3Date{
+		Value: v,
+	}
+}
+
+func (this *SrsAmf3Date) Decode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	marker, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if marker != RTMP_AMF3_Date {
+		return errors.New("amf3 check date marker failed.")
+	}
+
+	ref, _, err := ctx.readObjectRef(stream)
+	if err != nil {
+		return err
+	}
+
+	if ref != nil {
+		v, ok := ref.(*SrsAmf3Date)
+		if !ok {
+			return errors.New("amf3 date reference type not match.")
+		}
+		*this = *v
+		return nil
+	}
+
+	if this.Value, err = stream.ReadFloat64(binary.BigEndian); err != nil {
+		return err
+	}
+	ctx.addObject(this)
+	return nil
+}
+
+func (this *SrsAmf3Date) Encode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	stream.WriteByte(RTMP_AMF3_Date)
+	if err := WriteU29(stream, 0x01); err != nil {
+		return err
+	}
+	stream.WriteFloat64(this.Value, binary.BigEndian)
+	return nil
+}
+
+func (this *SrsAmf3Date) IsMyType(stream *utils.SrsStream) (bool, error) {
+	return isMyType(stream, RTMP_AMF3_Date)
+}
+
+func (this *SrsAmf3Date) GetValue() interface{} {
+	return this.Value
+}
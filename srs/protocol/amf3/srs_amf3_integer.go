@@ -0,0 +1,86 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf3
+
+import (
+	"encoding/binary"
+	"errors"
+	"go_srs/srs/utils"
+)
+
+/**
+ * the 29bits signed integer, encoded in U29.
+ * @remark, the value out of range is encoded as double.
+ */
+type SrsAmf3Integer struct {
+	Value int32
+}
+
+func NewSrsAmf3Integer(v int32) *SrsAmf3Integer {
+	return &SrsAmf3Integer{
+		Value: v,
+	}
+}
+
+func (this *SrsAmf3Integer) Decode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	marker, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if marker != RTMP_AMF3_Integer {
+		return errors.New("amf3 check integer marker failed.")
+	}
+
+	v, err := ReadU29(stream)
+	if err != nil {
+		return err
+	}
+
+	// sign extend the 29bits integer.
+	if v&0x10000000 != 0 {
+		this.Value = int32(v) - 0x20000000
+	} else {
+		this.Value = int32(v)
+	}
+	return nil
+}
+
+func (this *SrsAmf3Integer) Encode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	if this.Value < SRS_AMF3_INTEGER_MIN || this.Value > SRS_AMF3_INTEGER_MAX {
+		stream.WriteByte(RTMP_AMF3_Double)
+		stream.WriteFloat64(float64(this.Value), binary.BigEndian)
+		return nil
+	}
+
+	stream.WriteByte(RTMP_AMF3_Integer)
+	return WriteU29(stream, uint32(this.Value)&SRS_AMF3_U29_MAX)
+}
+
+func (this *SrsAmf3Integer) IsMyType(stream *utils.SrsStream) (bool, error) {
+	return isMyType(stream, RTMP_AMF3_Integer)
+}
+
+func (this *SrsAmf3Integer) GetValue() interface{} {
+	return this.Value
+}
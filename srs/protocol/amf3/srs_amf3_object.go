@@ -0,0 +1,281 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf3
+
+import (
+	"errors"
+	"go_srs/srs/utils"
+)
+
+/**
+ * the traits of amf3 object, which describes the class name and sealed members,
+ * the traits is sent once and then referenced by index.
+ */
+type SrsAmf3Traits struct {
+	ClassName      string
+	Dynamic        bool
+	Externalizable bool
+	Members        []string
+}
+
+func NewSrsAmf3Traits(className string, dynamic bool) *SrsAmf3Traits {
+	return &SrsAmf3Traits{
+		ClassName: className,
+		Dynamic:   dynamic,
+		Members:   make([]string, 0),
+	}
+}
+
+/**
+ * the object of amf3, the sealed values are in the order of traits members,
+ * the dynamic values are name-value pairs when traits is dynamic.
+ * @remark, only the externalizable ArrayCollection and ObjectProxy is supported,
+ *      which serialize the source in a single amf3 value.
+ */
+type SrsAmf3Object struct {
+	Traits   *SrsAmf3Traits
+	Sealed   []ISrsAmf3Any
+	Dynamic  []SrsAmf3ValuePair
+	External ISrsAmf3Any
+}
+
+/**
+ * create an anonymous dynamic object.
+ */
+func NewSrsAmf3Object() *SrsAmf3Object {
+	return &SrsAmf3Object{
+		Traits:  NewSrsAmf3Traits("", true),
+		Sealed:  make([]ISrsAmf3Any, 0),
+		Dynamic: make([]SrsAmf3ValuePair, 0),
+	}
+}
+
+func srs_amf3_is_supported_externalizable(className string) bool {
+	return className == SRS_AMF3_CLASS_ARRAY_COLLECTION || className == SRS_AMF3_CLASS_OBJECT_PROXY
+}
+
+func (this *SrsAmf3Object) Decode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	marker, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if marker != RTMP_AMF3_Object {
+		return errors.New("amf3 check object marker failed.")
+	}
+
+	ref, flags, err := ctx.readObjectRef(stream)
+	if err != nil {
+		return err
+	}
+
+	if ref != nil {
+		v, ok := ref.(*SrsAmf3Object)
+		if !ok {
+			return errors.New("amf3 object reference type not match.")
+		}
+		*this = *v
+		return nil
+	}
+
+	// U29O-traits-ref, the low bit is 0 for traits reference.
+	if flags&0x01 == 0 {
+		if this.Traits, err = ctx.getTraits(flags >> 1); err != nil {
+			return err
+		}
+	} else {
+		this.Traits = &SrsAmf3Traits{
+			Externalizable: flags&0x02 != 0,
+			Dynamic:        flags&0x04 != 0,
+		}
+		count := flags >> 3
+		if this.Traits.ClassName, err = ctx.ReadUtf8(stream); err != nil {
+			return err
+		}
+
+		// never preallocate by the count of stream, which maybe crafted.
+		this.Traits.Members = make([]string, 0)
+		for i := uint32(0); i < count; i++ {
+			name, err := ctx.ReadUtf8(stream)
+			if err != nil {
+				return err
+			}
+			this.Traits.Members = append(this.Traits.Members, name)
+		}
+		ctx.addTraits(this.Traits)
+	}
+
+	// the object table must be updated before the values, which may reference it.
+	ctx.addObject(this)
+
+	if this.Traits.Externalizable {
+		if !srs_amf3_is_supported_externalizable(this.Traits.ClassName) {
+			return errors.New("amf3 unsupported externalizable class " + this.Traits.ClassName)
+		}
+		this.External, err = ReadAny(ctx, stream)
+		return err
+	}
+
+	this.Sealed = make([]ISrsAmf3Any, 0, len(this.Traits.Members))
+	for i := 0; i < len(this.Traits.Members); i++ {
+		v, err := ReadAny(ctx, stream)
+		if err != nil {
+			return err
+		}
+		this.Sealed = append(this.Sealed, v)
+	}
+
+	this.Dynamic = make([]SrsAmf3ValuePair, 0)
+	if !this.Traits.Dynamic {
+		return nil
+	}
+
+	for {
+		name, err := ctx.ReadUtf8(stream)
+		if err != nil {
+			return err
+		}
+		if len(name) == 0 {
+			break
+		}
+
+		v, err := ReadAny(ctx, stream)
+		if err != nil {
+			return err
+		}
+		this.Dynamic = append(this.Dynamic, SrsAmf3ValuePair{Name: name, Value: v})
+	}
+	return nil
+}
+
+/**
+ * encode the object with inline traits.
+ */
+func (this *SrsAmf3Object) Encode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	stream.WriteByte(RTMP_AMF3_Object)
+
+	if this.Traits.Externalizable {
+		if !srs_amf3_is_supported_externalizable(this.Traits.ClassName) || this.External == nil {
+			return errors.New("amf3 unsupported externalizable class " + this.Traits.ClassName)
+		}
+		// U29O-traits-ext, 0x07.
+		if err := WriteU29(stream, 0x07); err != nil {
+			return err
+		}
+		if err := ctx.WriteUtf8(stream, this.Traits.ClassName); err != nil {
+			return err
+		}
+		return this.External.Encode(ctx, stream)
+	}
+
+	if len(this.Sealed) != len(this.Traits.Members) {
+		return errors.New("amf3 object sealed values not match the traits.")
+	}
+
+	// U29O-traits, inline object and inline traits.
+	flags := uint32(len(this.Traits.Members))<<4 | 0x03
+	if this.Traits.Dynamic {
+		flags |= 0x08
+	}
+	if err := WriteU29(stream, flags); err != nil {
+		return err
+	}
+
+	if err := ctx.WriteUtf8(stream, this.Traits.ClassName); err != nil {
+		return err
+	}
+	for i := 0; i < len(this.Traits.Members); i++ {
+		if err := ctx.WriteUtf8(stream, this.Traits.Members[i]); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < len(this.Sealed); i++ {
+		if err := this.Sealed[i].Encode(ctx, stream); err != nil {
+			return err
+		}
+	}
+
+	if !this.Traits.Dynamic {
+		return nil
+	}
+	for i := 0; i < len(this.Dynamic); i++ {
+		if err := ctx.WriteUtf8(stream, this.Dynamic[i].Name); err != nil {
+			return err
+		}
+		if err := this.Dynamic[i].Value.Encode(ctx, stream); err != nil {
+			return err
+		}
+	}
+	return ctx.WriteUtf8(stream, "")
+}
+
+func (this *SrsAmf3Object) IsMyType(stream *utils.SrsStream) (bool, error) {
+	return isMyType(stream, RTMP_AMF3_Object)
+}
+
+/**
+ * set the value of member, or the dynamic property when not a member.
+ */
+func (this *SrsAmf3Object) Set(name string, value ISrsAmf3Any) {
+	for i := 0; i < len(this.Traits.Members); i++ {
+		if this.Traits.Members[i] == name {
+			this.Sealed[i] = value
+			return
+		}
+	}
+
+	for i := 0; i < len(this.Dynamic); i++ {
+		if this.Dynamic[i].Name == name {
+			this.Dynamic[i].Value = value
+			return
+		}
+	}
+	this.Dynamic = append(this.Dynamic, SrsAmf3ValuePair{Name: name, Value: value})
+}
+
+/**
+ * get the value of member or dynamic property, nil if not found.
+ */
+func (this *SrsAmf3Object) Get(name string) ISrsAmf3Any {
+	for _, p := range this.Properties() {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return nil
+}
+
+/**
+ * the sealed members and dynamic properties, in the order of encoding.
+ */
+func (this *SrsAmf3Object) Properties() []SrsAmf3ValuePair {
+	props := make([]SrsAmf3ValuePair, 0, len(this.Sealed)+len(this.Dynamic))
+	for i := 0; i < len(this.Traits.Members) && i < len(this.Sealed); i++ {
+		props = append(props, SrsAmf3ValuePair{Name: this.Traits.Members[i], Value: this.Sealed[i]})
+	}
+	return append(props, this.Dynamic...)
+}
+
+func (this *SrsAmf3Object) GetValue() interface{} {
+	return this.Properties()
+}
@@ -0,0 +1,105 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package amf3
+
+import (
+	"errors"
+	"go_srs/srs/utils"
+)
+
+/**
+ * the XMLDocument(legacy flash.xml.XMLDocument) or XML(E4X) of amf3,
+ * both are UTF-8 chars without string reference.
+ */
+type SrsAmf3Xml struct {
+	Value string
+	// whether it's the legacy XMLDocument.
+	Document bool
+}
+
+func NewSrsAmf3Xml(v string, document bool) *SrsAmf3Xml {
+	return &SrsAmf3Xml{
+		Value:    v,
+		Document: document,
+	}
+}
+
+func (this *SrsAmf3Xml) marker() byte {
+	if this.Document {
+		return RTMP_AMF3_XmlDocument
+	}
+	return RTMP_AMF3_Xml
+}
+
+func (this *SrsAmf3Xml) Decode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	marker, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	if marker != RTMP_AMF3_XmlDocument && marker != RTMP_AMF3_Xml {
+		return errors.New("amf3 check xml marker failed.")
+	}
+	this.Document = marker == RTMP_AMF3_XmlDocument
+
+	ref, size, err := ctx.readObjectRef(stream)
+	if err != nil {
+		return err
+	}
+
+	if ref != nil {
+		v, ok := ref.(*SrsAmf3Xml)
+		if !ok {
+			return errors.New("amf3 xml reference type not match.")
+		}
+		this.Value = v.Value
+		return nil
+	}
+
+	if this.Value, err = stream.ReadString(size); err != nil {
+		return err
+	}
+	ctx.addObject(this)
+	return nil
+}
+
+func (this *SrsAmf3Xml) Encode(ctx *SrsAmf3Context, stream *utils.SrsStream) error {
+	if len(this.Value) > SRS_AMF3_U29_MAX>>1 {
+		return errors.New("amf3 xml too long")
+	}
+
+	stream.WriteByte(this.marker())
+	if err := WriteU29(stream, uint32(len(this.Value))<<1|0x01); err != nil {
+		return err
+	}
+	stream.WriteString(this.Value)
+	return nil
+}
+
+func (this *SrsAmf3Xml) IsMyType(stream *utils.SrsStream) (bool, error) {
+	return isMyType(stream, this.marker())
+}
+
+func (this *SrsAmf3Xml) GetValue() interface{} {
+	return this.Value
+}
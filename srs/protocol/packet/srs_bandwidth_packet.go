@@ -0,0 +1,126 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package packet
+
+import (
+	"go_srs/srs/global"
+	"go_srs/srs/protocol/amf0"
+	"go_srs/srs/utils"
+)
+
+/**
+ * the commands of bandwidth check, the server starts and stops the play and publish sampling,
+ * the client responses the starting and stopped event, and sends the final packet at last.
+ */
+const (
+	SRS_BW_CHECK_START_PLAY    = "onSrsBandCheckStartPlayBytes"
+	SRS_BW_CHECK_STARTING_PLAY = "onSrsBandCheckStartingPlayBytes"
+	SRS_BW_CHECK_STOP_PLAY     = "onSrsBandCheckStopPlayBytes"
+	SRS_BW_CHECK_STOPPED_PLAY  = "onSrsBandCheckStoppedPlayBytes"
+
+	SRS_BW_CHECK_START_PUBLISH    = "onSrsBandCheckStartPublishBytes"
+	SRS_BW_CHECK_STARTING_PUBLISH = "onSrsBandCheckStartingPublishBytes"
+	SRS_BW_CHECK_STOP_PUBLISH     = "onSrsBandCheckStopPublishBytes"
+	SRS_BW_CHECK_STOPPED_PUBLISH  = "onSrsBandCheckStoppedPublishBytes"
+
+	SRS_BW_CHECK_FINISHED = "onSrsBandCheckFinished"
+	SRS_BW_CHECK_FINAL    = "finalClientPacket"
+
+	// the data packets sent when sampling.
+	SRS_BW_CHECK_PLAYING    = "onSrsBandCheckPlaying"
+	SRS_BW_CHECK_PUBLISHING = "onSrsBandCheckPublishing"
+)
+
+/**
+ * whether the command is the bandwidth check command.
+ */
+func IsSrsBandwidthCommand(name string) bool {
+	switch name {
+	case SRS_BW_CHECK_START_PLAY, SRS_BW_CHECK_STARTING_PLAY, SRS_BW_CHECK_STOP_PLAY, SRS_BW_CHECK_STOPPED_PLAY,
+		SRS_BW_CHECK_START_PUBLISH, SRS_BW_CHECK_STARTING_PUBLISH, SRS_BW_CHECK_STOP_PUBLISH, SRS_BW_CHECK_STOPPED_PUBLISH,
+		SRS_BW_CHECK_FINISHED, SRS_BW_CHECK_FINAL, SRS_BW_CHECK_PLAYING, SRS_BW_CHECK_PUBLISHING:
+		return true
+	}
+	return false
+}
+
+/**
+ * the bandwidth check packet, over NetConnection.
+ * command(commandName, transactionId, null, data)
+ */
+type SrsBandwidthPacket struct {
+	CommandName   amf0.SrsAmf0String
+	TransactionId amf0.SrsAmf0Number
+	NullObj       amf0.SrsAmf0Null
+	// the data of command, nil when client sends nothing.
+	Data *amf0.SrsAmf0Object
+}
+
+func NewSrsBandwidthPacket(command string) *SrsBandwidthPacket {
+	return &SrsBandwidthPacket{
+		CommandName:   amf0.SrsAmf0String{Value: amf0.SrsAmf0Utf8{Value: command}},
+		TransactionId: amf0.SrsAmf0Number{Value: 0},
+		Data:          amf0.NewSrsAmf0Object(),
+	}
+}
+
+func (s *SrsBandwidthPacket) GetMessageType() int8 {
+	return global.RTMP_MSG_AMF0CommandMessage
+}
+
+func (s *SrsBandwidthPacket) GetPreferCid() int32 {
+	return global.RTMP_CID_OverConnection
+}
+
+func (this *SrsBandwidthPacket) Decode(stream *utils.SrsStream) error {
+	if err := this.TransactionId.Decode(stream); err != nil {
+		return err
+	}
+
+	if err := this.NullObj.Decode(stream); err != nil {
+		return err
+	}
+
+	this.Data = nil
+	if !stream.Empty() {
+		data, err := amf0.ReadAny(stream)
+		if err != nil {
+			return err
+		}
+		// ignore the data which is not object.
+		if obj, ok := data.(*amf0.SrsAmf0Object); ok {
+			this.Data = obj
+		}
+	}
+	return nil
+}
+
+func (this *SrsBandwidthPacket) Encode(stream *utils.SrsStream) error {
+	_ = this.CommandName.Encode(stream)
+	_ = this.TransactionId.Encode(stream)
+	_ = this.NullObj.Encode(stream)
+	if this.Data != nil {
+		_ = this.Data.Encode(stream)
+	}
+	return nil
+}
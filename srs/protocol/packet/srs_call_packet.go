@@ -0,0 +1,91 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package packet
+
+import (
+	"go_srs/srs/global"
+	"go_srs/srs/protocol/amf0"
+	"go_srs/srs/utils"
+)
+
+/**
+ * the generic call of client or server, for example, the NetConnection.call of flash,
+ * the command name is the method to call.
+ * call(commandName, transactionId, commandObject, arguments...)
+ */
+type SrsCallPacket struct {
+	CommandName amf0.SrsAmf0String
+	// the transaction id, 0 when the caller does not need the result.
+	TransactionId amf0.SrsAmf0Number
+	// the command object, generally null.
+	CommandObject amf0.ISrsAmf0Any
+	// the arguments of call, empty when no arguments.
+	Arguments []amf0.ISrsAmf0Any
+}
+
+func NewSrsCallPacket(name string) *SrsCallPacket {
+	return &SrsCallPacket{
+		CommandName:   amf0.SrsAmf0String{Value: amf0.SrsAmf0Utf8{Value: name}},
+		TransactionId: amf0.SrsAmf0Number{Value: 0},
+		CommandObject: amf0.NewSrsAmf0Null(),
+		Arguments:     make([]amf0.ISrsAmf0Any, 0),
+	}
+}
+
+func (s *SrsCallPacket) GetMessageType() int8 {
+	return global.RTMP_MSG_AMF0CommandMessage
+}
+
+func (s *SrsCallPacket) GetPreferCid() int32 {
+	return global.RTMP_CID_OverConnection
+}
+
+func (this *SrsCallPacket) Decode(stream *utils.SrsStream) error {
+	var err error
+	if err = this.TransactionId.Decode(stream); err != nil {
+		return err
+	}
+
+	if this.CommandObject, err = amf0.ReadAny(stream); err != nil {
+		return err
+	}
+
+	for !stream.Empty() {
+		arg, err := amf0.ReadAny(stream)
+		if err != nil {
+			return err
+		}
+		this.Arguments = append(this.Arguments, arg)
+	}
+	return nil
+}
+
+func (this *SrsCallPacket) Encode(stream *utils.SrsStream) error {
+	_ = this.CommandName.Encode(stream)
+	_ = this.TransactionId.Encode(stream)
+	_ = this.CommandObject.Encode(stream)
+	for i := 0; i < len(this.Arguments); i++ {
+		_ = this.Arguments[i].Encode(stream)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package packet
+
+import (
+	"go_srs/srs/global"
+	"go_srs/srs/protocol/amf0"
+	"go_srs/srs/utils"
+)
+
+/**
+ * the response of SrsCallPacket, the command name is _result or _error.
+ * _result(transactionId, commandObject, response)
+ */
+type SrsCallResPacket struct {
+	CommandName   amf0.SrsAmf0String
+	TransactionId amf0.SrsAmf0Number
+	// the command object, generally null.
+	CommandObject amf0.ISrsAmf0Any
+	// the response of call, nil when no response.
+	Response amf0.ISrsAmf0Any
+}
+
+func NewSrsCallResPacket(transactionId float64) *SrsCallResPacket {
+	return &SrsCallResPacket{
+		CommandName:   amf0.SrsAmf0String{Value: amf0.SrsAmf0Utf8{Value: amf0.RTMP_AMF0_COMMAND_RESULT}},
+		TransactionId: amf0.SrsAmf0Number{Value: transactionId},
+		CommandObject: amf0.NewSrsAmf0Null(),
+	}
+}
+
+func (s *SrsCallResPacket) GetMessageType() int8 {
+	return global.RTMP_MSG_AMF0CommandMessage
+}
+
+func (s *SrsCallResPacket) GetPreferCid() int32 {
+	return global.RTMP_CID_OverConnection
+}
+
+/**
+ * whether the call failed, the command name is _error.
+ */
+func (this *SrsCallResPacket) IsError() bool {
+	return this.CommandName.Value.Value == amf0.RTMP_AMF0_COMMAND_ERROR
+}
+
+func (this *SrsCallResPacket) Decode(stream *utils.SrsStream) error {
+	var err error
+	if err = this.TransactionId.Decode(stream); err != nil {
+		return err
+	}
+
+	if this.CommandObject, err = amf0.ReadAny(stream); err != nil {
+		return err
+	}
+
+	if !stream.Empty() {
+		if this.Response, err = amf0.ReadAny(stream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *SrsCallResPacket) Encode(stream *utils.SrsStream) error {
+	_ = this.CommandName.Encode(stream)
+	_ = this.TransactionId.Encode(stream)
+	_ = this.CommandObject.Encode(stream)
+	if this.Response != nil {
+		_ = this.Response.Encode(stream)
+	}
+	return nil
+}
@@ -32,7 +32,7 @@ import (
 type SrsCloseStreamPacket struct {
 	CommandName   amf0.SrsAmf0String
 	TransactionId amf0.SrsAmf0Number
-	NullObj       *amf0.SrsAmf0Object
+	NullObj       amf0.SrsAmf0Null
 }
 
 func NewSrsCloseStreamPacket() *SrsCloseStreamPacket {
@@ -66,5 +66,8 @@ func (this *SrsCloseStreamPacket) Decode(stream *utils.SrsStream) error {
 }
 
 func (this *SrsCloseStreamPacket) Encode(stream *utils.SrsStream) error {
+	_ = this.CommandName.Encode(stream)
+	_ = this.TransactionId.Encode(stream)
+	_ = this.NullObj.Encode(stream)
 	return nil
 }
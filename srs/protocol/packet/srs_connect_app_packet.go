@@ -37,6 +37,23 @@ type SrsConnectAppPacket struct {
 	Args          *amf0.SrsAmf0Object
 }
 
+/**
+ * the typed properties of connect command object.
+ */
+type SrsConnectAppCommandObj struct {
+	App            string  `amf0:"app"`
+	FlashVer       string  `amf0:"flashVer,omitempty"`
+	SwfUrl         string  `amf0:"swfUrl,omitempty"`
+	TcUrl          string  `amf0:"tcUrl"`
+	Fpad           bool    `amf0:"fpad"`
+	Capabilities   float64 `amf0:"capabilities"`
+	AudioCodecs    float64 `amf0:"audioCodecs"`
+	VideoCodecs    float64 `amf0:"videoCodecs"`
+	VideoFunction  float64 `amf0:"videoFunction"`
+	PageUrl        string  `amf0:"pageUrl,omitempty"`
+	ObjectEncoding float64 `amf0:"objectEncoding"`
+}
+
 func NewSrsConnectAppPacket() *SrsConnectAppPacket {
 	return &SrsConnectAppPacket{
 		CommandName:   amf0.SrsAmf0String{Value: amf0.SrsAmf0Utf8{Value: amf0.RTMP_AMF0_COMMAND_CONNECT}},
@@ -71,11 +88,24 @@ func (this *SrsConnectAppPacket) Decode(stream *utils.SrsStream) error {
 		return err
 	}
 
+	// the optional args, any amf0 type is allowed, but only object is used.
 	if !stream.Empty() {
-		err = this.Args.Decode(stream)
+		marker, err := stream.PeekByte()
 		if err != nil {
 			return err
 		}
+
+		v := amf0.GenerateSrsAmf0Any(marker)
+		if v == nil {
+			return errors.New("amf0 decode connect args failed, unsupported marker")
+		}
+		if err = v.Decode(stream); err != nil {
+			return err
+		}
+
+		if obj, ok := v.(*amf0.SrsAmf0Object); ok {
+			this.Args = obj
+		}
 	}
 
 	return nil
@@ -90,3 +120,14 @@ func (this *SrsConnectAppPacket) Encode(stream *utils.SrsStream) error {
 	}
 	return nil
 }
+
+/**
+ * get the typed command object, the unknown properties are ignored,
+ * and the properties in unexpected type are skipped and returned as amf0.SrsAmf0FieldErrors,
+ * for example, some clients send the objectEncoding in string.
+ */
+func (this *SrsConnectAppPacket) GetCommandObj() (*SrsConnectAppCommandObj, error) {
+	obj := &SrsConnectAppCommandObj{}
+	err := amf0.UnmarshalAnyLenient(this.CommandObj, obj)
+	return obj, err
+}
@@ -65,6 +65,26 @@ func (this *SrsConnectAppResPacket) Decode(stream *utils.SrsStream) error {
 		return err
 	}
 
+	// for RED5(1.0.6), the props is NULL, we must ignore it.
+	// @see https://github.com/ossrs/srs/issues/418
+	if !stream.Empty() {
+		marker, err := stream.PeekByte()
+		if err != nil {
+			return err
+		}
+
+		if marker == amf0.RTMP_AMF0_Object {
+			if err = this.Props.Decode(stream); err != nil {
+				return err
+			}
+		} else if p := amf0.GenerateSrsAmf0Any(marker); p != nil {
+			// ignore when props is not amf0 object.
+			if err = p.Decode(stream); err != nil {
+				return err
+			}
+		}
+	}
+
 	if err = this.Info.Decode(stream); err != nil {
 		return err
 	}
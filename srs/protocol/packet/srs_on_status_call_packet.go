@@ -31,7 +31,7 @@ import (
 type SrsOnStatusCallPacket struct {
 	CommandName   amf0.SrsAmf0String
 	TransactionId amf0.SrsAmf0Number
-	NullObj       amf0.SrsAmf0Object
+	NullObj       amf0.SrsAmf0Null
 	Data          *amf0.SrsAmf0Object
 }
 
@@ -51,7 +51,18 @@ func (this *SrsOnStatusCallPacket) GetPreferCid() int32 {
 	return global.RTMP_CID_OverStream
 }
 
-func (p *SrsOnStatusCallPacket) Decode(stream *utils.SrsStream) error {
+func (this *SrsOnStatusCallPacket) Decode(stream *utils.SrsStream) error {
+	if err := this.TransactionId.Decode(stream); err != nil {
+		return err
+	}
+
+	if err := this.NullObj.Decode(stream); err != nil {
+		return err
+	}
+
+	if err := this.Data.Decode(stream); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -71,6 +71,16 @@ func (this *SrsOnMetaDataPacket) Decode(stream *utils.SrsStream) error {
 		{
 			this.MetaData = amf0.GenerateSrsAmf0Any(marker)
 		}
+	case amf0.RTMP_AMF0_AVMplusObject:
+		{
+			// the metadata in amf3, convert to amf0 object.
+			var avm amf0.SrsAmf0AVMplusObject
+			if err = avm.Decode(stream); err != nil {
+				return err
+			}
+			this.MetaData = avm.ToAmf0()
+			return nil
+		}
 	}
 
 	if this.MetaData != nil {
@@ -100,6 +110,19 @@ func (this *SrsOnMetaDataPacket) Set(name string, value interface{}) error {
 	return nil
 }
 
+func (this *SrsOnMetaDataPacket) Remove(name string) {
+	switch this.MetaData.(type) {
+	case *amf0.SrsAmf0Object:
+		{
+			this.MetaData.(*amf0.SrsAmf0Object).Remove(name)
+		}
+	case *amf0.SrsAmf0EcmaArray:
+		{
+			this.MetaData.(*amf0.SrsAmf0EcmaArray).Remove(name)
+		}
+	}
+}
+
 func (this *SrsOnMetaDataPacket) Get(name string, value interface{}) error {
 	if this.MetaData == nil {
 		return errors.New("metadata is nil")
@@ -123,3 +146,30 @@ func (this *SrsOnMetaDataPacket) Encode(stream *utils.SrsStream) error {
 	_ = this.MetaData.Encode(stream)
 	return nil
 }
+
+/**
+ * unmarshal the metadata to typed value, for example, a struct with amf0 tags.
+ */
+func (this *SrsOnMetaDataPacket) Unmarshal(v interface{}) error {
+	if this.MetaData == nil {
+		return errors.New("metadata is nil")
+	}
+	return amf0.UnmarshalAny(this.MetaData, v)
+}
+
+/**
+ * marshal the typed value to metadata, the struct is encoded as object and map as ecma array.
+ */
+func (this *SrsOnMetaDataPacket) Marshal(v interface{}) error {
+	metaData, err := amf0.MarshalAny(v)
+	if err != nil {
+		return err
+	}
+
+	switch metaData.(type) {
+	case *amf0.SrsAmf0Object, *amf0.SrsAmf0EcmaArray:
+		this.MetaData = metaData
+		return nil
+	}
+	return errors.New("metadata must be object or ecma array")
+}
@@ -69,12 +69,24 @@ func (this *SrsPlayPacket) Decode(stream *utils.SrsStream) error {
 		return err
 	}
 
-	if len(this.StreamName.GetValue().(string)) > 0 {
+	// the start, duration and reset are optional.
+	if !stream.Empty() {
 		if err := this.Start.Decode(stream); err != nil {
 			return err
 		}
 	}
-	//todo fix this
+
+	if !stream.Empty() {
+		if err := this.Duration.Decode(stream); err != nil {
+			return err
+		}
+	}
+
+	if !stream.Empty() {
+		if err := this.Reset.Decode(stream); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
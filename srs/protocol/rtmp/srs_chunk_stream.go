@@ -22,6 +22,10 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 */
 package rtmp
 
+import (
+	"go_srs/srs/global"
+)
+
 const (
 	RTMP_FMT_TYPE0 = 0
 	RTMP_FMT_TYPE1 = 1
@@ -64,3 +68,95 @@ func NewSrsChunkStream(cid_ int32) *SrsChunkStream {
 	}
 	return s
 }
+
+/**
+ * the chunk stream for sending, keep the header of previous message,
+ * to compress the message header by fmt1/fmt2/fmt3 as the peer decodes it.
+ */
+type SrsChunkSendStream struct {
+	Cid int32
+	/**
+	 * the header of previous message, the timestampDelta is the value
+	 * written in the message header, that is, the timestamp for fmt0.
+	 */
+	Header SrsMessageHeader
+	/**
+	 * whether the chunk message header has extended timestamp.
+	 */
+	ExtendedTimestamp bool
+
+	MsgCount int32
+}
+
+func NewSrsChunkSendStream(cid int32) *SrsChunkSendStream {
+	return &SrsChunkSendStream{
+		Cid: cid,
+	}
+}
+
+/**
+ * choose the fmt for the first chunk of message, and update the state.
+ * @return the fmt and the timestamp(fmt0) or timestamp delta(fmt1/fmt2) to write.
+ * @remark, use fmt0 when the stream id changed, the timestamp jumps back,
+ *      or the delta needs extended timestamp, to keep the peer simple.
+ */
+func (this *SrsChunkSendStream) prepare(mh *SrsMessageHeader) (byte, int64) {
+	var format byte = RTMP_FMT_TYPE0
+	value := mh.timestamp
+
+	if this.MsgCount > 0 && mh.streamId == this.Header.streamId && mh.timestamp >= this.Header.timestamp {
+		delta := mh.timestamp - this.Header.timestamp
+		if delta < global.RTMP_EXTENDED_TIMESTAMP {
+			value = delta
+			if mh.messageType != this.Header.messageType || mh.payloadLength != this.Header.payloadLength {
+				format = RTMP_FMT_TYPE1
+			} else if this.ExtendedTimestamp || delta != int64(this.Header.timestampDelta) {
+				format = RTMP_FMT_TYPE2
+			} else {
+				// the peer use the previous delta for the fmt3 first chunk.
+				format = RTMP_FMT_TYPE3
+			}
+		}
+	}
+
+	this.Header = *mh
+	this.Header.timestampDelta = int32(value)
+	if format != RTMP_FMT_TYPE3 {
+		this.ExtendedTimestamp = value >= global.RTMP_EXTENDED_TIMESTAMP
+	}
+	this.MsgCount++
+	return format, value
+}
+
+/**
+ * encode the chunk header of the first chunk of message.
+ */
+func (this *SrsChunkSendStream) ChunkHeaderFirst(mh *SrsMessageHeader) []byte {
+	format, value := this.prepare(mh)
+	if format == RTMP_FMT_TYPE3 {
+		return this.ChunkHeaderContinue()
+	}
+	return srs_chunk_header(format, this.Cid, value, mh.payloadLength, mh.messageType, mh.streamId)
+}
+
+/**
+ * the extended timestamp of the fmt3 chunk, -1 when no extended timestamp.
+ */
+func (this *SrsChunkSendStream) continueTimestamp() int64 {
+	if this.ExtendedTimestamp {
+		return int64(this.Header.timestampDelta)
+	}
+	return -1
+}
+
+/**
+ * encode the chunk header of the continue chunk, always fmt3.
+ */
+func (this *SrsChunkSendStream) ChunkHeaderContinue() []byte {
+	data := srs_chunk_basic_header(make([]byte, 0, SRS_CONSTS_RTMP_MAX_FMT3_HEADER_SIZE), RTMP_FMT_TYPE3, this.Cid)
+	// the fmt3 chunk has the same extended timestamp with the previous header.
+	if this.ExtendedTimestamp {
+		data = srs_chunk_extended_timestamp(data, int64(this.Header.timestampDelta))
+	}
+	return data
+}
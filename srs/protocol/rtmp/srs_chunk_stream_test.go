@@ -0,0 +1,224 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package rtmp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"go_srs/srs/protocol/skt"
+)
+
+type chunkTestMessage struct {
+	cid         int32
+	messageType int8
+	timestamp   int64
+	size        int
+	streamId    int32
+}
+
+func (this *chunkTestMessage) header() SrsMessageHeader {
+	var mh SrsMessageHeader
+	mh.Initialize(this.messageType, int32(this.size), this.timestamp, this.streamId)
+	mh.perferCid = this.cid
+	return mh
+}
+
+func (this *chunkTestMessage) shared(fill byte) *SrsSharedPtrMessage {
+	msg := NewSrsRtmpMessage()
+	msg.header = this.header()
+	msg.payload = bytes.Repeat([]byte{fill}, this.size)
+	return NewSrsSharedPtrMessage(msg)
+}
+
+func TestChunkSendStreamPrepare(t *testing.T) {
+	cases := []struct {
+		msg    chunkTestMessage
+		format byte
+		value  int64
+		header []byte
+	}{
+		// the first message of chunk stream is always fmt0.
+		{chunkTestMessage{4, 8, 20, 10, 1}, RTMP_FMT_TYPE0, 20, []byte{0x04, 0x00, 0x00, 0x14, 0x00, 0x00, 0x0a, 0x08, 0x01, 0x00, 0x00, 0x00}},
+		// same type, length and delta as previous fmt0(delta is the timestamp), fmt3.
+		{chunkTestMessage{4, 8, 40, 10, 1}, RTMP_FMT_TYPE3, 20, []byte{0xc4}},
+		// delta changed, fmt2.
+		{chunkTestMessage{4, 8, 50, 10, 1}, RTMP_FMT_TYPE2, 10, []byte{0x84, 0x00, 0x00, 0x0a}},
+		// same delta, fmt3.
+		{chunkTestMessage{4, 8, 60, 10, 1}, RTMP_FMT_TYPE3, 10, []byte{0xc4}},
+		// length changed, fmt1.
+		{chunkTestMessage{4, 8, 80, 12, 1}, RTMP_FMT_TYPE1, 20, []byte{0x44, 0x00, 0x00, 0x14, 0x00, 0x00, 0x0c, 0x08}},
+		// type changed, fmt1.
+		{chunkTestMessage{4, 9, 90, 12, 1}, RTMP_FMT_TYPE1, 10, []byte{0x44, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x0c, 0x09}},
+		// timestamp jumps back, fmt0.
+		{chunkTestMessage{4, 9, 70, 12, 1}, RTMP_FMT_TYPE0, 70, []byte{0x04, 0x00, 0x00, 0x46, 0x00, 0x00, 0x0c, 0x09, 0x01, 0x00, 0x00, 0x00}},
+		// stream id changed, fmt0.
+		{chunkTestMessage{4, 9, 80, 12, 2}, RTMP_FMT_TYPE0, 80, []byte{0x04, 0x00, 0x00, 0x50, 0x00, 0x00, 0x0c, 0x09, 0x02, 0x00, 0x00, 0x00}},
+		// the delta needs extended timestamp, fmt0.
+		{chunkTestMessage{4, 9, 0x1000050, 12, 2}, RTMP_FMT_TYPE0, 0x1000050, []byte{0x04, 0xff, 0xff, 0xff, 0x00, 0x00, 0x0c, 0x09, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x50}},
+		// the previous header has extended timestamp, never fmt3 even the delta is the same.
+		{chunkTestMessage{4, 9, 0x1000060, 12, 2}, RTMP_FMT_TYPE2, 0x10, []byte{0x84, 0x00, 0x00, 0x10}},
+		{chunkTestMessage{4, 9, 0x1000070, 12, 2}, RTMP_FMT_TYPE3, 0x10, []byte{0xc4}},
+	}
+
+	prepared := NewSrsChunkSendStream(4)
+	encoded := NewSrsChunkSendStream(4)
+	for i, c := range cases {
+		mh := c.msg.header()
+		if format, value := prepared.prepare(&mh); format != c.format || value != c.value {
+			t.Fatalf("case %d: prepare got fmt%d %d, expect fmt%d %d", i, format, value, c.format, c.value)
+		}
+
+		mh = c.msg.header()
+		if b := encoded.ChunkHeaderFirst(&mh); !bytes.Equal(b, c.header) {
+			t.Fatalf("case %d: header got % x, expect % x", i, b, c.header)
+		}
+	}
+}
+
+func TestChunkSendStreamExtendedTimestamp(t *testing.T) {
+	cs := NewSrsChunkSendStream(6)
+
+	// the timestamp equals to 0xffffff must use the extended timestamp.
+	mh := (&chunkTestMessage{6, 9, 0xffffff, 200, 1}).header()
+	if b := cs.ChunkHeaderFirst(&mh); !bytes.Equal(b, []byte{0x06, 0xff, 0xff, 0xff, 0x00, 0x00, 0xc8, 0x09, 0x01, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff}) {
+		t.Fatalf("fmt0 got % x", b)
+	}
+	// the fmt3 continue chunk carries the same extended timestamp.
+	if b := cs.ChunkHeaderContinue(); !bytes.Equal(b, []byte{0xc6, 0x00, 0xff, 0xff, 0xff}) {
+		t.Fatalf("fmt3 got % x", b)
+	}
+
+	// the small delta after extended timestamp, no extended timestamp for continue chunks.
+	mh = (&chunkTestMessage{6, 9, 0x1000009, 200, 1}).header()
+	if b := cs.ChunkHeaderFirst(&mh); !bytes.Equal(b, []byte{0x86, 0x00, 0x00, 0x0a}) {
+		t.Fatalf("fmt2 got % x", b)
+	}
+	if b := cs.ChunkHeaderContinue(); !bytes.Equal(b, []byte{0xc6}) {
+		t.Fatalf("fmt3 got % x", b)
+	}
+}
+
+/**
+ * the chunks of messages over the default chunk size 128,
+ * each entry is the header, the message of payload and the payload size.
+ */
+func TestSendMessagesChunks(t *testing.T) {
+	msgs := []chunkTestMessage{
+		{4, 8, 0x1000000, 130, 1},
+		{4, 8, 0x1000010, 130, 1},
+		{4, 8, 0x1000020, 130, 1},
+		{5, 9, 40, 300, 1},
+	}
+	chunks := []struct {
+		header  []byte
+		message int
+		size    int
+	}{
+		// fmt0 with extended timestamp, and the fmt3 continue chunk with extended timestamp.
+		{[]byte{0x04, 0xff, 0xff, 0xff, 0x00, 0x00, 0x82, 0x08, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}, 0, 128},
+		{[]byte{0xc4, 0x01, 0x00, 0x00, 0x00}, 0, 2},
+		// fmt2 for delta, the fmt3 continue chunk without extended timestamp.
+		{[]byte{0x84, 0x00, 0x00, 0x10}, 1, 128},
+		{[]byte{0xc4}, 1, 2},
+		// fmt3 for the same delta.
+		{[]byte{0xc4}, 2, 128},
+		{[]byte{0xc4}, 2, 2},
+		// fmt0 for the new chunk stream, three chunks.
+		{[]byte{0x05, 0x00, 0x00, 0x28, 0x00, 0x01, 0x2c, 0x09, 0x01, 0x00, 0x00, 0x00}, 3, 128},
+		{[]byte{0xc5}, 3, 128},
+		{[]byte{0xc5}, 3, 44},
+	}
+
+	var expect []byte
+	for _, c := range chunks {
+		expect = append(expect, c.header...)
+		expect = append(expect, bytes.Repeat([]byte{byte(c.message)}, c.size)...)
+	}
+
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+	go func() {
+		defer c1.Close()
+		p := NewSrsProtocol(skt.NewSrsIOReadWriter(c1))
+		var shared []*SrsSharedPtrMessage
+		for i := range msgs {
+			shared = append(shared, msgs[i].shared(byte(i)))
+		}
+		p.SendMessages(shared, 1)
+	}()
+
+	b, err := ioutil.ReadAll(c2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, expect) {
+		t.Fatalf("chunks got\n% x\nexpect\n% x", b, expect)
+	}
+}
+
+func TestSendMessagesRecvMessage(t *testing.T) {
+	msgs := []chunkTestMessage{
+		{7, 8, 0, 10, 1}, {7, 8, 23, 10, 1}, {7, 8, 46, 10, 1}, {7, 8, 69, 11, 1}, {7, 8, 92, 11, 1},
+		{6, 9, 0, 300, 1}, {6, 9, 40, 200, 1}, {6, 9, 80, 200, 1}, {6, 9, 120, 200, 1},
+		{6, 9, 0xfffff0, 500, 1}, {6, 9, 0xffffff, 500, 1}, {6, 9, 0x1000010, 500, 1}, {6, 9, 0x1000030, 500, 1}, {6, 9, 0x1000050, 500, 1},
+		{6, 9, 0x1000050, 500, 2}, {6, 9, 10, 500, 2},
+		{100, 8, 5, 300, 1}, {100, 8, 6, 300, 1}, {400, 8, 5, 300, 1}, {400, 8, 0x2000000, 300, 1}, {400, 8, 0x2000001, 300, 1},
+	}
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	go func() {
+		cli := NewSrsProtocol(skt.NewSrsIOReadWriter(c1))
+		// send the messages of the same stream in one merged write.
+		for i := 0; i < len(msgs); {
+			var shared []*SrsSharedPtrMessage
+			for j := i; j < i+3 && j < len(msgs) && msgs[j].streamId == msgs[i].streamId; j++ {
+				shared = append(shared, msgs[j].shared(byte(j)))
+			}
+			if err := cli.SendMessages(shared, int(msgs[i].streamId)); err != nil {
+				return
+			}
+			i += len(shared)
+		}
+	}()
+
+	srv := NewSrsProtocol(skt.NewSrsIOReadWriter(c2))
+	for i, c := range msgs {
+		msg, err := srv.RecvMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mh := msg.header
+		if mh.timestamp != c.timestamp || mh.messageType != c.messageType || int(mh.payloadLength) != c.size || mh.streamId != c.streamId || mh.perferCid != c.cid {
+			t.Fatalf("case %d: got %+v, expect %+v", i, mh, c)
+		}
+		if !bytes.Equal(msg.payload, bytes.Repeat([]byte{byte(i)}, c.size)) {
+			t.Fatalf("case %d: payload mismatch", i)
+		}
+	}
+}
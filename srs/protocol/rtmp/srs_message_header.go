@@ -134,3 +134,81 @@ func (this *SrsMessageHeader) SetLength(len int32) {
 func (this *SrsMessageHeader) IsAV() bool {
 	return this.IsVideo() || this.IsAudio()
 }
+
+func (this *SrsMessageHeader) GetMessageType() int8 {
+	return this.messageType
+}
+
+func (this *SrsMessageHeader) GetStreamId() int32 {
+	return this.streamId
+}
+
+func (this *SrsMessageHeader) GetPayloadLength() int32 {
+	return this.payloadLength
+}
+
+func (this *SrsMessageHeader) GetPerferCid() int32 {
+	return this.perferCid
+}
+
+/**
+ * create a amf0 script header, set the size and stream_id.
+ */
+func (this *SrsMessageHeader) InitializeAmf0Script(size int32, streamId int32) {
+	this.messageType = global.RTMP_MSG_AMF0DataMessage
+	this.payloadLength = size
+	this.timestampDelta = 0
+	this.timestamp = 0
+	this.streamId = streamId
+	// amf0 script use connection2 chunk-id
+	this.perferCid = global.RTMP_CID_OverConnection2
+}
+
+/**
+ * create a audio header, set the size, timestamp and stream_id.
+ */
+func (this *SrsMessageHeader) InitializeAudio(size int32, timestamp int64, streamId int32) {
+	this.messageType = global.RTMP_MSG_AudioMessage
+	this.payloadLength = size
+	this.timestampDelta = int32(timestamp)
+	this.timestamp = timestamp
+	this.streamId = streamId
+	// audio chunk-id
+	this.perferCid = global.RTMP_CID_Audio
+}
+
+/**
+ * create a video header, set the size, timestamp and stream_id.
+ */
+func (this *SrsMessageHeader) InitializeVideo(size int32, timestamp int64, streamId int32) {
+	this.messageType = global.RTMP_MSG_VideoMessage
+	this.payloadLength = size
+	this.timestampDelta = int32(timestamp)
+	this.timestamp = timestamp
+	this.streamId = streamId
+	// video chunk-id
+	this.perferCid = global.RTMP_CID_Video
+}
+
+/**
+ * create a header of specified message type, set the size, timestamp and stream_id,
+ * the perfer cid is set by the message type.
+ */
+func (this *SrsMessageHeader) Initialize(messageType int8, size int32, timestamp int64, streamId int32) {
+	this.messageType = messageType
+	this.payloadLength = size
+	this.timestampDelta = int32(timestamp)
+	this.timestamp = timestamp
+	this.streamId = streamId
+
+	switch messageType {
+	case global.RTMP_MSG_AudioMessage:
+		this.perferCid = global.RTMP_CID_Audio
+	case global.RTMP_MSG_VideoMessage:
+		this.perferCid = global.RTMP_CID_Video
+	case global.RTMP_MSG_AMF0DataMessage, global.RTMP_MSG_AMF3DataMessage:
+		this.perferCid = global.RTMP_CID_OverConnection2
+	default:
+		this.perferCid = global.RTMP_CID_OverStream
+	}
+}
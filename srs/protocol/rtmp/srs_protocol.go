@@ -25,7 +25,7 @@ package rtmp
 import (
 	_ "bufio"
 	"bytes"
-	_ "context"
+	"context"
 	"encoding/binary"
 	"errors"
 	"go_srs/srs/global"
@@ -34,26 +34,62 @@ import (
 	"go_srs/srs/protocol/skt"
 	"go_srs/srs/utils"
 	_ "log"
+	"net"
 	"reflect"
+	"sync"
 	"time"
 )
 
 const SRS_PERF_CHUNK_STREAM_CACHE = 16
 
+/**
+ * the default timeout to recv message, for handshake, connect and negotiation.
+ */
+const SRS_CONSTS_RTMP_TIMEOUT = 30 * time.Second
+
+/**
+ * the acknowledgement window and counters.
+ * for in, the window is set by peer, we send acknowledgement when received bytes exceed it.
+ * for out, the window is set by us, peer send acknowledgement to us.
+ */
 type AckWindowSize struct {
-	Window         uint32
-	RecvBytes      int64
+	Window uint32
+	// the io bytes when the last acknowledgement is sent, only for in.
+	RecvBytes int64
+	// the sequence number of the last acknowledgement, sent for in and received for out.
 	SequenceNumber uint32
+	// the count of acknowledgements, sent for in and received for out.
+	NbAcks int64
 }
 
 type SrsProtocol struct {
 	io           *skt.SrsIOReadWriter
 	chunkCache   []*SrsChunkStream
 	chunkStreams map[int32]*SrsChunkStream
-	inChunkSize  int32
-	OutChunkSize int32
-	OutAckSize   AckWindowSize
+	// the chunk streams for sending, to compress the chunk header.
+	outChunkStreams map[int32]*SrsChunkSendStream
+	inChunkSize     int32
+	OutChunkSize    int32
+	// the acknowledgement state, written by the recv thread and read by others.
+	ackLock    sync.Mutex
+	inAckSize  AckWindowSize
+	outAckSize AckWindowSize
+	// the requests sent, key is transaction id, value is the command name,
+	// to decode the _result or _error of peer.
 	Requests     map[float64]string
+	requestsLock sync.Mutex
+	// the messages maybe sent in different goroutines, for example,
+	// the acknowledgement in recv thread and the media in consumer.
+	sendLock sync.Mutex
+	// the timeout for ExpectMessage.
+	recvTimeout time.Duration
+	// the timestamp of ping request is the ms since the base.
+	pingBase time.Time
+	// the ping state, written by the recv thread and read by others.
+	pingLock     sync.Mutex
+	pingSent     bool
+	rtt          time.Duration
+	lastRecvTime time.Time
 }
 
 func NewSrsProtocol(io_ *skt.SrsIOReadWriter) *SrsProtocol {
@@ -65,14 +101,27 @@ func NewSrsProtocol(io_ *skt.SrsIOReadWriter) *SrsProtocol {
 	}
 
 	return &SrsProtocol{
-		chunkStreams: make(map[int32]*SrsChunkStream),
-		chunkCache:   cache,
-		io:           io_,
-		inChunkSize:  global.SRS_CONSTS_RTMP_PROTOCOL_CHUNK_SIZE,
-		OutChunkSize: global.SRS_CONSTS_RTMP_PROTOCOL_CHUNK_SIZE,
+		chunkStreams:    make(map[int32]*SrsChunkStream),
+		outChunkStreams: make(map[int32]*SrsChunkSendStream),
+		chunkCache:      cache,
+		io:              io_,
+		inChunkSize:     global.SRS_CONSTS_RTMP_PROTOCOL_CHUNK_SIZE,
+		OutChunkSize:    global.SRS_CONSTS_RTMP_PROTOCOL_CHUNK_SIZE,
+		Requests:        make(map[float64]string),
+		recvTimeout:     SRS_CONSTS_RTMP_TIMEOUT,
+		pingBase:        time.Now(),
+		lastRecvTime:    time.Now(),
 	}
 }
 
+func (this *SrsProtocol) SetRecvTimeout(timeout time.Duration) {
+	this.recvTimeout = timeout
+}
+
+func (this *SrsProtocol) GetRecvTimeout() time.Duration {
+	return this.recvTimeout
+}
+
 var mhSizes = [4]int{11, 7, 3, 0}
 
 func (this *SrsProtocol) ReadBasicHeader() (fmt byte, cid int32, err error) {
@@ -104,7 +153,7 @@ func (this *SrsProtocol) ReadBasicHeader() (fmt byte, cid int32, err error) {
 
 		cid = 64
 		cid += (int32)(buffer3[0])
-		cid += (int32)(buffer3[1])
+		cid += (int32)(buffer3[1]) * 256
 		return
 	}
 	return
@@ -250,7 +299,7 @@ func (s *SrsProtocol) ReadMessageHeader(chunk *SrsChunkStream, format byte) (err
 				streamIdBuf[2] = buf[pos]
 				pos += 1
 				streamIdBuf[3] = buf[pos]
-				bufReader := bytes.NewBuffer(lengthBuf)
+				bufReader := bytes.NewBuffer(streamIdBuf)
 				binary.Read(bufReader, binary.LittleEndian, &chunk.Header.streamId)
 			}
 		}
@@ -263,8 +312,9 @@ func (s *SrsProtocol) ReadMessageHeader(chunk *SrsChunkStream, format byte) (err
 
 	if chunk.ExtendedTimestamp {
 		mhSize += 4
+		// peek the extended timestamp, for the continue chunk maybe not has it.
 		var buf2 []byte
-		if buf2, err = s.ReadNByte(4); err != nil {
+		if buf2, err = s.io.PeekFully(4); err != nil {
 			return
 		}
 
@@ -279,7 +329,7 @@ func (s *SrsProtocol) ReadMessageHeader(chunk *SrsChunkStream, format byte) (err
 		binary.Read(bufReader, binary.LittleEndian, &ts)
 		// always use 31bits timestamp, for some server may use 32bits extended timestamp.
 		// @see https://github.com/ossrs/srs/issues/111
-		ts &= 0x7ffffff
+		ts &= 0x7fffffff
 
 		/**
 		 * RTMP specification and ffmpeg/librtmp is false,
@@ -308,10 +358,13 @@ func (s *SrsProtocol) ReadMessageHeader(chunk *SrsChunkStream, format byte) (err
 		 * @remark, for the first chunk of message, always use the extended timestamp.
 		 */
 		if !isFirstChunkOfMsg && chunkTimestamp > 0 && chunkTimestamp != ts {
+			// no 4bytes extended timestamp in the continued chunk,
+			// the peeked bytes is the payload.
 			mhSize -= 4
-			//这里需要考虑下怎么处理
-			//no 4bytes extended timestamp in the continued chunk
 		} else {
+			if _, err = s.ReadNByte(4); err != nil {
+				return
+			}
 			chunk.Header.timestamp = (int64)(ts)
 		}
 	}
@@ -441,15 +494,57 @@ func (this *SrsProtocol) doDecodeMessage(msg *SrsRtmpMessage, stream *utils.SrsS
 		if msg.header.IsAmf3Command() && stream.Require(1) {
 			stream.Skip(1)
 		}
+		// skip the 1bytes format of amf3 data, the amf0 string marker is never 0x00.
+		if msg.header.IsAmf3Data() && stream.Require(1) {
+			if b, _ := stream.PeekByte(); b == 0x00 {
+				stream.Skip(1)
+			}
+		}
 		// amf0 command message.
 		// need to read the command name.
-		var amf0Command amf0.SrsAmf0String
-		err = amf0Command.Decode(stream)
-		if err != nil {
-			err = errors.New("srs_amf0_read_string error")
+		var command string
+		if command, err = srs_amf0_read_command_name(stream); err != nil {
 			return
 		}
-		command := amf0Command.Value.Value
+		// result/error packet
+		if command == amf0.RTMP_AMF0_COMMAND_RESULT || command == amf0.RTMP_AMF0_COMMAND_ERROR {
+			// peek the transaction id, the packet decode it again.
+			var transactionId amf0.SrsAmf0Number
+			if err = transactionId.Decode(utils.NewSrsStream(stream.PeekLeftBytes())); err != nil {
+				err = errors.New("decode AMF0/AMF3 transaction_id failed")
+				return
+			}
+
+			// find the call name, the response of unknown transaction_id, for example,
+			// the call is timeout, is decoded as the response of call which nobody waits on.
+			requestName, ok := this.fetchRequest(transactionId.Value)
+			if ok {
+				// the request is done by the response.
+				this.removeRequest(transactionId.Value)
+			}
+
+			if requestName == amf0.RTMP_AMF0_COMMAND_CONNECT {
+				pkt = packet.NewSrsConnectAppResPacket()
+				err = pkt.Decode(stream)
+				return
+			} else if requestName == amf0.RTMP_AMF0_COMMAND_CREATE_STREAM {
+				pkt = packet.NewSrsCreateStreamResPacket(0, 0)
+				err = pkt.Decode(stream)
+				return
+			} else if requestName == amf0.RTMP_AMF0_COMMAND_RELEASE_STREAM || requestName == amf0.RTMP_AMF0_COMMAND_FC_PUBLISH || requestName == amf0.RTMP_AMF0_COMMAND_UNPUBLISH {
+				pkt = packet.NewSrsFMLEStartResPacket(0)
+				err = pkt.Decode(stream)
+				return
+			}
+
+			// the response of call.
+			p := packet.NewSrsCallResPacket(0)
+			p.CommandName.Value.Value = command
+			pkt = p
+			err = pkt.Decode(stream)
+			return
+		}
+
 		// decode command object.
 		// todo other message
 		if command == amf0.RTMP_AMF0_COMMAND_CONNECT {
@@ -483,19 +578,131 @@ func (this *SrsProtocol) doDecodeMessage(msg *SrsRtmpMessage, stream *utils.SrsS
 			pkt = packet.NewSrsCloseStreamPacket()
 			err = pkt.Decode(stream)
 			return
+		} else if command == amf0.RTMP_AMF0_COMMAND_DELETE_STREAM {
+			pkt = packet.NewSrsDeleteStreamPacket()
+			err = pkt.Decode(stream)
+			return
+		} else if command == amf0.RTMP_AMF0_COMMAND_PAUSE {
+			pkt = packet.NewSrsPausePacket()
+			err = pkt.Decode(stream)
+			return
+		} else if command == amf0.RTMP_AMF0_COMMAND_SEEK {
+			pkt = packet.NewSrsSeekPacket()
+			err = pkt.Decode(stream)
+			return
+		} else if command == amf0.RTMP_AMF0_COMMAND_ON_STATUS && (msg.header.IsAmf0Command() || msg.header.IsAmf3Command()) {
+			pkt = packet.NewSrsOnStatusCallPacket()
+			err = pkt.Decode(stream)
+			return
 		} else if command == amf0.SRS_CONSTS_RTMP_SET_DATAFRAME || command == amf0.SRS_CONSTS_RTMP_ON_METADATA {
 			pkt = packet.NewSrsOnMetaDataPacket(command)
 			err = pkt.Decode(stream)
 			return
+		} else if packet.IsSrsBandwidthCommand(command) {
+			pkt = packet.NewSrsBandwidthPacket(command)
+			err = pkt.Decode(stream)
+			return
+		} else if msg.header.IsAmf0Command() || msg.header.IsAmf3Command() {
+			// the other commands are generic calls.
+			pkt = packet.NewSrsCallPacket(command)
+			err = pkt.Decode(stream)
+			return
 		}
 	} else if msg.header.IsSetChunkSize() {
 		pkt = packet.NewSrsSetChunkSizePacket()
 		err = pkt.Decode(stream)
 		return
+	} else if msg.header.IsWindowAckledgementSize() {
+		pkt = packet.NewSrsSetWindowAckSizePacket()
+		err = pkt.Decode(stream)
+		return
+	} else if msg.header.IsAckledgement() {
+		pkt = packet.NewSrsAcknowledgementPacket()
+		err = pkt.Decode(stream)
+		return
+	} else if msg.header.IsUserControlMessage() {
+		pkt = packet.NewSrsUserControlPacket()
+		err = pkt.Decode(stream)
+		return
 	}
 	return
 }
 
+/**
+ * record the request before sent, for the response maybe received in other goroutine.
+ */
+func (this *SrsProtocol) recordRequest(pkt packet.SrsPacket) {
+	this.requestsLock.Lock()
+	defer this.requestsLock.Unlock()
+	switch pkt.(type) {
+	case *packet.SrsConnectAppPacket:
+		{
+			p := pkt.(*packet.SrsConnectAppPacket)
+			this.Requests[p.TransactionId.GetValue().(float64)] = p.CommandName.GetValue().(string)
+		}
+	case *packet.SrsCreateStreamPacket:
+		{
+			p := pkt.(*packet.SrsCreateStreamPacket)
+			this.Requests[p.TransactionId.GetValue().(float64)] = p.CommandName.GetValue().(string)
+		}
+	case *packet.SrsFMLEStartPacket:
+		{
+			p := pkt.(*packet.SrsFMLEStartPacket)
+			this.Requests[p.TransactionId.GetValue().(float64)] = p.CommandName.GetValue().(string)
+		}
+	case *packet.SrsCallPacket:
+		{
+			// the call without transaction id need no response.
+			p := pkt.(*packet.SrsCallPacket)
+			if p.TransactionId.Value != 0 {
+				this.Requests[p.TransactionId.Value] = p.CommandName.Value.Value
+			}
+		}
+	}
+}
+
+func (this *SrsProtocol) fetchRequest(transactionId float64) (string, bool) {
+	this.requestsLock.Lock()
+	defer this.requestsLock.Unlock()
+	name, ok := this.Requests[transactionId]
+	return name, ok
+}
+
+func (this *SrsProtocol) removeRequest(transactionId float64) {
+	this.requestsLock.Lock()
+	defer this.requestsLock.Unlock()
+	delete(this.Requests, transactionId)
+}
+
+/**
+ * read the command name, which is amf0 string,
+ * or the amf3 string switched by AVM+ marker in amf3 command.
+ */
+func srs_amf0_read_command_name(stream *utils.SrsStream) (string, error) {
+	marker, err := stream.PeekByte()
+	if err != nil {
+		return "", errors.New("srs_amf0_read_string error")
+	}
+
+	if marker == amf0.RTMP_AMF0_AVMplusObject {
+		var avm amf0.SrsAmf0AVMplusObject
+		if err = avm.Decode(stream); err != nil {
+			return "", errors.New("srs_amf3_read_string error")
+		}
+		command, ok := avm.GetValue().(string)
+		if !ok {
+			return "", errors.New("srs_amf3_read_string error, not string")
+		}
+		return command, nil
+	}
+
+	var amf0Command amf0.SrsAmf0String
+	if err = amf0Command.Decode(stream); err != nil {
+		return "", errors.New("srs_amf0_read_string error")
+	}
+	return amf0Command.Value.Value, nil
+}
+
 func (s *SrsProtocol) DecodeMessage(msg *SrsRtmpMessage) (packet packet.SrsPacket, err error) {
 	stream := utils.NewSrsStream(msg.payload)
 	if stream == nil {
@@ -511,65 +718,239 @@ func (s *SrsProtocol) DecodeMessage(msg *SrsRtmpMessage) (packet packet.SrsPacke
 }
 
 func (s *SrsProtocol) OnRecvRtmpMessage(msg *SrsRtmpMessage) error {
-	var pkt packet.SrsPacket
-	if msg.header.messageType == global.RTMP_MSG_SetChunkSize || msg.header.messageType == global.RTMP_MSG_UserControlMessage || msg.header.messageType == global.RTMP_MSG_WindowAcknowledgementSize {
-		var err error
-		pkt, err = s.DecodeMessage(msg)
-		if err != nil {
-			return errors.New("decode packet from message payload failed.")
-		}
-		_ = pkt
+	s.pingLock.Lock()
+	s.lastRecvTime = time.Now()
+	s.pingLock.Unlock()
+
+	// try to response acknowledgement
+	if err := s.responseAcknowledgementMessage(); err != nil {
+		return err
 	}
 
-	if msg.header.messageType == global.RTMP_MSG_SetChunkSize {
+	switch msg.header.messageType {
+	case global.RTMP_MSG_SetChunkSize, global.RTMP_MSG_UserControlMessage, global.RTMP_MSG_WindowAcknowledgementSize, global.RTMP_MSG_Acknowledgement:
+	default:
+		return nil
+	}
+
+	pkt, err := s.DecodeMessage(msg)
+	if err != nil {
+		return errors.New("decode packet from message payload failed.")
+	}
+
+	switch p := pkt.(type) {
+	case *packet.SrsSetChunkSizePacket:
 		//参数检查
-		s.inChunkSize = pkt.(*packet.SrsSetChunkSizePacket).ChunkSize
+		s.inChunkSize = p.ChunkSize
+	case *packet.SrsSetWindowAckSizePacket:
+		if p.AckowledgementWindowSize > 0 {
+			s.ackLock.Lock()
+			s.inAckSize.Window = uint32(p.AckowledgementWindowSize)
+			s.ackLock.Unlock()
+		}
+	case *packet.SrsAcknowledgementPacket:
+		s.ackLock.Lock()
+		s.outAckSize.SequenceNumber = p.SequenceNumber
+		s.outAckSize.NbAcks++
+		s.ackLock.Unlock()
+	case *packet.SrsUserControlPacket:
+		if p.EventType == packet.SrcPCUCPingRequest {
+			return s.responsePingMessage(p.EventData)
+		}
+		if p.EventType == packet.SrcPCUCPingResponse {
+			s.onPingResponse(p.EventData)
+		}
 	}
 
 	return nil
 }
 
+/**
+ * send the ping request to peer, the event data is the local timestamp in ms,
+ * the peer responses it in the ping response, to calc the round trip time.
+ */
+func (s *SrsProtocol) SendPingRequest() error {
+	pkt := packet.NewSrsUserControlPacket()
+	pkt.EventType = packet.SrcPCUCPingRequest
+	pkt.EventData = int32(time.Since(s.pingBase) / time.Millisecond)
+
+	s.pingLock.Lock()
+	s.pingSent = true
+	s.pingLock.Unlock()
+
+	return s.SendPacket(pkt, 0)
+}
+
+func (s *SrsProtocol) responsePingMessage(timestamp int32) error {
+	pkt := packet.NewSrsUserControlPacket()
+	pkt.EventType = packet.SrcPCUCPingResponse
+	pkt.EventData = timestamp
+	return s.SendPacket(pkt, 0)
+}
+
+func (s *SrsProtocol) onPingResponse(timestamp int32) {
+	now := int32(time.Since(s.pingBase) / time.Millisecond)
+
+	s.pingLock.Lock()
+	defer s.pingLock.Unlock()
+	// ignore the response of the ping not sent by us.
+	if !s.pingSent || timestamp < 0 || timestamp > now {
+		return
+	}
+	s.rtt = time.Duration(now-timestamp) * time.Millisecond
+}
+
+/**
+ * the round trip time of the last ping response, 0 when peer never responses.
+ */
+func (s *SrsProtocol) GetRtt() time.Duration {
+	s.pingLock.Lock()
+	defer s.pingLock.Unlock()
+	return s.rtt
+}
+
+/**
+ * the time of the last message received from peer, to detect the dead peer.
+ */
+func (s *SrsProtocol) GetLastRecvTime() time.Time {
+	s.pingLock.Lock()
+	defer s.pingLock.Unlock()
+	return s.lastRecvTime
+}
+
+/**
+ * send the acknowledgement when the received bytes exceed the window of peer.
+ */
+func (s *SrsProtocol) responseAcknowledgementMessage() error {
+	s.ackLock.Lock()
+	if s.inAckSize.Window <= 0 {
+		s.ackLock.Unlock()
+		return nil
+	}
+
+	// ignore when delta bytes not exceed half of window(ack size).
+	delta := uint32(s.io.GetRecvBytes() - s.inAckSize.RecvBytes)
+	if delta < s.inAckSize.Window/2 {
+		s.ackLock.Unlock()
+		return nil
+	}
+	s.inAckSize.RecvBytes = s.io.GetRecvBytes()
+
+	// when the sequence number overflow, reset it.
+	sequenceNumber := s.inAckSize.SequenceNumber + delta
+	if sequenceNumber > 0xf0000000 {
+		sequenceNumber = delta
+	}
+	s.inAckSize.SequenceNumber = sequenceNumber
+	s.inAckSize.NbAcks++
+	s.ackLock.Unlock()
+
+	pkt := packet.NewSrsAcknowledgementPacket()
+	pkt.SequenceNumber = sequenceNumber
+	if err := s.SendPacket(pkt, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+/**
+ * the bytes sent but not acknowledged by peer, for the out window.
+ * @remark, the sequence number of peer maybe reset when overflow, so it's not exactly.
+ */
+func (s *SrsProtocol) GetOutUnackedBytes() int64 {
+	s.ackLock.Lock()
+	defer s.ackLock.Unlock()
+	if s.outAckSize.NbAcks <= 0 {
+		return s.io.GetSendBytes()
+	}
+
+	unacked := s.io.GetSendBytes() - int64(s.outAckSize.SequenceNumber)
+	if unacked < 0 {
+		return 0
+	}
+	return unacked
+}
+
+/**
+ * the acknowledgement counters of in window, the acknowledgements sent to peer.
+ */
+func (s *SrsProtocol) GetInAckSize() AckWindowSize {
+	s.ackLock.Lock()
+	defer s.ackLock.Unlock()
+	return s.inAckSize
+}
+
+/**
+ * the acknowledgement counters of out window, the acknowledgements received from peer.
+ */
+func (s *SrsProtocol) GetOutAckSize() AckWindowSize {
+	s.ackLock.Lock()
+	defer s.ackLock.Unlock()
+	return s.outAckSize
+}
+
+func (s *SrsProtocol) GetRecvBytes() int64 {
+	return s.io.GetRecvBytes()
+}
+
+func (s *SrsProtocol) GetSendBytes() int64 {
+	return s.io.GetSendBytes()
+}
+
+/**
+ * recv a message in the caller goroutine, interrupted when ctx done.
+ * @remark the protocol is unusable when interrupted, user should close it.
+ */
+func (this *SrsProtocol) RecvMessageContext(ctx context.Context) (*SrsRtmpMessage, error) {
+	var msg *SrsRtmpMessage
+	err := this.io.ReadContext(ctx, func() (err error) {
+		msg, err = this.RecvMessage()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+/**
+ * expect a specified packet in the recv timeout, ignore other messages.
+ * @param pkt the ptr to store the packet, for example, *SrsConnectAppPacket.
+ */
 func (this *SrsProtocol) ExpectMessage(pkt packet.SrsPacket) error {
+	ctx, cancel := context.WithTimeout(context.Background(), this.recvTimeout)
+	defer cancel()
+	return this.ExpectMessageContext(ctx, pkt)
+}
+
+/**
+ * expect a specified packet until ctx done, ignore other messages.
+ */
+func (this *SrsProtocol) ExpectMessageContext(ctx context.Context, pkt packet.SrsPacket) error {
 	if reflect.TypeOf(pkt).Kind() != reflect.Ptr {
 		return errors.New("need ptr to store result")
 	}
 
-	donePkt := make(chan packet.SrsPacket)
-	//todo 这里需要修改为cancelctx
-	go func() {
+	return this.io.ReadContext(ctx, func() error {
 		for {
 			msg, err := this.RecvMessage()
 			if err != nil {
-				continue
-			}
-
-			if msg == nil {
-				continue
+				return err
 			}
 
-			p, err1 := this.DecodeMessage(msg)
-			if err1 != nil {
+			p, err := this.DecodeMessage(msg)
+			if err != nil || p == nil {
 				continue
 			}
 
 			if reflect.TypeOf(p) != reflect.TypeOf(pkt) {
 				continue
 			}
-			donePkt <- p
-			break
-		}
-	}()
 
-	var tmp_pkt packet.SrsPacket
-	for {
-		select {
-		case tmp_pkt = <-donePkt:
-			reflect.ValueOf(pkt).Elem().Set(reflect.ValueOf(tmp_pkt).Elem())
+			reflect.ValueOf(pkt).Elem().Set(reflect.ValueOf(p).Elem())
 			return nil
-		case <-time.After(time.Second * 2):
-			return errors.New("expect message timeout, type=" + reflect.TypeOf(pkt).String())
 		}
-	}
+	})
 }
 
 func (this *SrsProtocol) SendPacket(packet packet.SrsPacket, streamId int32) error {
@@ -577,10 +958,6 @@ func (this *SrsProtocol) SendPacket(packet packet.SrsPacket, streamId int32) err
 	return err
 }
 
-func (this *SrsProtocol) SendMsg(msg *SrsRtmpMessage, streamId int32) error {
-	return nil
-}
-
 func (this *SrsProtocol) doSendPacket(pkt packet.SrsPacket, streamId int32) error {
 	stream := utils.NewSrsStream([]byte{})
 	err := pkt.Encode(stream)
@@ -598,6 +975,10 @@ func (this *SrsProtocol) doSendPacket(pkt packet.SrsPacket, streamId int32) erro
 	header.streamId = streamId
 	header.perferCid = pkt.GetPreferCid()
 
+	this.recordRequest(pkt)
+
+	this.sendLock.Lock()
+	defer this.sendLock.Unlock()
 	err = this.doSimpleSend(&header, payload)
 	if err == nil {
 		return this.onSendPacket(&header, pkt)
@@ -605,37 +986,102 @@ func (this *SrsProtocol) doSendPacket(pkt packet.SrsPacket, streamId int32) erro
 	return err
 }
 
+/**
+ * get the chunk stream to send message over.
+ * @remark, the cid 0 and 1 is reserved for 2B and 3B basic header.
+ */
+func (this *SrsProtocol) getSendChunkStream(cid int32) *SrsChunkSendStream {
+	if cid < global.RTMP_CID_ProtocolControl {
+		cid = global.RTMP_CID_OverConnection
+	}
+
+	cs, ok := this.outChunkStreams[cid]
+	if !ok {
+		cs = NewSrsChunkSendStream(cid)
+		this.outChunkStreams[cid] = cs
+	}
+	return cs
+}
+
+/**
+ * send the message in chunks, the first chunk header is compressed
+ * by the previous message of the chunk stream, the continue chunks use fmt3.
+ */
 func (this *SrsProtocol) doSimpleSend(mh *SrsMessageHeader, payload []byte) error {
-	var sendedCount int = 0
-	var d []byte
-	var err error
-	firstPkt := true
+	cs := this.getSendChunkStream(mh.perferCid)
+	iovs := this.doChunks(cs, payload, nil, cs.ChunkHeaderFirst(mh), nil)
+	if _, err := this.io.WriteBuffers(iovs); err != nil {
+		return err
+	}
+	return nil
+}
+
+/**
+ * append the chunk headers and payload slices of the message to iovs,
+ * the payload is not copied, so the iovs can be written by writev.
+ * @param c0 the header of first chunk.
+ * @param c3 the header of continue chunks, nil to encode by the chunk stream.
+ */
+func (this *SrsProtocol) doChunks(cs *SrsChunkSendStream, payload []byte, iovs net.Buffers, c0 []byte, c3 []byte) net.Buffers {
+	iovs = append(iovs, c0)
+
 	leftPayload := payload
-	for len(leftPayload) > 0 {
-		if firstPkt {
-			firstPkt = false
-			d, err = srs_chunk_header_c0(mh.perferCid, int32(mh.timestamp), mh.payloadLength, mh.messageType, mh.streamId)
-			if err != nil {
-				return err
-			}
-		} else {
-			d, err = srs_chunk_header_c3(mh.perferCid, int32(mh.timestamp))
+	for {
+		payloadSize := utils.MinInt32(int32(len(leftPayload)), this.OutChunkSize)
+		iovs = append(iovs, leftPayload[:payloadSize])
+		leftPayload = leftPayload[payloadSize:]
+		if len(leftPayload) <= 0 {
+			break
 		}
 
-		payloadSize := utils.MinInt32(int32(len(leftPayload)), this.OutChunkSize) //int32(len(leftPayload))//
-		sendPayload := leftPayload[:payloadSize]
-		leftPayload = leftPayload[payloadSize:]
-		d = append(d, sendPayload...)
-		n2, err2 := this.io.Write(d)
-		if err2 != nil {
-			return err2
+		// all continue chunks of a message share the same fmt3 header.
+		if c3 == nil {
+			c3 = cs.ChunkHeaderContinue()
 		}
-		sendedCount += n2
+		iovs = append(iovs, c3)
 	}
-	return nil
+	return iovs
 }
 
-func (this *SrsProtocol) SendMessages(msgs []*SrsRtmpMessage, streamId int) error {
+/**
+ * append the chunks of the shared message to iovs,
+ * the chunk headers are cached in the shared payload, reused by all consumers.
+ */
+func (this *SrsProtocol) doSharedChunks(msg *SrsSharedPtrMessage, iovs net.Buffers) net.Buffers {
+	// use the immutable type and length of the shared payload.
+	mh := msg.ptr.header
+	mh.timestamp = msg.header.timestamp
+	mh.streamId = msg.header.streamId
+
+	cs := this.getSendChunkStream(mh.perferCid)
+	format, value := cs.prepare(&mh)
+	if format == RTMP_FMT_TYPE3 {
+		value = cs.continueTimestamp()
+	}
+
+	c0 := msg.ptr.chunkHeader(format, cs.Cid, value, mh.streamId)
+	c3 := msg.ptr.chunkHeader(RTMP_FMT_TYPE3, cs.Cid, cs.continueTimestamp(), 0)
+	return this.doChunks(cs, msg.GetPayload(), iovs, c0, c3)
+}
+
+/**
+ * send the common message, the payload is shared without copy.
+ */
+func (this *SrsProtocol) SendMsg(msg *SrsRtmpMessage, streamId int32) error {
+	shared := NewSrsSharedPtrMessage(msg)
+	defer shared.Free()
+	return this.SendMessages([]*SrsSharedPtrMessage{shared}, int(streamId))
+}
+
+/**
+ * send the messages in a single merged write,
+ * all chunks of all messages are written by writev then flushed once.
+ */
+func (this *SrsProtocol) SendMessages(msgs []*SrsSharedPtrMessage, streamId int) error {
+	this.sendLock.Lock()
+	defer this.sendLock.Unlock()
+
+	var iovs net.Buffers
 	for i := 0; i < len(msgs); i++ {
 		if msgs[i] == nil {
 			continue
@@ -645,35 +1091,17 @@ func (this *SrsProtocol) SendMessages(msgs []*SrsRtmpMessage, streamId int) erro
 			continue
 		}
 
-		msg := msgs[i]
-		leftPayload := msg.GetPayload()
-		var sendedCount int = 0
-		var d []byte
-		var err error
-		firstPkt := true
-		for len(leftPayload) > 0 {
-			if firstPkt {
-				firstPkt = false
-				d, err = srs_chunk_header_c0(msg.GetHeader().perferCid, int32(msg.GetHeader().timestamp), msg.GetHeader().payloadLength, msg.GetHeader().messageType, int32(streamId))
-				if err != nil {
-					return err
-				}
-			} else {
-				d, err = srs_chunk_header_c3(msg.GetHeader().perferCid, int32(msg.GetHeader().timestamp))
-			}
+		// the message is sent over the specified stream.
+		msgs[i].header.streamId = int32(streamId)
+		iovs = this.doSharedChunks(msgs[i], iovs)
+	}
 
-			payloadSize := utils.MinInt32(int32(len(leftPayload)), this.OutChunkSize) //int32(len(leftPayload))//
-			sendPayload := leftPayload[:payloadSize]
-			leftPayload = leftPayload[payloadSize:]
-			d = append(d, sendPayload...)
-			n2, err2 := this.io.Write(d)
-			if err2 != nil {
-				return err2
-			}
-			sendedCount += n2
-		}
+	if len(iovs) <= 0 {
 		return nil
+	}
 
+	if _, err := this.io.WriteBuffers(iovs); err != nil {
+		return err
 	}
 	return nil
 }
@@ -687,25 +1115,9 @@ func (this *SrsProtocol) onSendPacket(mh *SrsMessageHeader, pkt packet.SrsPacket
 	case global.RTMP_MSG_SetChunkSize:
 		this.OutChunkSize = pkt.(*packet.SrsSetChunkSizePacket).ChunkSize
 	case global.RTMP_MSG_WindowAcknowledgementSize:
-		this.OutAckSize.Window = uint32(pkt.(*packet.SrsSetWindowAckSizePacket).AckowledgementWindowSize)
-	case global.RTMP_MSG_AMF0CommandMessage, global.RTMP_MSG_AMF3CommandMessage:
-		switch pkt.(type) {
-		case *packet.SrsConnectAppPacket:
-			{
-				p := pkt.(*packet.SrsConnectAppPacket)
-				this.Requests[p.TransactionId.GetValue().(float64)] = p.CommandName.GetValue().(string)
-			}
-		case *packet.SrsCreateStreamPacket:
-			{
-				p := pkt.(*packet.SrsCreateStreamPacket)
-				this.Requests[p.TransactionId.GetValue().(float64)] = p.CommandName.GetValue().(string)
-			}
-		case *packet.SrsFMLEStartPacket:
-			{
-				p := pkt.(*packet.SrsFMLEStartPacket)
-				this.Requests[p.TransactionId.GetValue().(float64)] = p.CommandName.GetValue().(string)
-			}
-		}
+		this.ackLock.Lock()
+		this.outAckSize.Window = uint32(pkt.(*packet.SrsSetWindowAckSizePacket).AckowledgementWindowSize)
+		this.ackLock.Unlock()
 	case global.RTMP_MSG_VideoMessage:
 		//todo
 	case global.RTMP_MSG_AudioMessage:
@@ -714,50 +1126,65 @@ func (this *SrsProtocol) onSendPacket(mh *SrsMessageHeader, pkt packet.SrsPacket
 	return nil
 }
 
-const SRS_CONSTS_RTMP_MAX_FMT0_HEADER_SIZE = 16
+/**
+ * the max size of chunk header, 3B basic header, 11B message header
+ * and 4B extended timestamp for fmt0, 3B basic header and 4B extended timestamp for fmt3.
+ */
+const SRS_CONSTS_RTMP_MAX_FMT0_HEADER_SIZE = 18
+const SRS_CONSTS_RTMP_MAX_FMT3_HEADER_SIZE = 7
+
+/**
+ * write the basic header, 1B for cid 2-63, 2B for cid 64-319, 3B for cid 64-65599.
+ */
+func srs_chunk_basic_header(data []byte, format byte, cid int32) []byte {
+	if cid < 64 {
+		return append(data, format<<6|byte(cid))
+	} else if cid < 320 {
+		return append(data, format<<6, byte(cid-64))
+	}
+	return append(data, format<<6|0x01, byte((cid-64)&0xFF), byte((cid-64)>>8))
+}
+
+/**
+ * write the 4bytes extended timestamp, big-endian.
+ */
+func srs_chunk_extended_timestamp(data []byte, timestamp int64) []byte {
+	return append(data, byte(timestamp>>24), byte(timestamp>>16), byte(timestamp>>8), byte(timestamp))
+}
+
+/**
+ * write the chunk header of fmt0/fmt1/fmt2, the timestamp is the delta for fmt1/fmt2.
+ *   3bytes: timestamp delta,    fmt=0,1,2
+ *   3bytes: payload length,     fmt=0,1
+ *   1bytes: message type,       fmt=0,1
+ *   4bytes: stream id,          fmt=0
+ * for fmt3, only the basic header and extended timestamp.
+ */
+func srs_chunk_header(format byte, cid int32, timestamp int64, payloadLength int32, messageType int8, streamId int32) []byte {
+	data := srs_chunk_basic_header(make([]byte, 0, SRS_CONSTS_RTMP_MAX_FMT0_HEADER_SIZE), format, cid)
+	extended := timestamp >= global.RTMP_EXTENDED_TIMESTAMP
+
+	if format <= RTMP_FMT_TYPE2 {
+		// timestamp, 3bytes, big-endian
+		if extended {
+			data = append(data, 0xFF, 0xFF, 0xFF)
+		} else {
+			data = append(data, byte(timestamp>>16), byte(timestamp>>8), byte(timestamp))
+		}
+	}
+
+	if format <= RTMP_FMT_TYPE1 {
+		// message_length, 3bytes, big-endian
+		data = append(data, byte(payloadLength>>16), byte(payloadLength>>8), byte(payloadLength))
+		// message_type, 1bytes
+		data = append(data, byte(messageType))
+	}
+
+	if format == RTMP_FMT_TYPE0 {
+		// stream_id, 4bytes, little-endian
+		data = append(data, byte(streamId), byte(streamId>>8), byte(streamId>>16), byte(streamId>>24))
+	}
 
-func srs_chunk_header_c0(perferCid int32, timestamp int32, payload_length int32, message_type int8, stream_id int32) ([]byte, error) {
-	var len int32 = 0
-	// to directly set the field.
-	data := make([]byte, SRS_CONSTS_RTMP_MAX_FMT0_HEADER_SIZE)
-	// write new chunk stream header, fmt is 0
-	data[0] = byte(0x00 | (perferCid & 0x3F))
-	len += 1
-	// chunk message header, 11 bytes
-	// timestamp, 3bytes, big-endian
-	if timestamp < global.RTMP_EXTENDED_TIMESTAMP {
-		b := utils.Int32ToBytes(timestamp, binary.LittleEndian)
-		data[1] = b[2]
-		data[2] = b[1]
-		data[3] = b[0]
-	} else { //有扩展字段，则timestamp全f
-		data[1] = 0xFF
-		data[2] = 0xFF
-		data[3] = 0xFF
-	}
-	len += 3
-
-	// message_length, 3bytes, big-endian
-	b := utils.Int32ToBytes(payload_length, binary.LittleEndian)
-	data[4] = b[2]
-	data[5] = b[1]
-	data[6] = b[0]
-	len += 3
-	// message_type, 1bytes
-	data[7] = byte(message_type)
-	// log.Print("data[7]=", data[7])
-	len += 1
-	// stream_id, 4bytes, little-endian
-	b = utils.Int32ToBytes(stream_id, binary.LittleEndian)
-	data[8] = b[0]
-	data[9] = b[1]
-	data[10] = b[2]
-	data[11] = b[3]
-	len += 4
-	// for c0
-	// chunk extended timestamp header, 0 or 4 bytes, big-endian
-	//
-	// for c3:
 	// chunk extended timestamp header, 0 or 4 bytes, big-endian
 	// 6.1.3. Extended Timestamp
 	// This field is transmitted only when the normal time stamp in the
@@ -771,55 +1198,19 @@ func srs_chunk_header_c0(perferCid int32, timestamp int32, payload_length int32,
 	//        must send the extended-timestamp to flash-player.
 	// @see: ngx_rtmp_prepare_message
 	// @see: http://blog.csdn.net/win_lin/article/details/13363699
-	// TODO: FIXME: extract to outer.
-	if timestamp >= global.RTMP_EXTENDED_TIMESTAMP {
-		b = utils.Int32ToBytes(timestamp, binary.BigEndian)
-		data[12] = b[3]
-		data[13] = b[2]
-		data[14] = b[1]
-		data[15] = b[0]
-		len += 4
+	if extended {
+		data = srs_chunk_extended_timestamp(data, timestamp)
 	}
-	return data[:len], nil
+	return data
 }
 
-const SRS_CONSTS_RTMP_MAX_FMT3_HEADER_SIZE = 5
+func srs_chunk_header_c0(perferCid int32, timestamp int32, payload_length int32, message_type int8, stream_id int32) ([]byte, error) {
+	return srs_chunk_header(RTMP_FMT_TYPE0, perferCid, int64(uint32(timestamp)), payload_length, message_type, stream_id), nil
+}
 
+/**
+ * the fmt3 chunk header, with the same extended timestamp as the c0.
+ */
 func srs_chunk_header_c3(prefer_cid int32, timestamp int32) ([]byte, error) {
-	// to directly set the field.
-	var len int32 = 0
-	// to directly set the field.
-	data := make([]byte, SRS_CONSTS_RTMP_MAX_FMT3_HEADER_SIZE)
-	// write no message header chunk stream, fmt is 3
-	// @remark, if perfer_cid > 0x3F, that is, use 2B/3B chunk header,
-	// SRS will rollback to 1B chunk header.
-	data[0] = byte(0xC0 | (prefer_cid & 0x3F))
-	len++
-	// for c0
-	// chunk extended timestamp header, 0 or 4 bytes, big-endian
-	//
-	// for c3:
-	// chunk extended timestamp header, 0 or 4 bytes, big-endian
-	// 6.1.3. Extended Timestamp
-	// This field is transmitted only when the normal time stamp in the
-	// chunk message header is set to 0x00ffffff. If normal time stamp is
-	// set to any value less than 0x00ffffff, this field MUST NOT be
-	// present. This field MUST NOT be present if the timestamp field is not
-	// present. Type 3 chunks MUST NOT have this field.
-	// adobe changed for Type3 chunk:
-	//        FMLE always sendout the extended-timestamp,
-	//        must send the extended-timestamp to FMS,
-	//        must send the extended-timestamp to flash-player.
-	// @see: ngx_rtmp_prepare_message
-	// @see: http://blog.csdn.net/win_lin/article/details/13363699
-	// TODO: FIXME: extract to outer.
-	if timestamp >= global.RTMP_EXTENDED_TIMESTAMP {
-		b := utils.Int32ToBytes(timestamp, binary.BigEndian)
-		data[1] = b[3]
-		data[2] = b[2]
-		data[3] = b[1]
-		data[4] = b[0]
-		len += 4
-	}
-	return data[:len], nil
+	return srs_chunk_header(RTMP_FMT_TYPE3, prefer_cid, int64(uint32(timestamp)), 0, 0, 0), nil
 }
@@ -0,0 +1,347 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package rtmp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"go_srs/srs/global"
+	"go_srs/srs/protocol/amf0"
+	"go_srs/srs/protocol/packet"
+	"go_srs/srs/protocol/skt"
+	"net"
+	"net/url"
+	"time"
+)
+
+/**
+ * implements the client role protocol.
+ */
+type SrsRtmpClient struct {
+	io         *skt.SrsIOReadWriter
+	Protocol   *SrsProtocol
+	HandShaker *SrsComplexHandShake
+}
+
+func NewSrsRtmpClient(io *skt.SrsIOReadWriter) *SrsRtmpClient {
+	return &SrsRtmpClient{
+		io:         io,
+		Protocol:   NewSrsProtocol(io),
+		HandShaker: NewSrsComplexHandShake(io),
+	}
+}
+
+/**
+ * dial the server of tcUrl and create the client, the schema is rtmp or rtmps(rtmp over tls),
+ * for example, rtmps://live-api-s.facebook.com:443/rtmp
+ */
+func DialSrsRtmpClient(ctx context.Context, tcUrl string) (*SrsRtmpClient, error) {
+	u, err := url.Parse(tcUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	var c net.Conn
+	switch u.Scheme {
+	case "rtmp":
+		{
+			c, err = dialer.DialContext(ctx, "tcp", srs_host_port(u, global.SRS_CONSTS_RTMP_DEFAULT_PORT))
+		}
+	case "rtmps":
+		{
+			tlsDialer := &tls.Dialer{
+				NetDialer: &dialer,
+				Config:    &tls.Config{ServerName: u.Hostname()},
+			}
+			c, err = tlsDialer.DialContext(ctx, "tcp", srs_host_port(u, global.SRS_CONSTS_RTMPS_DEFAULT_PORT))
+		}
+	default:
+		{
+			return nil, errors.New("unsupported schema " + u.Scheme)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewSrsRtmpClient(skt.NewSrsIOReadWriter(c)), nil
+}
+
+func srs_host_port(u *url.URL, defaultPort string) string {
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func (this *SrsRtmpClient) Close() {
+	this.io.Close()
+}
+
+/**
+ * handshake with server, try complex, then simple handshake.
+ */
+func (this *SrsRtmpClient) HandShake() error {
+	ctx, cancel := context.WithTimeout(context.Background(), this.Protocol.GetRecvTimeout())
+	defer cancel()
+	return this.HandShakeContext(ctx)
+}
+
+func (this *SrsRtmpClient) HandShakeContext(ctx context.Context) error {
+	return this.io.ReadContext(ctx, this.HandShaker.HandShakeWithServer)
+}
+
+/**
+ * only use simple handshake
+ */
+func (this *SrsRtmpClient) SimpleHandShake() error {
+	return NewSrsSimpleHandShake(this.io).HandShakeWithServer()
+}
+
+/**
+ * connect to server app, the tcUrl is the url of the app,
+ * for example, rtmp://127.0.0.1:1935/live?vhost=srs.net
+ * @return the info of the connect response, the data of info is the server info.
+ */
+func (this *SrsRtmpClient) ConnectApp(app string, tcUrl string, pageUrl string, swfUrl string) (*amf0.SrsAmf0Object, error) {
+	// Connect(vhost, app)
+	{
+		pkt := packet.NewSrsConnectAppPacket()
+		pkt.CommandObj.Set("app", app)
+		pkt.CommandObj.Set("flashVer", "WIN 15,0,0,239")
+		if swfUrl != "" {
+			pkt.CommandObj.Set("swfUrl", swfUrl)
+		}
+		pkt.CommandObj.Set("tcUrl", tcUrl)
+		pkt.CommandObj.Set("fpad", false)
+		pkt.CommandObj.Set("capabilities", float64(239))
+		pkt.CommandObj.Set("audioCodecs", float64(3575))
+		pkt.CommandObj.Set("videoCodecs", float64(252))
+		pkt.CommandObj.Set("videoFunction", float64(1))
+		if pageUrl != "" {
+			pkt.CommandObj.Set("pageUrl", pageUrl)
+		}
+		pkt.CommandObj.Set("objectEncoding", float64(global.RTMP_SIG_AMF0_VER))
+		if err := this.Protocol.SendPacket(pkt, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	// Set Window Acknowledgement size(2500000)
+	{
+		pkt := packet.NewSrsSetWindowAckSizePacket()
+		pkt.AckowledgementWindowSize = 2500000
+		if err := this.Protocol.SendPacket(pkt, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	// expect connect _result
+	resPkt := packet.NewSrsConnectAppResPacket()
+	if err := this.Protocol.ExpectMessage(resPkt); err != nil {
+		return nil, err
+	}
+
+	var level string
+	if err := resPkt.Info.Get(global.StatusLevel, &level); err == nil && level == global.StatusLevelError {
+		var description string
+		_ = resPkt.Info.Get(global.StatusDescription, &description)
+		return resPkt.Info, errors.New("connect app failed, " + description)
+	}
+	return resPkt.Info, nil
+}
+
+/**
+ * create a stream, then play/publish data over this stream.
+ */
+func (this *SrsRtmpClient) CreateStream() (int, error) {
+	// CreateStream
+	{
+		pkt := packet.NewSrsCreateStreamPacket()
+		pkt.TransactionId.Value = 2
+		if err := this.Protocol.SendPacket(pkt, 0); err != nil {
+			return 0, err
+		}
+	}
+
+	// CreateStream _result.
+	resPkt := packet.NewSrsCreateStreamResPacket(0, 0)
+	if err := this.Protocol.ExpectMessage(resPkt); err != nil {
+		return 0, err
+	}
+	return int(resPkt.StreamId.Value), nil
+}
+
+/**
+ * start play stream.
+ */
+func (this *SrsRtmpClient) Play(stream string, streamId int) error {
+	// Play(stream)
+	{
+		pkt := packet.NewSrsPlayPacket()
+		pkt.StreamName.Value.Value = stream
+		if err := this.Protocol.SendPacket(pkt, int32(streamId)); err != nil {
+			return err
+		}
+	}
+
+	// SetBufferLength(1000ms)
+	{
+		pkt := packet.NewSrsUserControlPacket()
+		pkt.EventType = global.SrcPCUCSetBufferLength
+		pkt.EventData = int32(streamId)
+		pkt.ExtraData = 1000
+		if err := this.Protocol.SendPacket(pkt, 0); err != nil {
+			return err
+		}
+	}
+
+	// SetChunkSize
+	return this.setChunkSize(global.SRS_CONSTS_RTMP_SRS_CHUNK_SIZE)
+}
+
+/**
+ * start publish stream. use flash publish workflow:
+ *       connect-app => create-stream => flash-publish
+ */
+func (this *SrsRtmpClient) Publish(stream string, streamId int) error {
+	// SetChunkSize
+	if err := this.setChunkSize(global.SRS_CONSTS_RTMP_SRS_CHUNK_SIZE); err != nil {
+		return err
+	}
+
+	// publish(stream)
+	pkt := packet.NewSrsPublishPacket()
+	pkt.StreamName.Value.Value = stream
+	return this.Protocol.SendPacket(pkt, int32(streamId))
+}
+
+/**
+ * start publish stream. use FMLE publish workflow:
+ *       connect-app => FMLE publish
+ * @return the stream id created by FMLE publish.
+ */
+func (this *SrsRtmpClient) FmlePublish(stream string) (int, error) {
+	// SrsFMLEStartPacket
+	{
+		pkt := packet.NewSrsFMLEStartPacket(amf0.RTMP_AMF0_COMMAND_RELEASE_STREAM)
+		pkt.TransactionId.Value = 2
+		pkt.StreamName.Value.Value = stream
+		if err := this.Protocol.SendPacket(pkt, 0); err != nil {
+			return 0, err
+		}
+	}
+
+	// FCPublish
+	{
+		pkt := packet.NewSrsFMLEStartPacket(amf0.RTMP_AMF0_COMMAND_FC_PUBLISH)
+		pkt.TransactionId.Value = 3
+		pkt.StreamName.Value.Value = stream
+		if err := this.Protocol.SendPacket(pkt, 0); err != nil {
+			return 0, err
+		}
+	}
+
+	// CreateStream
+	{
+		pkt := packet.NewSrsCreateStreamPacket()
+		pkt.TransactionId.Value = 4
+		if err := this.Protocol.SendPacket(pkt, 0); err != nil {
+			return 0, err
+		}
+	}
+
+	// expect result of CreateStream
+	resPkt := packet.NewSrsCreateStreamResPacket(0, 0)
+	if err := this.Protocol.ExpectMessage(resPkt); err != nil {
+		return 0, err
+	}
+	streamId := int(resPkt.StreamId.Value)
+
+	// publish(stream)
+	{
+		if err := this.setChunkSize(global.SRS_CONSTS_RTMP_SRS_CHUNK_SIZE); err != nil {
+			return 0, err
+		}
+
+		pkt := packet.NewSrsPublishPacket()
+		pkt.TransactionId.Value = 5
+		pkt.StreamName.Value.Value = stream
+		if err := this.Protocol.SendPacket(pkt, int32(streamId)); err != nil {
+			return 0, err
+		}
+	}
+	return streamId, nil
+}
+
+func (this *SrsRtmpClient) setChunkSize(chunkSize int32) error {
+	if this.Protocol.OutChunkSize == chunkSize {
+		return nil
+	}
+
+	pkt := packet.NewSrsSetChunkSizePacket()
+	pkt.ChunkSize = chunkSize
+	return this.Protocol.SendPacket(pkt, 0)
+}
+
+func (this *SrsRtmpClient) RecvMessage() (*SrsRtmpMessage, error) {
+	return this.Protocol.RecvMessage()
+}
+
+func (this *SrsRtmpClient) RecvMessageContext(ctx context.Context) (*SrsRtmpMessage, error) {
+	return this.Protocol.RecvMessageContext(ctx)
+}
+
+func (this *SrsRtmpClient) ExpectMessageContext(ctx context.Context, pkt packet.SrsPacket) error {
+	return this.Protocol.ExpectMessageContext(ctx, pkt)
+}
+
+func (this *SrsRtmpClient) SetRecvTimeout(timeout time.Duration) {
+	this.Protocol.SetRecvTimeout(timeout)
+}
+
+func (this *SrsRtmpClient) DecodeMessage(msg *SrsRtmpMessage) (packet.SrsPacket, error) {
+	return this.Protocol.DecodeMessage(msg)
+}
+
+func (this *SrsRtmpClient) SendPacket(pkt packet.SrsPacket, streamId int) error {
+	return this.Protocol.SendPacket(pkt, int32(streamId))
+}
+
+func (this *SrsRtmpClient) SendMsg(msg *SrsRtmpMessage, streamId int) error {
+	return this.Protocol.SendMsg(msg, int32(streamId))
+}
+
+func (this *SrsRtmpClient) SendMessages(msgs []*SrsSharedPtrMessage, streamId int) error {
+	return this.Protocol.SendMessages(msgs, streamId)
+}
+
+func (this *SrsRtmpClient) GetRecvBytes() int64 {
+	return this.io.GetRecvBytes()
+}
+
+func (this *SrsRtmpClient) GetSendBytes() int64 {
+	return this.io.GetSendBytes()
+}
@@ -46,9 +46,10 @@ func NewSrsHandshakeBytes(io_ *skt.SrsIOReadWriter) *SrsHandshakeBytes {
 }
 
 func (this *SrsHandshakeBytes) ReadC0C1() error {
+	// the c0c1 maybe already read by the complex handshake,
+	// which falls back to simple handshake.
 	if len(this.C0C1) > 0 {
-		err := errors.New("handshake read c0c1 failed, already read")
-		return err
+		return nil
 	}
 
 	this.C0C1 = make([]byte, 1537)
@@ -109,3 +110,41 @@ func (this *SrsHandshakeBytes) ReadC2() int {
 func (this *SrsHandshakeBytes) CheckC2() bool {
 	return bytes.Equal(this.C2, this.S0S1S2[1:1537])
 }
+
+func (this *SrsHandshakeBytes) CreateC0C1() error {
+	if len(this.C0C1) > 0 {
+		return nil
+	}
+	rand.Seed(time.Now().UnixNano())
+	this.C0C1 = make([]byte, 1537)
+	//c0 = version
+	this.C0C1[0] = 0x3
+	//c1 for bytes(timestamp)
+	b := utils.Int32ToBytes(int32(time.Now().Unix()), binary.LittleEndian)
+	copy(this.C0C1[1:5], b)
+	//c1 rand bytes
+	if n, err := rand.Read(this.C0C1[9:1537]); err != nil || n != 1528 {
+		return errors.New("create rand number failed")
+	}
+	return nil
+}
+
+func (this *SrsHandshakeBytes) ReadS0S1S2() error {
+	if len(this.S0S1S2) > 0 {
+		return nil
+	}
+
+	this.S0S1S2 = make([]byte, 3073)
+	_, err := this.io.ReadFully(this.S0S1S2, 1000)
+	return err
+}
+
+func (this *SrsHandshakeBytes) CreateC2() error {
+	if len(this.C2) > 0 {
+		return nil
+	}
+	//c2=s1
+	this.C2 = make([]byte, 1536)
+	copy(this.C2, this.S0S1S2[1:1537])
+	return nil
+}
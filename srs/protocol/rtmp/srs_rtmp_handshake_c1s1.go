@@ -0,0 +1,403 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package rtmp
+
+import (
+	"bytes"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"math/rand"
+	"time"
+)
+
+/**
+ * 68bytes FMS key which is used to sign the sever packet.
+ * the first 36bytes is "Genuine Adobe Flash Media Server 001".
+ */
+var SrsGenuineFMSKey = []byte{
+	0x47, 0x65, 0x6e, 0x75, 0x69, 0x6e, 0x65, 0x20,
+	0x41, 0x64, 0x6f, 0x62, 0x65, 0x20, 0x46, 0x6c,
+	0x61, 0x73, 0x68, 0x20, 0x4d, 0x65, 0x64, 0x69,
+	0x61, 0x20, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x20, 0x30, 0x30, 0x31, // Genuine Adobe Flash Media Server 001
+	0xf0, 0xee, 0xc2, 0x4a, 0x80, 0x68, 0xbe, 0xe8,
+	0x2e, 0x00, 0xd0, 0xd1, 0x02, 0x9e, 0x7e, 0x57,
+	0x6e, 0xec, 0x5d, 0x2d, 0x29, 0x80, 0x6f, 0xab,
+	0x93, 0xb8, 0xe6, 0x36, 0xcf, 0xeb, 0x31, 0xae,
+} // 68
+
+/**
+ * 62bytes FP key which is used to sign the client packet.
+ * the first 30bytes is "Genuine Adobe Flash Player 001".
+ */
+var SrsGenuineFPKey = []byte{
+	0x47, 0x65, 0x6E, 0x75, 0x69, 0x6E, 0x65, 0x20,
+	0x41, 0x64, 0x6F, 0x62, 0x65, 0x20, 0x46, 0x6C,
+	0x61, 0x73, 0x68, 0x20, 0x50, 0x6C, 0x61, 0x79,
+	0x65, 0x72, 0x20, 0x30, 0x30, 0x31, // Genuine Adobe Flash Player 001
+	0xF0, 0xEE, 0xC2, 0x4A, 0x80, 0x68, 0xBE, 0xE8,
+	0x2E, 0x00, 0xD0, 0xD1, 0x02, 0x9E, 0x7E, 0x57,
+	0x6E, 0xEC, 0x5D, 0x2D, 0x29, 0x80, 0x6F, 0xAB,
+	0x93, 0xB8, 0xE6, 0x36, 0xCF, 0xEB, 0x31, 0xAE,
+} // 62
+
+const (
+	SRS_HANDSHAKE_C1S1_SIZE      = 1536
+	SRS_HANDSHAKE_BLOCK_SIZE     = 764
+	SRS_HANDSHAKE_KEY_SIZE       = 128
+	SRS_HANDSHAKE_DIGEST_SIZE    = 32
+	SRS_HANDSHAKE_C2S2_RAND_SIZE = SRS_HANDSHAKE_C1S1_SIZE - SRS_HANDSHAKE_DIGEST_SIZE
+)
+
+/**
+ * the schema type.
+ */
+type SrsHandshakeSchema int
+
+const (
+	/**
+	 * key-digest sequence
+	 */
+	SrsSchema0 SrsHandshakeSchema = 0
+	/**
+	 * digest-key sequence
+	 * @remark, FMS requires the schema1(digest-key), or connect failed.
+	 */
+	SrsSchema1 SrsHandshakeSchema = 1
+)
+
+// the complex handshake failed and the caller should try the simple handshake.
+var ErrTrySimpleHandshake = errors.New("complex handshake failed, try simple handshake")
+
+func openssl_HMACsha256(key []byte, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+/**
+ * the DH of complex handshake, use the 1024bits MODP group of RFC2409,
+ * the public key is always 128bytes.
+ */
+var srsDHPrime1024, _ = new(big.Int).SetString(
+	"FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1"+
+		"29024E088A67CC74020BBEA63B139B22514A08798E3404DD"+
+		"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245"+
+		"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE65381"+
+		"FFFFFFFFFFFFFFFF", 16)
+
+type SrsDH struct {
+	privateKey *big.Int
+	publicKey  *big.Int
+}
+
+func NewSrsDH() (*SrsDH, error) {
+	// private key in [2, p-2]
+	max := new(big.Int).Sub(srsDHPrime1024, big.NewInt(3))
+	priv, err := crand.Int(crand.Reader, max)
+	if err != nil {
+		return nil, err
+	}
+	priv.Add(priv, big.NewInt(2))
+
+	return &SrsDH{
+		privateKey: priv,
+		publicKey:  new(big.Int).Exp(big.NewInt(2), priv, srsDHPrime1024),
+	}, nil
+}
+
+/**
+ * copy the public key, padding with zero to 128bytes.
+ */
+func (this *SrsDH) CopyPublicKey() []byte {
+	return srsPadKey(this.publicKey.Bytes())
+}
+
+/**
+ * generate and copy the shared key with the peer public key.
+ */
+func (this *SrsDH) CopySharedKey(peerPublicKey []byte) ([]byte, error) {
+	pub := new(big.Int).SetBytes(peerPublicKey)
+	if pub.Cmp(big.NewInt(1)) <= 0 || pub.Cmp(srsDHPrime1024) >= 0 {
+		return nil, errors.New("invalid dh peer public key")
+	}
+	return srsPadKey(new(big.Int).Exp(pub, this.privateKey, srsDHPrime1024).Bytes()), nil
+}
+
+func srsPadKey(b []byte) []byte {
+	key := make([]byte, SRS_HANDSHAKE_KEY_SIZE)
+	copy(key[SRS_HANDSHAKE_KEY_SIZE-len(b):], b)
+	return key
+}
+
+/**
+ * the c1s1 of complex handshake, 1536bytes.
+ * schema0:
+ *     time: 4bytes
+ *     version: 4bytes
+ *     key: 764bytes
+ *     digest: 764bytes
+ * schema1:
+ *     time: 4bytes
+ *     version: 4bytes
+ *     digest: 764bytes
+ *     key: 764bytes
+ *
+ * 764bytes key structure
+ *     random-data: (offset)bytes
+ *     key-data: 128bytes
+ *     random-data: (764-offset-128-4)bytes
+ *     offset: 4bytes
+ *
+ * 764bytes digest structure
+ *     offset: 4bytes
+ *     random-data: (offset)bytes
+ *     digest-data: 32bytes
+ *     random-data: (764-4-offset-32)bytes
+ *
+ * @remark, the data is kept as raw bytes, the key and digest are
+ *     located by the offset, so the join of the c1s1 without digest is
+ *     simply the raw bytes skip the 32bytes digest.
+ */
+type SrsC1S1 struct {
+	Data   []byte
+	Schema SrsHandshakeSchema
+}
+
+func NewSrsC1S1() *SrsC1S1 {
+	return &SrsC1S1{
+		Data:   make([]byte, SRS_HANDSHAKE_C1S1_SIZE),
+		Schema: SrsSchema0,
+	}
+}
+
+func (this *SrsC1S1) Time() uint32 {
+	return binary.BigEndian.Uint32(this.Data[0:4])
+}
+
+func (this *SrsC1S1) Version() uint32 {
+	return binary.BigEndian.Uint32(this.Data[4:8])
+}
+
+func (this *SrsC1S1) keyBlockPos() int {
+	if this.Schema == SrsSchema0 {
+		return 8
+	}
+	return 8 + SRS_HANDSHAKE_BLOCK_SIZE
+}
+
+func (this *SrsC1S1) digestBlockPos() int {
+	if this.Schema == SrsSchema0 {
+		return 8 + SRS_HANDSHAKE_BLOCK_SIZE
+	}
+	return 8
+}
+
+func (this *SrsC1S1) keyPos() int {
+	block := this.keyBlockPos()
+	p := this.Data[block+SRS_HANDSHAKE_BLOCK_SIZE-4 : block+SRS_HANDSHAKE_BLOCK_SIZE]
+	offset := int(p[0]) + int(p[1]) + int(p[2]) + int(p[3])
+	return block + offset%(SRS_HANDSHAKE_BLOCK_SIZE-SRS_HANDSHAKE_KEY_SIZE-4)
+}
+
+func (this *SrsC1S1) digestPos() int {
+	block := this.digestBlockPos()
+	p := this.Data[block : block+4]
+	offset := int(p[0]) + int(p[1]) + int(p[2]) + int(p[3])
+	return block + 4 + offset%(SRS_HANDSHAKE_BLOCK_SIZE-SRS_HANDSHAKE_DIGEST_SIZE-4)
+}
+
+func (this *SrsC1S1) Key() []byte {
+	pos := this.keyPos()
+	return this.Data[pos : pos+SRS_HANDSHAKE_KEY_SIZE]
+}
+
+func (this *SrsC1S1) Digest() []byte {
+	pos := this.digestPos()
+	return this.Data[pos : pos+SRS_HANDSHAKE_DIGEST_SIZE]
+}
+
+/**
+ * calc the digest of c1s1, the 1504bytes which excludes the digest itself.
+ */
+func (this *SrsC1S1) calcDigest(key []byte) []byte {
+	pos := this.digestPos()
+	join := make([]byte, 0, SRS_HANDSHAKE_C1S1_SIZE-SRS_HANDSHAKE_DIGEST_SIZE)
+	join = append(join, this.Data[:pos]...)
+	join = append(join, this.Data[pos+SRS_HANDSHAKE_DIGEST_SIZE:]...)
+	return openssl_HMACsha256(key, join)
+}
+
+/**
+ * parse the c1s1 in schema, user must validate the digest to check the schema.
+ */
+func (this *SrsC1S1) Parse(data []byte, schema SrsHandshakeSchema) error {
+	if len(data) != SRS_HANDSHAKE_C1S1_SIZE {
+		return errors.New("c1s1 size must be 1536bytes")
+	}
+	copy(this.Data, data)
+	this.Schema = schema
+	return nil
+}
+
+/**
+ * validate the c1 digest, signed by the first 30bytes of FP key.
+ */
+func (this *SrsC1S1) C1Validate() bool {
+	return bytes.Equal(this.calcDigest(SrsGenuineFPKey[:30]), this.Digest())
+}
+
+/**
+ * validate the s1 digest, signed by the first 36bytes of FMS key.
+ */
+func (this *SrsC1S1) S1Validate() bool {
+	return bytes.Equal(this.calcDigest(SrsGenuineFMSKey[:36]), this.Digest())
+}
+
+/**
+ * create the c1 in schema, the key of c1 is the dh public key,
+ * the digest is signed by FP key.
+ */
+func (this *SrsC1S1) C1Create(schema SrsHandshakeSchema) error {
+	if schema != SrsSchema0 && schema != SrsSchema1 {
+		return errors.New("create c1 failed, invalid schema")
+	}
+	this.Schema = schema
+
+	rand.Seed(time.Now().UnixNano())
+	rand.Read(this.Data)
+	binary.BigEndian.PutUint32(this.Data[0:4], uint32(time.Now().Unix()))
+	binary.BigEndian.PutUint32(this.Data[4:8], 0x80000702)
+
+	dh, err := NewSrsDH()
+	if err != nil {
+		return err
+	}
+	copy(this.Key(), dh.CopyPublicKey())
+	copy(this.Digest(), this.calcDigest(SrsGenuineFPKey[:30]))
+	return nil
+}
+
+/**
+ * create the s1 from c1, use the same schema of c1.
+ * the key of s1 is the dh public key, the digest is signed by FMS key.
+ */
+func (this *SrsC1S1) S1Create(c1 *SrsC1S1) error {
+	if c1.Schema != SrsSchema0 && c1.Schema != SrsSchema1 {
+		return errors.New("create s1 failed, invalid schema")
+	}
+	this.Schema = c1.Schema
+
+	rand.Seed(time.Now().UnixNano())
+	rand.Read(this.Data)
+	binary.BigEndian.PutUint32(this.Data[0:4], uint32(time.Now().Unix()))
+	binary.BigEndian.PutUint32(this.Data[4:8], 0x01000504)
+
+	dh, err := NewSrsDH()
+	if err != nil {
+		return err
+	}
+	copy(this.Key(), dh.CopyPublicKey())
+	copy(this.Digest(), this.calcDigest(SrsGenuineFMSKey[:36]))
+	return nil
+}
+
+/**
+ * the c2s2 of complex handshake, 1536bytes.
+ *     random-data: 1504bytes
+ *     digest-data: 32bytes
+ */
+type SrsC2S2 struct {
+	Data []byte
+}
+
+func NewSrsC2S2() *SrsC2S2 {
+	return &SrsC2S2{
+		Data: make([]byte, SRS_HANDSHAKE_C1S1_SIZE),
+	}
+}
+
+func (this *SrsC2S2) Random() []byte {
+	return this.Data[:SRS_HANDSHAKE_C2S2_RAND_SIZE]
+}
+
+func (this *SrsC2S2) Digest() []byte {
+	return this.Data[SRS_HANDSHAKE_C2S2_RAND_SIZE:]
+}
+
+func (this *SrsC2S2) Parse(data []byte) error {
+	if len(data) != SRS_HANDSHAKE_C1S1_SIZE {
+		return errors.New("c2s2 size must be 1536bytes")
+	}
+	copy(this.Data, data)
+	return nil
+}
+
+/**
+ * the digest of c2s2 is signed by the temp key, which is the
+ * hmac of the peer c1s1 digest with the whole 68bytes FMS key(s2)
+ * or 62bytes FP key(c2).
+ */
+func (this *SrsC2S2) calcDigest(key []byte, peerDigest []byte) []byte {
+	tempKey := openssl_HMACsha256(key, peerDigest)
+	return openssl_HMACsha256(tempKey, this.Random())
+}
+
+/**
+ * create the s2 from c1, the digest is signed by FMS key.
+ */
+func (this *SrsC2S2) S2Create(c1 *SrsC1S1) error {
+	rand.Seed(time.Now().UnixNano())
+	rand.Read(this.Random())
+	copy(this.Digest(), this.calcDigest(SrsGenuineFMSKey, c1.Digest()))
+	return nil
+}
+
+/**
+ * create the c2 from s1, the digest is signed by FP key.
+ */
+func (this *SrsC2S2) C2Create(s1 *SrsC1S1) error {
+	rand.Seed(time.Now().UnixNano())
+	rand.Read(this.Random())
+	copy(this.Digest(), this.calcDigest(SrsGenuineFPKey, s1.Digest()))
+	return nil
+}
+
+/**
+ * validate the s2 from c1, the digest is signed by FMS key.
+ */
+func (this *SrsC2S2) S2Validate(c1 *SrsC1S1) bool {
+	return bytes.Equal(this.calcDigest(SrsGenuineFMSKey, c1.Digest()), this.Digest())
+}
+
+/**
+ * validate the c2 from s1, the digest is signed by FP key.
+ */
+func (this *SrsC2S2) C2Validate(s1 *SrsC1S1) bool {
+	return bytes.Equal(this.calcDigest(SrsGenuineFPKey, s1.Digest()), this.Digest())
+}
@@ -76,5 +76,158 @@ func (this *SrsSimpleHandShake) HandShakeWithClient() error {
 }
 
 func (this *SrsSimpleHandShake) HandShakeWithServer() error {
+	if err := this.HSBytes.CreateC0C1(); err != nil {
+		return err
+	}
+
+	if _, err := this.io.Write(this.HSBytes.C0C1); err != nil {
+		return err
+	}
+
+	if err := this.HSBytes.ReadS0S1S2(); err != nil {
+		return err
+	}
+
+	// plain text required.
+	if this.HSBytes.S0S1S2[0] != 0x03 {
+		return errors.New("handshake failed, plain text required")
+	}
+
+	if err := this.HSBytes.CreateC2(); err != nil {
+		return err
+	}
+
+	if _, err := this.io.Write(this.HSBytes.C2); err != nil {
+		return err
+	}
+	return nil
+}
+
+/**
+ * rtmp complex handshake,
+ * @see also crtmp(crtmpserver) or librtmp,
+ * @see also: http://blog.csdn.net/win_lin/article/details/13006803
+ */
+type SrsComplexHandShake struct {
+	HSBytes *SrsHandshakeBytes
+	io      *skt.SrsIOReadWriter
+}
+
+func NewSrsComplexHandShake(io_ *skt.SrsIOReadWriter) *SrsComplexHandShake {
+	return &SrsComplexHandShake{
+		HSBytes: NewSrsHandshakeBytes(io_),
+		io:      io_,
+	}
+}
+
+/**
+ * complex handshake.
+ * @return user must use the simple handshake when ErrTrySimpleHandshake returned,
+ *       for the c0c1 is not a complex handshake packet, and the c0c1 is kept in HSBytes.
+ */
+func (this *SrsComplexHandShake) HandShakeWithClient() error {
+	err := this.HSBytes.ReadC0C1()
+	if err != nil {
+		return err
+	}
+
+	// plain text required.
+	if this.HSBytes.C0C1[0] != 0x03 {
+		return errors.New("only support rtmp plain text.")
+	}
+
+	// decode c1, try schema0 then schema1.
+	c1 := NewSrsC1S1()
+	if err = c1.Parse(this.HSBytes.C0C1[1:], SrsSchema0); err != nil {
+		return err
+	}
+	if !c1.C1Validate() {
+		if err = c1.Parse(this.HSBytes.C0C1[1:], SrsSchema1); err != nil {
+			return err
+		}
+		if !c1.C1Validate() {
+			return ErrTrySimpleHandshake
+		}
+	}
+
+	// encode s1 in the same schema of c1.
+	s1 := NewSrsC1S1()
+	if err = s1.S1Create(c1); err != nil {
+		return err
+	}
+
+	s2 := NewSrsC2S2()
+	if err = s2.S2Create(c1); err != nil {
+		return err
+	}
+
+	// s0s1s2
+	this.HSBytes.S0S1S2 = make([]byte, 0, 3073)
+	this.HSBytes.S0S1S2 = append(this.HSBytes.S0S1S2, 0x03)
+	this.HSBytes.S0S1S2 = append(this.HSBytes.S0S1S2, s1.Data...)
+	this.HSBytes.S0S1S2 = append(this.HSBytes.S0S1S2, s2.Data...)
+	if _, err = this.io.Write(this.HSBytes.S0S1S2); err != nil {
+		return err
+	}
+
+	// recv c2
+	if 0 != this.HSBytes.ReadC2() {
+		return errors.New("HandShake ReadC2 failed")
+	}
+
+	// never verify c2, for ffmpeg will failed.
+	// it's ok for flash.
+	return nil
+}
+
+/**
+ * complex handshake with server, always use schema1 which FMS requires.
+ * @remark when the s1 is not a complex handshake packet, for example,
+ *       the server only supports simple handshake, response the c2 with s1.
+ */
+func (this *SrsComplexHandShake) HandShakeWithServer() error {
+	// c0c1
+	c1 := NewSrsC1S1()
+	if err := c1.C1Create(SrsSchema1); err != nil {
+		return err
+	}
+
+	this.HSBytes.C0C1 = make([]byte, 0, 1537)
+	this.HSBytes.C0C1 = append(this.HSBytes.C0C1, 0x03)
+	this.HSBytes.C0C1 = append(this.HSBytes.C0C1, c1.Data...)
+	if _, err := this.io.Write(this.HSBytes.C0C1); err != nil {
+		return err
+	}
+
+	// s0s1s2
+	if err := this.HSBytes.ReadS0S1S2(); err != nil {
+		return err
+	}
+
+	// plain text required.
+	if this.HSBytes.S0S1S2[0] != 0x03 {
+		return errors.New("handshake failed, plain text required")
+	}
+
+	// verify s1, use the same schema of c1.
+	s1 := NewSrsC1S1()
+	if err := s1.Parse(this.HSBytes.S0S1S2[1:1537], c1.Schema); err != nil {
+		return err
+	}
+
+	// c2
+	if s1.S1Validate() {
+		c2 := NewSrsC2S2()
+		if err := c2.C2Create(s1); err != nil {
+			return err
+		}
+		this.HSBytes.C2 = c2.Data
+	} else if err := this.HSBytes.CreateC2(); err != nil {
+		return err
+	}
+
+	if _, err := this.io.Write(this.HSBytes.C2); err != nil {
+		return err
+	}
 	return nil
 }
@@ -22,6 +22,11 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 */
 package rtmp
 
+import (
+	"errors"
+	"go_srs/srs/utils"
+)
+
 type SrsRtmpMessage struct {
 	// 4.1. Message Header
 	header SrsMessageHeader
@@ -82,3 +87,55 @@ func (this *SrsRtmpMessage) ChunkHeader(c0 bool) ([]byte, error) {
 		return d, err
 	}
 }
+
+/**
+ * demux the aggregate message to the sub messages, each is a flv tag:
+ *      type(1B), data size(3B), timestamp(3B), timestamp extended(1B), stream id(3B),
+ *      data(data size), previous tag size(4B).
+ * the timestamp of sub messages is adjusted by the delta of the aggregate message
+ * to the first sub message.
+ */
+func (this *SrsRtmpMessage) DemuxAggregate() ([]*SrsRtmpMessage, error) {
+	if !this.header.IsAggregate() {
+		return nil, errors.New("demux aggregate failed, not aggregate message")
+	}
+
+	msgs := make([]*SrsRtmpMessage, 0)
+	stream := utils.NewSrsStream(this.payload)
+	var delta int64 = 0
+	first := true
+	for !stream.Empty() {
+		if !stream.Require(11) {
+			return nil, errors.New("demux aggregate failed, invalid tag header")
+		}
+
+		b, _ := stream.ReadBytes(11)
+		messageType := int8(b[0])
+		dataSize := int32(b[1])<<16 | int32(b[2])<<8 | int32(b[3])
+		timestamp := int64(b[4])<<16 | int64(b[5])<<8 | int64(b[6]) | int64(b[7])<<24
+
+		// adjust abs timestamp in aggregate msg.
+		if first {
+			delta = this.header.timestamp - timestamp
+			first = false
+		}
+		timestamp += delta
+
+		data, err := stream.ReadBytes(uint32(dataSize))
+		if err != nil {
+			return nil, errors.New("demux aggregate failed, invalid tag data")
+		}
+
+		// the previous tag size, the last tag may omit it.
+		if stream.Require(4) {
+			stream.Skip(4)
+		}
+
+		msg := NewSrsRtmpMessage()
+		msg.header.Initialize(messageType, dataSize, timestamp, this.header.streamId)
+		msg.SetPayload(data)
+		msg.recvedSize = dataSize
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
@@ -23,32 +23,47 @@ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 package rtmp
 
 import (
-	_ "context"
+	"context"
+	"errors"
 	"go_srs/srs/global"
 	"go_srs/srs/protocol/amf0"
 	"go_srs/srs/protocol/packet"
 	"go_srs/srs/protocol/skt"
 	_ "log"
 	_ "net/url"
+	"strconv"
 	_ "strings"
-	_ "time"
+	"sync"
+	"time"
 )
 
+/**
+ * the call to peer is responsed _error, the response is the information of error.
+ */
+var ErrCallFailed = errors.New("call failed, peer response _error")
+
 type SrsRtmpServer struct {
-	io            *skt.SrsIOReadWriter
-	Protocol      *SrsProtocol
-	HandShaker    *SrsSimpleHandShake
-	IOErrListener skt.SrsIOErrListener
+	io                *skt.SrsIOReadWriter
+	Protocol          *SrsProtocol
+	HandShaker        *SrsSimpleHandShake
+	ComplexHandShaker *SrsComplexHandShake
+	IOErrListener     skt.SrsIOErrListener
+	// the calls to client waiting for result, key is the transaction id.
+	callsLock  sync.Mutex
+	calls      map[float64]chan *packet.SrsCallResPacket
+	lastCallId float64
 }
 
 func NewSrsRtmpServer(io *skt.SrsIOReadWriter, listener skt.SrsIOErrListener) *SrsRtmpServer {
 	//io_ := skt.NewSrsIOReadWriter(conn)
 	//io_ = io
 	return &SrsRtmpServer{
-		io:            io,
-		Protocol:      NewSrsProtocol(io),
-		HandShaker:    NewSrsSimpleHandShake(io),
-		IOErrListener: listener,
+		io:                io,
+		Protocol:          NewSrsProtocol(io),
+		HandShaker:        NewSrsSimpleHandShake(io),
+		ComplexHandShaker: NewSrsComplexHandShake(io),
+		IOErrListener:     listener,
+		calls:             make(map[float64]chan *packet.SrsCallResPacket),
 	}
 }
 
@@ -60,14 +75,83 @@ func (this *SrsRtmpServer) GetClientIP() string {
 	return this.io.GetClientIP()
 }
 
+func (this *SrsRtmpServer) GetRecvBytes() int64 {
+	return this.Protocol.GetRecvBytes()
+}
+
+func (this *SrsRtmpServer) GetSendBytes() int64 {
+	return this.Protocol.GetSendBytes()
+}
+
+/**
+ * the acknowledgement counters of in and out window.
+ */
+func (this *SrsRtmpServer) GetInAckSize() AckWindowSize {
+	return this.Protocol.GetInAckSize()
+}
+
+func (this *SrsRtmpServer) GetOutAckSize() AckWindowSize {
+	return this.Protocol.GetOutAckSize()
+}
+
+func (this *SrsRtmpServer) GetOutUnackedBytes() int64 {
+	return this.Protocol.GetOutUnackedBytes()
+}
+
+/**
+ * send the ping request to client, the round trip time is updated when client responses.
+ */
+func (this *SrsRtmpServer) PingRequest() error {
+	return this.Protocol.SendPingRequest()
+}
+
+func (this *SrsRtmpServer) GetRtt() time.Duration {
+	return this.Protocol.GetRtt()
+}
+
+func (this *SrsRtmpServer) GetLastRecvTime() time.Time {
+	return this.Protocol.GetLastRecvTime()
+}
+
+func (this *SrsRtmpServer) SetRecvTimeout(timeout time.Duration) {
+	this.Protocol.SetRecvTimeout(timeout)
+}
+
 func (this *SrsRtmpServer) HandShake() error {
-	err := this.HandShaker.HandShakeWithClient()
+	ctx, cancel := context.WithTimeout(context.Background(), this.Protocol.GetRecvTimeout())
+	defer cancel()
+	return this.HandShakeContext(ctx)
+}
+
+/**
+ * handshake with client, interrupted when ctx done.
+ */
+func (this *SrsRtmpServer) HandShakeContext(ctx context.Context) error {
+	return this.io.ReadContext(ctx, this.doHandShake)
+}
+
+func (this *SrsRtmpServer) doHandShake() error {
+	// try complex handshake first,
+	// fall back to simple handshake with the same c0c1.
+	err := this.ComplexHandShaker.HandShakeWithClient()
+	if err != ErrTrySimpleHandshake {
+		return err
+	}
+
+	this.HandShaker.HSBytes = this.ComplexHandShaker.HSBytes
+	err = this.HandShaker.HandShakeWithClient()
 	return err
 }
 
 func (this *SrsRtmpServer) ConnectApp() (packet.SrsPacket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), this.Protocol.GetRecvTimeout())
+	defer cancel()
+	return this.ConnectAppContext(ctx)
+}
+
+func (this *SrsRtmpServer) ConnectAppContext(ctx context.Context) (packet.SrsPacket, error) {
 	connPacket := packet.NewSrsConnectAppPacket()
-	if err := this.Protocol.ExpectMessage(connPacket); err != nil {
+	if err := this.Protocol.ExpectMessageContext(ctx, connPacket); err != nil {
 		return nil, err
 	}
 	return connPacket, nil
@@ -77,6 +161,14 @@ func (this *SrsRtmpServer) RecvMessage() (*SrsRtmpMessage, error) {
 	return this.Protocol.RecvMessage()
 }
 
+func (this *SrsRtmpServer) RecvMessageContext(ctx context.Context) (*SrsRtmpMessage, error) {
+	return this.Protocol.RecvMessageContext(ctx)
+}
+
+func (this *SrsRtmpServer) ExpectMessageContext(ctx context.Context, pkt packet.SrsPacket) error {
+	return this.Protocol.ExpectMessageContext(ctx, pkt)
+}
+
 func (this *SrsRtmpServer) DecodeMessage(msg *SrsRtmpMessage) (packet.SrsPacket, error) {
 	return this.Protocol.DecodeMessage(msg)
 }
@@ -88,13 +180,18 @@ func (this *SrsRtmpServer) OnRecvError(err error) {
 }
 
 func (this *SrsRtmpServer) IdentifyClient(streamId int) (SrsRtmpConnType, string, float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), this.Protocol.GetRecvTimeout())
+	defer cancel()
+	return this.IdentifyClientContext(ctx, streamId)
+}
+
+func (this *SrsRtmpServer) IdentifyClientContext(ctx context.Context, streamId int) (SrsRtmpConnType, string, float64, error) {
 	var typ SrsRtmpConnType
 	var streamname string
-	var duration float64
 	for {
-		msg, err := this.Protocol.RecvMessage()
+		msg, err := this.Protocol.RecvMessageContext(ctx)
 		if err != nil {
-			continue
+			return typ, streamname, 0, err
 		}
 		header := msg.GetHeader()
 		if header.IsAckledgement() || header.IsSetChunkSize() || header.IsWindowAckledgementSize() || header.IsUserControlMessage() {
@@ -106,47 +203,48 @@ func (this *SrsRtmpServer) IdentifyClient(streamId int) (SrsRtmpConnType, string
 		}
 
 		pkt, err := this.Protocol.DecodeMessage(msg)
-		switch pkt.(type) {
-		//todo
+		if err != nil {
+			return typ, streamname, 0, err
+		}
+
+		switch p := pkt.(type) {
 		case *packet.SrsCreateStreamPacket:
 			{
-				typ, streamname, duration, err = this.identifyCreateStreamClient(pkt.(*packet.SrsCreateStreamPacket), streamId)
-				return typ, streamname, duration, err
+				return this.identifyCreateStreamClient(ctx, p, streamId)
 			}
 		case *packet.SrsFMLEStartPacket:
 			{
-				typ, streamname, err = this.identifyFmlePublishClient(pkt.(*packet.SrsFMLEStartPacket))
-				if err != nil {
-					return typ, streamname, 0, nil
-				}
-				return typ, streamname, 0, nil
+				typ, streamname, err = this.identifyFmlePublishClient(p)
+				return typ, streamname, 0, err
 			}
 		case *packet.SrsPlayPacket:
 			{
-				typ, streamname, duration, err = this.identifyPlayclient(pkt.(*packet.SrsPlayPacket))
-				return typ, streamname, duration, err
+				return this.identifyPlayclient(p)
 			}
 		}
-		return typ, streamname, 0, nil
+		// ignore other packets, for example, the call of client.
 	}
-	_ = typ
-	return typ, streamname, 0, nil
 }
 
-func (this *SrsRtmpServer) identifyCreateStreamClient(req *packet.SrsCreateStreamPacket, streamId int) (SrsRtmpConnType, string, float64, error) {
-	typ := SrsRtmpConnType(SrsRtmpConnFMLEPublish)
-	resPkt := packet.NewSrsCreateStreamResPacket(req.TransactionId.GetValue().(float64), float64(streamId))
-	err := this.Protocol.SendPacket(resPkt, 0)
+/**
+ * identify the client after createStream, the client maybe:
+ *     play, the play client.
+ *     publish, the flash publish client.
+ *     FCPublish, the haivision publish client, without releaseStream.
+ *     createStream, create another stream.
+ */
+func (this *SrsRtmpServer) identifyCreateStreamClient(ctx context.Context, req *packet.SrsCreateStreamPacket, streamId int) (SrsRtmpConnType, string, float64, error) {
+	var typ SrsRtmpConnType
 	var streamname string
-	var duration float64
-	if err != nil {
-		return SrsRtmpConnType(SrsRtmpConnFMLEPublish), "", 0, err
+	resPkt := packet.NewSrsCreateStreamResPacket(req.TransactionId.GetValue().(float64), float64(streamId))
+	if err := this.Protocol.SendPacket(resPkt, 0); err != nil {
+		return typ, streamname, 0, err
 	}
 
 	for {
-		msg, err := this.Protocol.RecvMessage()
+		msg, err := this.Protocol.RecvMessageContext(ctx)
 		if err != nil {
-			continue
+			return typ, streamname, 0, err
 		}
 		header := msg.GetHeader()
 		if header.IsAckledgement() || header.IsSetChunkSize() || header.IsWindowAckledgementSize() || header.IsUserControlMessage() {
@@ -158,32 +256,32 @@ func (this *SrsRtmpServer) identifyCreateStreamClient(req *packet.SrsCreateStrea
 		}
 
 		pkt, err := this.Protocol.DecodeMessage(msg)
-		switch pkt.(type) {
+		if err != nil {
+			return typ, streamname, 0, err
+		}
+
+		switch p := pkt.(type) {
 		case *packet.SrsPlayPacket:
 			{
-				typ, streamname, duration, err = this.identifyPlayclient(pkt.(*packet.SrsPlayPacket))
-				return typ, streamname, duration, err
+				return this.identifyPlayclient(p)
+			}
+		case *packet.SrsPublishPacket:
+			{
+				typ, streamname = this.identifyFlashPublishClient(p)
+				return typ, streamname, 0, nil
 			}
 		case *packet.SrsCreateStreamPacket:
 			{
-				typ, streamname, duration, err = this.identifyCreateStreamClient(pkt.(*packet.SrsCreateStreamPacket), streamId)
-				return typ, streamname, duration, err
+				return this.identifyCreateStreamClient(ctx, p, streamId)
 			}
 		case *packet.SrsFMLEStartPacket:
 			{
-				typ, streamname, err = this.identifyFmlePublishClient(pkt.(*packet.SrsFMLEStartPacket))
-				if err != nil {
-					return typ, streamname, 0, nil
-				}
-				return typ, streamname, 0, nil
+				typ, streamname, err = this.identifyHaivisionPublishClient(p)
+				return typ, streamname, 0, err
 			}
-			//todo
-			//identify_haivision_publish_client
-			//identify_flash_publish_client
 		}
+		// ignore other packets.
 	}
-	_ = typ
-	return typ, streamname, 0, nil
 }
 
 func (this *SrsRtmpServer) identifyPlayclient(pkt *packet.SrsPlayPacket) (SrsRtmpConnType, string, float64, error) {
@@ -191,8 +289,10 @@ func (this *SrsRtmpServer) identifyPlayclient(pkt *packet.SrsPlayPacket) (SrsRtm
 }
 
 func (this *SrsRtmpServer) SendMsg(msg *SrsRtmpMessage, streamId int) error {
-	msgs := make([]*SrsRtmpMessage, 1)
-	msgs[0] = msg
+	return this.Protocol.SendMsg(msg, int32(streamId))
+}
+
+func (this *SrsRtmpServer) SendMessages(msgs []*SrsSharedPtrMessage, streamId int) error {
 	return this.Protocol.SendMessages(msgs, streamId)
 }
 
@@ -206,6 +306,88 @@ func (this *SrsRtmpServer) identifyFmlePublishClient(req *packet.SrsFMLEStartPac
 	return typ, req.StreamName.Value.Value, nil
 }
 
+/**
+ * the haivision encoder send FCPublish after createStream, without releaseStream.
+ */
+func (this *SrsRtmpServer) identifyHaivisionPublishClient(req *packet.SrsFMLEStartPacket) (SrsRtmpConnType, string, error) {
+	typ := SrsRtmpConnType(SrsRtmpConnHaivisionPublish)
+	pkt := packet.NewSrsFMLEStartResPacket(req.TransactionId.Value)
+	if err := this.Protocol.SendPacket(pkt, 0); err != nil {
+		return typ, req.StreamName.Value.Value, err
+	}
+	return typ, req.StreamName.Value.Value, nil
+}
+
+/**
+ * the flash publish client send publish after createStream.
+ */
+func (this *SrsRtmpServer) identifyFlashPublishClient(req *packet.SrsPublishPacket) (SrsRtmpConnType, string) {
+	return SrsRtmpConnType(SrsRtmpConnFlashPublish), req.StreamName.Value.Value
+}
+
+/**
+ * when client(type is play) send pause message,
+ * if is pause, response the following packets:
+ *     onStatus(NetStream.Pause.Notify)
+ *     StreamEOF
+ * if is unpause, response the following packets:
+ *     onStatus(NetStream.Unpause.Notify)
+ *     StreamBegin
+ */
+func (this *SrsRtmpServer) OnPlayClientPause(streamId int, isPause bool) error {
+	statusPkt := packet.NewSrsOnStatusCallPacket()
+	statusPkt.Data.Set(global.StatusLevel, global.StatusLevelStatus)
+	if isPause {
+		statusPkt.Data.Set(global.StatusCode, global.StatusCodeStreamPause)
+		statusPkt.Data.Set(global.StatusDescription, "Paused stream.")
+	} else {
+		statusPkt.Data.Set(global.StatusCode, global.StatusCodeStreamUnpause)
+		statusPkt.Data.Set(global.StatusDescription, "Unpaused stream.")
+	}
+	if err := this.Protocol.SendPacket(statusPkt, int32(streamId)); err != nil {
+		return err
+	}
+
+	pkt := packet.NewSrsUserControlPacket()
+	pkt.EventType = global.SrcPCUCStreamEOF
+	if !isPause {
+		pkt.EventType = global.SrcPCUCStreamBegin
+	}
+	pkt.EventData = int32(streamId)
+	return this.Protocol.SendPacket(pkt, 0)
+}
+
+/**
+ * when client(type is play) send seek message,
+ * if seekable, response the following packets:
+ *     StreamBegin
+ *     onStatus(NetStream.Seek.Notify)
+ * otherwise, response onStatus(NetStream.Seek.Failed), for example, the live stream.
+ */
+func (this *SrsRtmpServer) OnPlayClientSeek(streamId int, seekable bool, timeMs float64) error {
+	statusPkt := packet.NewSrsOnStatusCallPacket()
+	if !seekable {
+		statusPkt.Data.Set(global.StatusLevel, global.StatusLevelError)
+		statusPkt.Data.Set(global.StatusCode, global.StatusCodeStreamSeekFailed)
+		statusPkt.Data.Set(global.StatusDescription, "Seek failed, stream is not seekable.")
+		return this.Protocol.SendPacket(statusPkt, int32(streamId))
+	}
+
+	pkt := packet.NewSrsUserControlPacket()
+	pkt.EventType = global.SrcPCUCStreamBegin
+	pkt.EventData = int32(streamId)
+	if err := this.Protocol.SendPacket(pkt, 0); err != nil {
+		return err
+	}
+
+	statusPkt.Data.Set(global.StatusLevel, global.StatusLevelStatus)
+	statusPkt.Data.Set(global.StatusCode, global.StatusCodeStreamSeek)
+	statusPkt.Data.Set(global.StatusDescription, "Seeking "+strconv.FormatFloat(timeMs, 'f', -1, 64)+" ms.")
+	statusPkt.Data.Set(global.StatusDetails, "stream")
+	statusPkt.Data.Set(global.StatusClientId, global.RTMP_SIG_CLIENT_ID)
+	return this.Protocol.SendPacket(statusPkt, int32(streamId))
+}
+
 func (this *SrsRtmpServer) StartPlay(streamId int) error {
 	// StreamBegin
 	pkt := packet.NewSrsUserControlPacket()
@@ -318,6 +500,26 @@ func (this *SrsRtmpServer) ResponseConnectApp(objectEncoding float64) error {
 	return err
 }
 
+/**
+ * reject the connect of client, _error with the description.
+ */
+func (this *SrsRtmpServer) ResponseConnectReject(description string) error {
+	info := amf0.NewSrsAmf0Object()
+	info.Set(global.StatusLevel, global.StatusLevelError)
+	info.Set(global.StatusCode, global.StatusCodeConnectRejected)
+	info.Set(global.StatusDescription, description)
+
+	// the transaction id of connect is always 1.
+	pkt := packet.NewSrsCallResPacket(1)
+	pkt.CommandName.Value.Value = amf0.RTMP_AMF0_COMMAND_ERROR
+	pkt.Response = info
+	return this.Protocol.SendPacket(pkt, 0)
+}
+
+func (this *SrsRtmpServer) SendPacket(pkt packet.SrsPacket, streamId int) error {
+	return this.Protocol.SendPacket(pkt, int32(streamId))
+}
+
 func (this *SrsRtmpServer) OnBwDone() error {
 	pkt := packet.NewSrsOnBwDonePacket()
 	err := this.Protocol.SendPacket(pkt, 0)
@@ -325,11 +527,20 @@ func (this *SrsRtmpServer) OnBwDone() error {
 }
 
 func (this *SrsRtmpServer) StartFmlePublish(streamId int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), this.Protocol.GetRecvTimeout())
+	defer cancel()
+	return this.StartFmlePublishContext(ctx, streamId)
+}
+
+/**
+ * the FMLE publish negotiation, interrupted when ctx done.
+ */
+func (this *SrsRtmpServer) StartFmlePublishContext(ctx context.Context, streamId int) error {
 	// FCPublish
 	var fc_publish_tid float64 = 0
 	{
 		startPkt := packet.NewSrsFMLEStartPacket("")
-		if err := this.Protocol.ExpectMessage(startPkt); err != nil {
+		if err := this.Protocol.ExpectMessageContext(ctx, startPkt); err != nil {
 			return err
 		}
 		fc_publish_tid = startPkt.TransactionId.GetValue().(float64)
@@ -343,7 +554,7 @@ func (this *SrsRtmpServer) StartFmlePublish(streamId int) error {
 	var create_stream_tid float64 = 0
 	{
 		createPkt := packet.NewSrsCreateStreamPacket()
-		if err := this.Protocol.ExpectMessage(createPkt); err != nil {
+		if err := this.Protocol.ExpectMessageContext(ctx, createPkt); err != nil {
 			return err
 		}
 		create_stream_tid = createPkt.TransactionId.Value
@@ -357,7 +568,7 @@ func (this *SrsRtmpServer) StartFmlePublish(streamId int) error {
 	// publish
 	{
 		publishPacket := packet.NewSrsPublishPacket()
-		if err := this.Protocol.ExpectMessage(publishPacket); err != nil {
+		if err := this.Protocol.ExpectMessageContext(ctx, publishPacket); err != nil {
 			return err
 		}
 	}
@@ -388,3 +599,198 @@ func (this *SrsRtmpServer) StartFmlePublish(streamId int) error {
 
 	return nil
 }
+
+/**
+ * the flash publish client already sent publish, response the onStatus(NetStream.Publish.Start).
+ */
+func (this *SrsRtmpServer) StartFlashPublish(streamId int) error {
+	statusPacket := packet.NewSrsOnStatusCallPacket()
+	statusPacket.Data.Set(global.StatusLevel, global.StatusLevelStatus)
+	statusPacket.Data.Set(global.StatusCode, global.StatusCodePublishStart)
+	statusPacket.Data.Set(global.StatusDescription, "Started publishing stream.")
+	statusPacket.Data.Set(global.StatusClientId, global.RTMP_SIG_CLIENT_ID)
+	return this.Protocol.SendPacket(statusPacket, int32(streamId))
+}
+
+func (this *SrsRtmpServer) StartHaivisionPublish(streamId int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), this.Protocol.GetRecvTimeout())
+	defer cancel()
+	return this.StartHaivisionPublishContext(ctx, streamId)
+}
+
+/**
+ * the haivision publish negotiation, the FCPublish already responsed,
+ * interrupted when ctx done.
+ */
+func (this *SrsRtmpServer) StartHaivisionPublishContext(ctx context.Context, streamId int) error {
+	// publish
+	{
+		publishPacket := packet.NewSrsPublishPacket()
+		if err := this.Protocol.ExpectMessageContext(ctx, publishPacket); err != nil {
+			return err
+		}
+	}
+
+	return this.ResponsePublish(streamId, true)
+}
+
+/**
+ * response the publish of client on the stream,
+ * @param fcPublish, whether the client sent FCPublish, response onFCPublish for it.
+ */
+func (this *SrsRtmpServer) ResponsePublish(streamId int, fcPublish bool) error {
+	// publish response onFCPublish(NetStream.Publish.Start)
+	if fcPublish {
+		statusPacket := packet.NewSrsOnStatusCallPacket()
+		statusPacket.CommandName.Value.Value = global.RTMP_AMF0_COMMAND_ON_FC_PUBLISH
+		statusPacket.Data.Set(global.StatusCode, global.StatusCodePublishStart)
+		statusPacket.Data.Set(global.StatusDescription, "Started publishing stream.")
+		if err := this.Protocol.SendPacket(statusPacket, int32(streamId)); err != nil {
+			return err
+		}
+	}
+
+	// publish response onStatus(NetStream.Publish.Start)
+	return this.StartFlashPublish(streamId)
+}
+
+/**
+ * response the createStream of client, the stream id is allocated by caller.
+ */
+func (this *SrsRtmpServer) ResponseCreateStream(transactionId float64, streamId int) error {
+	pkt := packet.NewSrsCreateStreamResPacket(transactionId, float64(streamId))
+	return this.Protocol.SendPacket(pkt, 0)
+}
+
+/**
+ * response the releaseStream, FCPublish and FCUnpublish of client.
+ */
+func (this *SrsRtmpServer) ResponseFMLEStart(transactionId float64) error {
+	pkt := packet.NewSrsFMLEStartResPacket(transactionId)
+	return this.Protocol.SendPacket(pkt, 0)
+}
+
+/**
+ * the client FCUnpublish the stream, response onFCUnpublish, the result of FCUnpublish
+ * and onStatus(NetStream.Unpublish.Success).
+ */
+func (this *SrsRtmpServer) FmleUnpublish(streamId int, transactionId float64) error {
+	// publish response onFCUnpublish(NetStream.unpublish.Success)
+	{
+		statusPacket := packet.NewSrsOnStatusCallPacket()
+		statusPacket.CommandName.Value.Value = global.RTMP_AMF0_COMMAND_ON_FC_UNPUBLISH
+		statusPacket.Data.Set(global.StatusCode, global.StatusCodeUnpublishSuccess)
+		statusPacket.Data.Set(global.StatusDescription, "Stop publishing stream.")
+		if err := this.Protocol.SendPacket(statusPacket, int32(streamId)); err != nil {
+			return err
+		}
+	}
+
+	// FCUnpublish response
+	{
+		pkt := packet.NewSrsFMLEStartResPacket(transactionId)
+		if err := this.Protocol.SendPacket(pkt, int32(streamId)); err != nil {
+			return err
+		}
+	}
+
+	// publish response onStatus(NetStream.Unpublish.Success)
+	{
+		statusPacket := packet.NewSrsOnStatusCallPacket()
+		statusPacket.Data.Set(global.StatusLevel, global.StatusLevelStatus)
+		statusPacket.Data.Set(global.StatusCode, global.StatusCodeUnpublishSuccess)
+		statusPacket.Data.Set(global.StatusDescription, "Stream is now unpublished")
+		statusPacket.Data.Set(global.StatusClientId, global.RTMP_SIG_CLIENT_ID)
+		if err := this.Protocol.SendPacket(statusPacket, int32(streamId)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ * call the method of client and wait for the result, interrupted when ctx done,
+ * the recv thread must deliver the SrsCallResPacket to OnCallResult.
+ * @return the response of client, and ErrCallFailed when client response _error.
+ */
+func (this *SrsRtmpServer) Call(ctx context.Context, name string, args ...amf0.ISrsAmf0Any) (*packet.SrsCallResPacket, error) {
+	result := make(chan *packet.SrsCallResPacket, 1)
+	this.callsLock.Lock()
+	this.lastCallId++
+	transactionId := this.lastCallId
+	this.calls[transactionId] = result
+	this.callsLock.Unlock()
+
+	// the request is never responsed when timeout, remove it to avoid leak.
+	defer func() {
+		this.callsLock.Lock()
+		delete(this.calls, transactionId)
+		this.callsLock.Unlock()
+		this.Protocol.removeRequest(transactionId)
+	}()
+
+	pkt := packet.NewSrsCallPacket(name)
+	pkt.TransactionId.Value = transactionId
+	pkt.Arguments = append(pkt.Arguments, args...)
+	if err := this.Protocol.SendPacket(pkt, 0); err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-result:
+		{
+			if res.IsError() {
+				return res, ErrCallFailed
+			}
+			return res, nil
+		}
+	case <-ctx.Done():
+		{
+			return nil, ctx.Err()
+		}
+	}
+}
+
+/**
+ * deliver the result of client to the Call waiting for it.
+ * @return whether the result is waited by a Call.
+ */
+func (this *SrsRtmpServer) OnCallResult(pkt *packet.SrsCallResPacket) bool {
+	this.callsLock.Lock()
+	defer this.callsLock.Unlock()
+	result, ok := this.calls[pkt.TransactionId.Value]
+	if !ok {
+		return false
+	}
+
+	delete(this.calls, pkt.TransactionId.Value)
+	result <- pkt
+	return true
+}
+
+/**
+ * response the call of client, _result with the response,
+ * or _error with the information of error when err is not nil.
+ */
+func (this *SrsRtmpServer) ResponseCall(transactionId float64, response amf0.ISrsAmf0Any, err error) error {
+	// the call without transaction id need no response.
+	if transactionId == 0 {
+		return nil
+	}
+
+	pkt := packet.NewSrsCallResPacket(transactionId)
+	pkt.Response = response
+	if response == nil {
+		pkt.Response = amf0.NewSrsAmf0Null()
+	}
+
+	if err != nil {
+		info := amf0.NewSrsAmf0Object()
+		info.Set(global.StatusLevel, global.StatusLevelError)
+		info.Set(global.StatusCode, global.StatusCodeCallFailed)
+		info.Set(global.StatusDescription, err.Error())
+		pkt.CommandName.Value.Value = amf0.RTMP_AMF0_COMMAND_ERROR
+		pkt.Response = info
+	}
+	return this.Protocol.SendPacket(pkt, 0)
+}
@@ -0,0 +1,171 @@
+/*
+The MIT License (MIT)
+
+Copyright (c) 2019 GOSRS(gosrs)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+package rtmp
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+/**
+ * the encoded chunk header cached in the shared payload.
+ */
+type srsCachedChunkHeader struct {
+	format    byte
+	cid       int32
+	timestamp int64
+	streamId  int32
+	data      []byte
+}
+
+/**
+ * the payload shared by all copies of the shared ptr message,
+ * the message type, length and payload are immutable once created.
+ */
+type SrsSharedPtrPayload struct {
+	// the shared header, only the type, length and perfer cid is used.
+	header  SrsMessageHeader
+	payload []byte
+	// the track of enhanced rtmp multitrack, 0 for the default track.
+	trackId uint8
+	// the reference count of the copies.
+	sharedCount int32
+	/**
+	 * the encoded chunk headers, for all consumers of the same message
+	 * always send the same chunk header, encode it once and reuse it.
+	 */
+	cacheLock sync.Mutex
+	headers   []*srsCachedChunkHeader
+}
+
+/**
+ * the shared ptr message, the payload is shared and reference counted,
+ * while the header is copied for each consumer, so the timestamp
+ * can be changed by the jitter or shrink of each consumer.
+ */
+type SrsSharedPtrMessage struct {
+	// the header of the copy, only the timestamp and stream id can be changed.
+	header SrsMessageHeader
+	ptr    *SrsSharedPtrPayload
+}
+
+/**
+ * create the shared ptr message from the common message,
+ * the payload of msg is taken over without copy, user should never change it.
+ */
+func NewSrsSharedPtrMessage(msg *SrsRtmpMessage) *SrsSharedPtrMessage {
+	ptr := &SrsSharedPtrPayload{
+		header:      msg.header,
+		payload:     msg.payload,
+		sharedCount: 1,
+	}
+	ptr.header.payloadLength = int32(len(msg.payload))
+
+	return &SrsSharedPtrMessage{
+		header: ptr.header,
+		ptr:    ptr,
+	}
+}
+
+/**
+ * create the shared ptr message of track, for the track demuxed from enhanced rtmp multitrack packet.
+ */
+func NewSrsSharedPtrTrackMessage(msg *SrsRtmpMessage, trackId uint8) *SrsSharedPtrMessage {
+	m := NewSrsSharedPtrMessage(msg)
+	m.ptr.trackId = trackId
+	return m
+}
+
+/**
+ * copy the message, the payload is shared and the header is copied.
+ */
+func (this *SrsSharedPtrMessage) Copy() *SrsSharedPtrMessage {
+	atomic.AddInt32(&this.ptr.sharedCount, 1)
+	return &SrsSharedPtrMessage{
+		header: this.header,
+		ptr:    this.ptr,
+	}
+}
+
+/**
+ * release the copy, the payload is released when all copies are freed.
+ */
+func (this *SrsSharedPtrMessage) Free() {
+	if atomic.AddInt32(&this.ptr.sharedCount, -1) > 0 {
+		return
+	}
+
+	this.ptr.cacheLock.Lock()
+	this.ptr.headers = nil
+	this.ptr.cacheLock.Unlock()
+}
+
+/**
+ * get the number of copies which shares the payload.
+ */
+func (this *SrsSharedPtrMessage) Count() int32 {
+	return atomic.LoadInt32(&this.ptr.sharedCount)
+}
+
+func (this *SrsSharedPtrMessage) GetHeader() *SrsMessageHeader {
+	return &(this.header)
+}
+
+func (this *SrsSharedPtrMessage) GetPayload() []byte {
+	return this.ptr.payload
+}
+
+func (this *SrsSharedPtrMessage) GetTrackId() uint8 {
+	return this.ptr.trackId
+}
+
+/**
+ * get the encoded chunk header of the message, encode and cache it when not found.
+ * @param timestamp the timestamp or delta for fmt0/1/2, the extended timestamp
+ *      for fmt3 or -1 when no extended timestamp.
+ */
+func (this *SrsSharedPtrPayload) chunkHeader(format byte, cid int32, timestamp int64, streamId int32) []byte {
+	// the stream id is only written in fmt0.
+	if format != RTMP_FMT_TYPE0 {
+		streamId = 0
+	}
+
+	this.cacheLock.Lock()
+	defer this.cacheLock.Unlock()
+
+	for _, h := range this.headers {
+		if h.format == format && h.cid == cid && h.timestamp == timestamp && h.streamId == streamId {
+			return h.data
+		}
+	}
+
+	h := &srsCachedChunkHeader{
+		format:    format,
+		cid:       cid,
+		timestamp: timestamp,
+		streamId:  streamId,
+		data:      srs_chunk_header(format, cid, timestamp, this.header.payloadLength, this.header.messageType, streamId),
+	}
+	this.headers = append(this.headers, h)
+	return h.data
+}
@@ -24,9 +24,12 @@ package skt
 
 import (
 	"bufio"
+	"context"
 	_ "fmt"
 	"io"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -38,6 +41,7 @@ type SrsIOReadWriter struct {
 	conn     net.Conn
 	IOReader *bufio.Reader
 	IOWriter *bufio.Writer
+	// the io bytes, written by the reader and writer, read by others.
 	nb_read  int64
 	nb_write int64
 }
@@ -52,11 +56,11 @@ func NewSrsIOReadWriter(c net.Conn) *SrsIOReadWriter {
 }
 
 func (this *SrsIOReadWriter) GetRecvBytes() int64 {
-	return this.nb_read
+	return atomic.LoadInt64(&this.nb_read)
 }
 
 func (this *SrsIOReadWriter) GetSendBytes() int64 {
-	return this.nb_write
+	return atomic.LoadInt64(&this.nb_write)
 }
 
 func (this *SrsIOReadWriter) GetClientIP() string {
@@ -66,7 +70,7 @@ func (this *SrsIOReadWriter) GetClientIP() string {
 func (this *SrsIOReadWriter) Read(b []byte) (int, error) {
 	c, e := this.IOReader.Read(b)
 	if e == nil {
-		this.nb_read += int64(c)
+		atomic.AddInt64(&this.nb_read, int64(c))
 	}
 	return c, e
 }
@@ -79,7 +83,7 @@ func (this *SrsIOReadWriter) ReadWithTimeout(b []byte, timeoutms uint32) (int, e
 	this.conn.SetReadDeadline(time.Now().Add(time.Millisecond * time.Duration(timeoutms)))
 	c, e := this.IOReader.Read(b)
 	if e == nil {
-		this.nb_read += int64(c)
+		atomic.AddInt64(&this.nb_read, int64(c))
 	}
 	return c, e
 }
@@ -93,7 +97,7 @@ func (this *SrsIOReadWriter) ReadFully(b []byte, timeoutms uint32) (int, error)
 			return 0, err
 		}
 
-		this.nb_read += int64(n)
+		atomic.AddInt64(&this.nb_read, int64(n))
 		left = left - n
 		if left <= 0 {
 			return count, nil
@@ -105,7 +109,7 @@ func (this *SrsIOReadWriter) ReadFullyWithTimeout(b []byte, timeoutms uint32) (i
 	this.conn.SetReadDeadline(time.Now().Add(time.Millisecond * time.Duration(timeoutms)))
 	c, e := io.ReadFull(this.conn, b)
 	if e == nil {
-		this.nb_read += int64(c)
+		atomic.AddInt64(&this.nb_read, int64(c))
 	}
 	return c, e
 }
@@ -113,13 +117,83 @@ func (this *SrsIOReadWriter) ReadFullyWithTimeout(b []byte, timeoutms uint32) (i
 func (this *SrsIOReadWriter) Write(b []byte) (int, error) {
 	n, err := this.IOWriter.Write(b)
 	_ = this.IOWriter.Flush()
-	this.nb_write += int64(n)
+	atomic.AddInt64(&this.nb_write, int64(n))
+	return n, err
+}
+
+/**
+ * write the buffers in a single writev syscall when the conn supports it,
+ * the buffered data is flushed first to keep the bytes in order.
+ */
+func (this *SrsIOReadWriter) WriteBuffers(bufs net.Buffers) (int64, error) {
+	if err := this.IOWriter.Flush(); err != nil {
+		return 0, err
+	}
+
+	n, err := bufs.WriteTo(this.conn)
+	atomic.AddInt64(&this.nb_write, n)
 	return n, err
 }
 
 func (this *SrsIOReadWriter) WriteWithTimeout(b []byte, timeoutms uint32) (int, error) {
 	this.conn.SetWriteDeadline(time.Now().Add(time.Millisecond * time.Duration(timeoutms)))
 	c, e := this.IOWriter.Write(b)
-	this.nb_write += int64(c)
+	atomic.AddInt64(&this.nb_write, int64(c))
 	return c, e
 }
+
+/**
+ * run the read under the context in the caller goroutine, the read deadline
+ * is set to the deadline of ctx, and the blocking read is interrupted when ctx done.
+ * @remark the reader state is undefined when interrupted, user should close the conn.
+ * @return ctx.Err() when the read is interrupted by ctx.
+ */
+func (this *SrsIOReadWriter) ReadContext(ctx context.Context, read func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// zero deadline for no deadline.
+	deadline, _ := ctx.Deadline()
+	this.conn.SetReadDeadline(deadline)
+
+	var wg sync.WaitGroup
+	done := make(chan bool)
+	if ctx.Done() != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-ctx.Done():
+				// wakeup the blocking read.
+				this.conn.SetReadDeadline(time.Unix(1, 0))
+			case <-done:
+			}
+		}()
+	}
+
+	err := read()
+
+	// the watcher must quit before reset the deadline.
+	close(done)
+	wg.Wait()
+	this.conn.SetReadDeadline(time.Time{})
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// the deadline of conn maybe fired before the timer of ctx.
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return context.DeadlineExceeded
+		}
+	}
+	return err
+}
+
+/**
+ * peek the bytes without consuming, block until the bytes is available.
+ */
+func (this *SrsIOReadWriter) PeekFully(n int) ([]byte, error) {
+	return this.IOReader.Peek(n)
+}